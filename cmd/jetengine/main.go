@@ -5,14 +5,21 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/sirupsen/logrus"
 
+	"jetengine/internal/archive"
+	"jetengine/internal/blobstore"
 	"jetengine/internal/bot"
 	"jetengine/internal/config"
+	"jetengine/internal/queue"
+	"jetengine/internal/refresh"
 	"jetengine/internal/scraper"
+	"jetengine/internal/server"
 	"jetengine/internal/storage"
+	"jetengine/internal/telegramauth"
 )
 
 func main() {
@@ -31,7 +38,8 @@ func main() {
 	log.SetLevel(logrus.InfoLevel)
 
 	log.WithFields(logrus.Fields{
-		"badgerdb_path": cfg.BadgerDBPath,
+		"badgerdb_path":   cfg.BadgerDBPath,
+		"blob_store_path": cfg.BlobStorePath,
 	}).Info("Configuration loaded successfully")
 
 	// --- Initialize Components ---
@@ -49,16 +57,88 @@ func main() {
 		}
 	}()
 
-	// Scraper
-	scraperService := scraper.NewRodScraper(log)
-	// TODO: Add scraperService.Close() if needed and call in defer
+	// Scraper: static HTML first, headless browser only when needed.
+	// Both refine the generic metadata with the site-specific extractors,
+	// and share the per-site request limits.
+	politeness := scraper.NewPoliteness(scraper.PolitenessOptions{
+		HostConcurrency:      cfg.ScraperHostConcurrency,
+		HostDelay:            cfg.ScraperHostDelay,
+		RequestsPerSecond:    cfg.ScraperRateLimit,
+		Burst:                cfg.ScraperRateBurst,
+		RespectRobots:        cfg.ScraperRespectRobots,
+		AllowPrivateNetworks: cfg.ScraperAllowPrivateNetworks,
+	}, log)
+	extractors := scraper.DefaultExtractors(nil, politeness)
+	rodScraper := scraper.NewRodScraper(scraper.RodOptions{
+		MaxPages:             cfg.ScraperMaxPages,
+		PageTimeout:          cfg.ScraperPageTimeout,
+		Extractors:           extractors,
+		ExtractArticles:      cfg.ScraperExtractArticles,
+		Politeness:           politeness,
+		AllowPrivateNetworks: cfg.ScraperAllowPrivateNetworks,
+	}, log)
+	httpScraper := scraper.NewHTTPScraper(scraper.HTTPOptions{
+		Extractors:           extractors,
+		ExtractArticles:      cfg.ScraperExtractArticles,
+		Politeness:           politeness,
+		AllowPrivateNetworks: cfg.ScraperAllowPrivateNetworks,
+	}, log)
+	var scraperService scraper.Scraper = scraper.NewFallbackScraper(httpScraper, rodScraper, log)
+	// Users saving the same page share one scrape while it is cached
+	var scrapeCache *scraper.CachingScraper
+	if cfg.ScraperCacheTTL > 0 {
+		scrapeCache = scraper.NewCachingScraper(scraperService, repo, cfg.ScraperCacheTTL, log)
+		scraperService = scrapeCache
+	}
+	defer func() {
+		log.Info("Closing scraper...")
+		if err := scraperService.Close(); err != nil {
+			log.WithError(err).Error("Error closing scraper")
+		}
+	}()
+
+	// Snapshots are captured with the browser and kept in the blob store
+	blobs, err := blobstore.New(cfg.BlobStorePath, log)
+	if err != nil {
+		log.Fatalf("Failed to initialize blob store: %v", err)
+	}
+	archiver := archive.New(rodScraper, blobs, repo, log)
+
+	// Saved links are scraped in the background by the persistent job queue
+	jobQueue := queue.New(repo, queue.Options{
+		Workers:     cfg.QueueWorkers,
+		MaxAttempts: cfg.QueueMaxAttempts,
+		Backoff:     cfg.QueueRetryBackoff,
+		MaxBackoff:  cfg.QueueMaxBackoff,
+	}, log)
 
 	// Bot Handler
-	botHandler, err := bot.NewHandler(cfg, repo, scraperService, log)
+	botHandler, err := bot.NewHandler(cfg, repo, scraperService, jobQueue, archiver, log)
 	if err != nil {
 		log.Fatalf("Failed to initialize Telegram bot handler: %v", err)
 	}
 
+	// REST API and web UI, authenticated with personal tokens, the configured
+	// API keys and browser sessions started with a Telegram login
+	var apiServer *server.Server
+	if cfg.ServerAddr != "" {
+		apiKeys, err := server.ParseStaticKeys(cfg.APIKeys)
+		if err != nil {
+			log.Fatalf("Invalid API_KEYS: %v", err)
+		}
+		auth := server.Authenticators{server.NewTokenAuth(repo), apiKeys}
+		sessions := server.NewSessions(repo, telegramauth.NewVerifier(cfg.TelegramBotToken, cfg.TelegramAuthMaxAge), server.SessionOptions{
+			TTL:         cfg.SessionTTL,
+			Secure:      cfg.SessionCookieSecure,
+			BotUsername: strings.TrimPrefix(cfg.TelegramBotUsername, "@"),
+		}, log)
+		apiHandler := server.NewHandler(repo, scraperService, jobQueue, auth, sessions, log)
+		apiServer = server.New(server.Options{
+			Addr:            cfg.ServerAddr,
+			ShutdownTimeout: cfg.ServerShutdownTimeout,
+		}, apiHandler, log)
+	}
+
 	// --- Application Startup ---
 	log.Info("Starting JetEngine...")
 
@@ -66,8 +146,54 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop() // Ensure stop is called to release resources
 
-	// Start the bot polling in a separate goroutine
+	// Start the bot polling and the scrape workers in separate goroutines
 	go botHandler.Start(ctx)
+	queueDone := make(chan struct{})
+	go func() {
+		defer close(queueDone)
+		jobQueue.Run(ctx, botHandler.ScrapeJobs())
+	}()
+
+	// Saved links are re-checked periodically to catch changes and dead pages
+	refreshDone := make(chan struct{})
+	if cfg.RefreshInterval > 0 {
+		checker := refresh.New(repo, scraperService, botHandler, refresh.Options{
+			Interval:  cfg.RefreshInterval,
+			MaxAge:    cfg.RefreshMaxAge,
+			BatchSize: cfg.RefreshBatchSize,
+			DeadAfter: cfg.RefreshDeadAfter,
+		}, log)
+		go func() {
+			defer close(refreshDone)
+			checker.Run(ctx)
+		}()
+	} else {
+		close(refreshDone)
+	}
+
+	// Snapshots of deleted links and replaced captures are removed periodically
+	sweeperDone := make(chan struct{})
+	if cfg.BlobSweepInterval > 0 {
+		go func() {
+			defer close(sweeperDone)
+			archiver.RunSweeper(ctx, cfg.BlobSweepInterval)
+		}()
+	} else {
+		close(sweeperDone)
+	}
+
+	// The API server shuts down gracefully when the context is cancelled
+	serverDone := make(chan struct{})
+	if apiServer != nil {
+		go func() {
+			defer close(serverDone)
+			if err := apiServer.Run(ctx); err != nil {
+				log.WithError(err).Error("HTTP server stopped with an error")
+			}
+		}()
+	} else {
+		close(serverDone)
+	}
 
 	log.Info("JetEngine is running. Press Ctrl+C to exit.")
 
@@ -78,8 +204,21 @@ func main() {
 	log.Info("Shutting down JetEngine...")
 	stop() // Explicitly call stop to ensure signal handling is cleaned up
 
-	// The deferred repo.Close() will run now.
-	// Add cleanup for other components if needed (e.g., scraper).
+	// Running jobs, checks and requests still write to the database, so wait for them before closing it
+	<-queueDone
+	<-refreshDone
+	<-sweeperDone
+	<-serverDone
+
+	if scrapeCache != nil {
+		stats := scrapeCache.Stats()
+		log.WithFields(logrus.Fields{
+			"hits":   stats.Hits,
+			"misses": stats.Misses,
+		}).Info("Scrape cache statistics")
+	}
+
+	// The deferred scraperService.Close() and repo.Close() will run now.
 
 	log.Info("JetEngine shut down gracefully.")
 }
@@ -0,0 +1,145 @@
+// Package archive preserves saved links by capturing snapshots of their pages
+// (screenshot, PDF, MHTML) and keeping them in the blob store.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/blobstore"
+	"jetengine/internal/domain"
+	"jetengine/internal/scraper"
+	"jetengine/internal/storage"
+)
+
+// SweepGracePeriod is how long new snapshot content is kept before the
+// sweeper may delete it: the blob is stored before the link referencing it.
+const SweepGracePeriod = time.Hour
+
+// Archiver captures snapshots of saved links on demand. Snapshot content is
+// stored once per digest in the blob store and referenced from the link.
+type Archiver struct {
+	snapshotter scraper.Snapshotter
+	blobs       *blobstore.Store
+	repo        storage.Repository
+	log         logrus.FieldLogger
+}
+
+// New creates an archiver.
+func New(snapshotter scraper.Snapshotter, blobs *blobstore.Store, repo storage.Repository, logger logrus.FieldLogger) *Archiver {
+	return &Archiver{
+		snapshotter: snapshotter,
+		blobs:       blobs,
+		repo:        repo,
+		log:         logger.WithField("component", "archiver"),
+	}
+}
+
+// Snapshot returns the snapshot of the given kind for a link, capturing it
+// first if the link has none yet or its content went missing.
+// It returns the link as updated with the new snapshot.
+func (a *Archiver) Snapshot(ctx context.Context, userID int64, linkID string, kind domain.SnapshotKind) (domain.Link, domain.Snapshot, error) {
+	link, err := a.repo.GetLinkByID(ctx, userID, linkID)
+	if err != nil {
+		return domain.Link{}, domain.Snapshot{}, err
+	}
+	if s, ok := link.Snapshot(kind); ok && a.blobs.Exists(s.Digest) {
+		return link, s, nil
+	}
+
+	log := a.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"link_id": linkID,
+		"kind":    kind,
+	})
+
+	// Capture what the user saw: the page after redirects
+	target := link.ResolvedURL
+	if target == "" {
+		target = link.URL
+	}
+	captures, err := a.snapshotter.Capture(ctx, target, kind)
+	if err != nil {
+		return domain.Link{}, domain.Snapshot{}, err
+	}
+	if len(captures) != 1 {
+		return domain.Link{}, domain.Snapshot{}, fmt.Errorf("expected one %s capture, got %d", kind, len(captures))
+	}
+	c := captures[0]
+
+	digest, size, err := a.blobs.Put(bytes.NewReader(c.Data))
+	if err != nil {
+		log.WithError(err).Error("Failed to store snapshot")
+		return domain.Link{}, domain.Snapshot{}, fmt.Errorf("failed to store %s snapshot: %w", kind, err)
+	}
+	snapshot := domain.Snapshot{
+		Kind:       kind,
+		Digest:     digest,
+		Size:       size,
+		MediaType:  c.MediaType,
+		CapturedAt: time.Now(),
+	}
+
+	link, err = a.repo.AddSnapshot(ctx, userID, linkID, snapshot)
+	if err != nil {
+		return domain.Link{}, domain.Snapshot{}, err
+	}
+	log.WithFields(logrus.Fields{"digest": digest, "size": size}).Info("Link snapshot archived")
+	return link, snapshot, nil
+}
+
+// Open returns the content of a snapshot. The caller must close it.
+func (a *Archiver) Open(snapshot domain.Snapshot) (io.ReadCloser, error) {
+	f, err := a.blobs.Open(snapshot.Digest)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		a.log.WithField("digest", snapshot.Digest).Warn("Snapshot content is missing from the blob store")
+	}
+	return f, err
+}
+
+// Sweep deletes snapshot content that no link references any more, such as
+// snapshots of deleted links or ones replaced by a newer capture. Content
+// stored or reused within minAge is kept. It returns the number of blobs removed.
+func (a *Archiver) Sweep(ctx context.Context, minAge time.Duration) (int, error) {
+	// Take the cutoff first: blobs stored after it may belong to snapshots
+	// recorded after the digests were collected
+	before := time.Now().Add(-minAge)
+	digests, err := a.repo.SnapshotDigests(ctx)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := a.blobs.Sweep(func(digest string) bool { return digests[digest] }, before)
+	if err != nil {
+		return removed, fmt.Errorf("failed to sweep snapshots: %w", err)
+	}
+	if removed > 0 {
+		a.log.WithField("removed", removed).Info("Removed unreferenced snapshots")
+	}
+	return removed, nil
+}
+
+// RunSweeper sweeps unreferenced snapshots right away and then every
+// interval, until ctx is cancelled.
+func (a *Archiver) RunSweeper(ctx context.Context, interval time.Duration) {
+	a.log.WithField("interval", interval).Info("Starting snapshot sweeper")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := a.Sweep(ctx, SweepGracePeriod); err != nil && ctx.Err() == nil {
+			a.log.WithError(err).Error("Snapshot sweep failed")
+		}
+		select {
+		case <-ctx.Done():
+			a.log.Info("Snapshot sweeper stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
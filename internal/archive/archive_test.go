@@ -0,0 +1,143 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"jetengine/internal/blobstore"
+	"jetengine/internal/domain"
+	"jetengine/internal/scraper"
+	"jetengine/internal/storage"
+)
+
+// fakeSnapshotter returns fixed content and records the captured URLs.
+type fakeSnapshotter struct {
+	data string
+	err  error
+	urls []string
+}
+
+func (f *fakeSnapshotter) Capture(ctx context.Context, url string, kinds ...domain.SnapshotKind) ([]scraper.Capture, error) {
+	f.urls = append(f.urls, url)
+	if f.err != nil {
+		return nil, f.err
+	}
+	var captures []scraper.Capture
+	for _, kind := range kinds {
+		captures = append(captures, scraper.Capture{Kind: kind, MediaType: "application/pdf", Data: []byte(f.data)})
+	}
+	return captures, nil
+}
+
+// TestArchiver_Snapshot tests capturing, reusing and deduplicating snapshots.
+func TestArchiver_Snapshot(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ctx := context.Background()
+
+	repo, err := storage.NewBadgerRepository(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer repo.Close()
+	blobs, err := blobstore.New(t.TempDir(), logger)
+	require.NoError(t, err)
+	snapshotter := &fakeSnapshotter{data: "%PDF-1.7"}
+	a := New(snapshotter, blobs, repo, logger)
+
+	first := domain.Link{URL: "https://example.com/a", ResolvedURL: "https://www.example.com/a", UserID: 1}
+	second := domain.Link{URL: "https://example.com/b", UserID: 1}
+	require.NoError(t, repo.SaveLink(ctx, &first))
+	require.NoError(t, repo.SaveLink(ctx, &second))
+
+	link, snapshot, err := a.Snapshot(ctx, 1, first.ID, domain.SnapshotPDF)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://www.example.com/a"}, snapshotter.urls, "The resolved URL should be captured")
+	assert.Equal(t, int64(8), snapshot.Size)
+	assert.Len(t, link.Snapshots, 1)
+
+	r, err := a.Open(snapshot)
+	require.NoError(t, err)
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "%PDF-1.7", string(content))
+
+	// --- An existing snapshot is reused ---
+	_, again, err := a.Snapshot(ctx, 1, first.ID, domain.SnapshotPDF)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.Digest, again.Digest)
+	assert.Len(t, snapshotter.urls, 1)
+
+	// --- Identical content of another link shares the blob ---
+	_, other, err := a.Snapshot(ctx, 1, second.ID, domain.SnapshotPDF)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.Digest, other.Digest)
+
+	// --- Errors ---
+	_, _, err = a.Snapshot(ctx, 2, first.ID, domain.SnapshotPDF)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	snapshotter.err = errors.New("browser crashed")
+	_, _, err = a.Snapshot(ctx, 1, first.ID, domain.SnapshotScreenshot)
+	assert.Error(t, err)
+}
+
+// TestArchiver_Sweep tests deleting snapshots of deleted links and replaced captures.
+func TestArchiver_Sweep(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ctx := context.Background()
+
+	repo, err := storage.NewBadgerRepository(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer repo.Close()
+	blobs, err := blobstore.New(t.TempDir(), logger)
+	require.NoError(t, err)
+	snapshotter := &fakeSnapshotter{data: "first"}
+	a := New(snapshotter, blobs, repo, logger)
+
+	first := domain.Link{URL: "https://example.com/a", UserID: 1}
+	second := domain.Link{URL: "https://example.com/b", UserID: 1}
+	require.NoError(t, repo.SaveLink(ctx, &first))
+	require.NoError(t, repo.SaveLink(ctx, &second))
+
+	_, shared, err := a.Snapshot(ctx, 1, first.ID, domain.SnapshotPDF)
+	require.NoError(t, err)
+	_, _, err = a.Snapshot(ctx, 1, second.ID, domain.SnapshotPDF)
+	require.NoError(t, err)
+	snapshotter.data = "screenshot"
+	_, replaced, err := a.Snapshot(ctx, 1, second.ID, domain.SnapshotScreenshot)
+	require.NoError(t, err)
+
+	// --- Recent content survives the grace period ---
+	removed, err := a.Sweep(ctx, SweepGracePeriod)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+
+	// --- Content still referenced by another link is kept ---
+	require.NoError(t, repo.DeleteLinkByID(ctx, 1, first.ID))
+	removed, err = a.Sweep(ctx, -time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+	assert.True(t, blobs.Exists(shared.Digest))
+
+	// --- Replaced and deleted snapshots are removed ---
+	_, err = repo.AddSnapshot(ctx, 1, second.ID, domain.Snapshot{Kind: domain.SnapshotScreenshot, Digest: shared.Digest})
+	require.NoError(t, err)
+	removed, err = a.Sweep(ctx, -time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.False(t, blobs.Exists(replaced.Digest))
+
+	require.NoError(t, repo.DeleteLinkByID(ctx, 1, second.ID))
+	removed, err = a.Sweep(ctx, -time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.False(t, blobs.Exists(shared.Digest))
+}
@@ -0,0 +1,175 @@
+// Package blobstore keeps large binary objects, such as page snapshots, on the
+// local file system next to the database. Blobs are content-addressed by their
+// SHA-256 digest, so storing the same content twice keeps a single copy.
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrNotFound is returned when a blob does not exist.
+	ErrNotFound = errors.New("blob not found")
+
+	// ErrInvalidDigest is returned for digests that are not a hex SHA-256.
+	ErrInvalidDigest = errors.New("invalid blob digest")
+)
+
+// Store is a content-addressed blob store in a directory. A blob with digest
+// "abcd…" is kept at {dir}/ab/abcd…; uploads are written to {dir}/tmp first
+// and renamed into place, so readers never see partial blobs.
+type Store struct {
+	dir string
+	log logrus.FieldLogger
+}
+
+// New opens the blob store in dir, creating the directory if needed.
+func New(dir string, logger logrus.FieldLogger) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "tmp"), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob store at %s: %w", dir, err)
+	}
+	return &Store{
+		dir: dir,
+		log: logger.WithField("component", "blobstore"),
+	}, nil
+}
+
+// Put stores the content of r and returns its digest and size.
+// Content that is already stored is not written again.
+func (s *Store) Put(r io.Reader) (digest string, size int64, err error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, "tmp"), "blob-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temporary blob: %w", err)
+	}
+	defer func() {
+		// Removing fails harmlessly once the file was renamed into place
+		_ = os.Remove(tmp.Name())
+	}()
+
+	hash := sha256.New()
+	size, err = io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write blob: %w", err)
+	}
+	digest = hex.EncodeToString(hash.Sum(nil))
+
+	path := s.path(digest)
+	log := s.log.WithFields(logrus.Fields{"digest": digest, "size": size})
+	if _, err := os.Stat(path); err == nil {
+		// Reused blobs count as new, so Sweep keeps them until they are referenced
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			return "", 0, fmt.Errorf("failed to refresh blob: %w", err)
+		}
+		log.Debug("Blob already stored")
+		return digest, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", 0, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, fmt.Errorf("failed to store blob: %w", err)
+	}
+	log.Info("Blob stored")
+	return digest, size, nil
+}
+
+// Open returns a reader for the blob with the given digest.
+// It returns ErrNotFound if the blob does not exist.
+func (s *Store) Open(digest string) (*os.File, error) {
+	if !validDigest(digest) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidDigest, digest)
+	}
+	f, err := os.Open(s.path(digest))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, digest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob %s: %w", digest, err)
+	}
+	return f, nil
+}
+
+// Exists reports whether a blob with the given digest is stored.
+func (s *Store) Exists(digest string) bool {
+	if !validDigest(digest) {
+		return false
+	}
+	_, err := os.Stat(s.path(digest))
+	return err == nil
+}
+
+// Sweep removes the blobs for which keep returns false and returns how many
+// were removed. Blobs stored or reused at or after before are kept: they are
+// written before the snapshot referencing them, which may not be recorded yet.
+func (s *Store) Sweep(keep func(digest string) bool, before time.Time) (int, error) {
+	shards, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list blob store: %w", err)
+	}
+
+	removed := 0
+	for _, shard := range shards {
+		// Only the two-character shards hold blobs; tmp holds uploads
+		if !shard.IsDir() || len(shard.Name()) != 2 {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(s.dir, shard.Name()))
+		if err != nil {
+			return removed, fmt.Errorf("failed to list blobs: %w", err)
+		}
+		for _, entry := range entries {
+			digest := entry.Name()
+			if !validDigest(digest) || keep(digest) {
+				continue
+			}
+			info, err := entry.Info()
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return removed, fmt.Errorf("failed to stat blob %s: %w", digest, err)
+			}
+			if !info.ModTime().Before(before) {
+				continue
+			}
+			if err := os.Remove(s.path(digest)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return removed, fmt.Errorf("failed to remove blob %s: %w", digest, err)
+			}
+			s.log.WithFields(logrus.Fields{"digest": digest, "size": info.Size()}).Debug("Blob removed")
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// path returns the file path of a blob, sharded by the first two digest characters.
+func (s *Store) path(digest string) string {
+	return filepath.Join(s.dir, digest[:2], digest)
+}
+
+// validDigest reports whether digest is a lower-case hex SHA-256, which also
+// guarantees it is safe to use in a file path.
+func validDigest(digest string) bool {
+	if len(digest) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range digest {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package blobstore
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	dir := t.TempDir()
+	s, err := New(dir, logger)
+	require.NoError(t, err)
+	return s, dir
+}
+
+// TestStore_PutAndOpen tests storing, deduplicating and reading blobs.
+func TestStore_PutAndOpen(t *testing.T) {
+	s, dir := newTestStore(t)
+
+	digest, size, err := s.Put(strings.NewReader("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", digest)
+	assert.Equal(t, int64(5), size)
+	assert.True(t, s.Exists(digest))
+
+	// --- The same content is stored once ---
+	again, _, err := s.Put(strings.NewReader("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, digest, again)
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "2c", digest)}, files, "Only the blob itself should remain, no temporary files")
+
+	f, err := s.Open(digest)
+	require.NoError(t, err)
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+}
+
+// TestStore_Open tests errors for unknown and malformed digests.
+func TestStore_Open(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	_, err := s.Open(strings.Repeat("a", 64))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	for _, digest := range []string{"", "../../etc/passwd", strings.Repeat("A", 64), strings.Repeat("a", 63)} {
+		_, err := s.Open(digest)
+		assert.ErrorIs(t, err, ErrInvalidDigest, digest)
+		assert.False(t, s.Exists(digest))
+	}
+
+	_, err = os.Stat(filepath.Join(s.dir, "tmp"))
+	assert.NoError(t, err)
+}
+
+// TestStore_Sweep tests removing unreferenced blobs while keeping recent ones.
+func TestStore_Sweep(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	kept, _, err := s.Put(strings.NewReader("kept"))
+	require.NoError(t, err)
+	unused, _, err := s.Put(strings.NewReader("unused"))
+	require.NoError(t, err)
+	recent, _, err := s.Put(strings.NewReader("recent"))
+	require.NoError(t, err)
+
+	old := time.Now().Add(-2 * time.Hour)
+	for _, digest := range []string{kept, unused, recent} {
+		require.NoError(t, os.Chtimes(s.path(digest), old, old))
+	}
+	// Storing content again makes it recent
+	_, _, err = s.Put(strings.NewReader("recent"))
+	require.NoError(t, err)
+
+	keep := func(digest string) bool { return digest == kept }
+	removed, err := s.Sweep(keep, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.True(t, s.Exists(kept))
+	assert.False(t, s.Exists(unused))
+	assert.True(t, s.Exists(recent), "Blobs reused within the grace period should be kept")
+
+	removed, err = s.Sweep(keep, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.False(t, s.Exists(recent))
+	assert.True(t, s.Exists(kept))
+}
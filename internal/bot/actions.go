@@ -0,0 +1,253 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// Callback actions for the inline buttons on a link card.
+// Each payload is "{action}:{linkID}", which stays well under 64 bytes.
+const (
+	actionToggleRead    = "read"
+	actionDelete        = "del"
+	actionDeleteConfirm = "delok"
+	actionDeleteCancel  = "delno"
+	actionEditTags      = "tags"
+	actionRefresh       = "refresh"
+)
+
+// tagPromptPrefix starts the force-reply prompt sent by "Edit tags".
+// Replies to a message starting with it are treated as new tags for the link
+// whose ID follows, so no conversation state has to be kept in memory.
+const tagPromptPrefix = "Edit tags for link "
+
+// linkActionLookup resolves the link and card message for a link action callback.
+// It returns a user-facing notification text when the action cannot proceed.
+func (h *Handler) linkActionLookup(ctx context.Context, query *models.CallbackQuery, id string) (domain.Link, *models.Message, string) {
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": query.From.ID,
+		"link_id": id,
+	})
+
+	msg := query.Message.Message
+	if msg == nil {
+		log.Warn("Link card message is no longer accessible")
+		return domain.Link{}, nil, "This message is too old, use /mylist instead."
+	}
+
+	link, err := h.repo.GetLinkByID(ctx, query.From.ID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return domain.Link{}, nil, "This link no longer exists."
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to look up link")
+		return domain.Link{}, nil, "Could not load this link, please try again."
+	}
+	return link, msg, ""
+}
+
+// toggleReadCallback flips the read state of a link and refreshes its card.
+func (h *Handler) toggleReadCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	link, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	// Only the read state is written, so a scrape finishing meanwhile is kept
+	link, err := h.repo.SetRead(ctx, link.UserID, link.ID, !link.Read)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "This link no longer exists."
+	}
+	if err != nil {
+		h.log.WithError(err).WithField("link_id", id).Error("Failed to update read state")
+		return "Could not update this link, please try again."
+	}
+
+	h.editMessage(ctx, b, msg, formatLinkCard(link), linkKeyboard(link))
+	if link.Read {
+		return "Marked as read"
+	}
+	return "Marked as unread"
+}
+
+// refreshCallback scrapes a link again, bypassing the scrape cache, and shows
+// the card as pending until the job updates it.
+func (h *Handler) refreshCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	link, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+	if link.Pending {
+		return "Already fetching the details of this link."
+	}
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": link.UserID,
+		"link_id": id,
+	})
+
+	link, err := h.repo.SetPending(ctx, link.UserID, link.ID, true)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "This link no longer exists."
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to mark link as pending")
+		return "Could not update this link, please try again."
+	}
+	job := domain.ScrapeJob{
+		UserID:    link.UserID,
+		LinkID:    link.ID,
+		URL:       link.URL,
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.ID,
+		Refresh:   true,
+	}
+	if err := h.queue.Enqueue(ctx, &job); err != nil {
+		log.WithError(err).Error("Failed to enqueue refresh job")
+		if _, err := h.repo.SetPending(ctx, link.UserID, link.ID, false); err != nil {
+			log.WithError(err).Error("Failed to reset pending state")
+		}
+		return "Could not refresh this link, please try again."
+	}
+
+	h.editMessage(ctx, b, msg, formatLinkCard(link), linkKeyboard(link))
+	return "Refreshing…"
+}
+
+// deleteCallback asks the user to confirm deleting a link.
+func (h *Handler) deleteCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	link, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	h.editMessage(ctx, b, msg, formatLinkCard(link)+"\n\n<b>Delete this link?</b>", deleteConfirmKeyboard(id))
+	return ""
+}
+
+// deleteConfirmCallback deletes a link after the user confirmed it.
+func (h *Handler) deleteConfirmCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	link, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	if err := h.repo.DeleteLinkByID(ctx, link.UserID, link.ID); err != nil {
+		h.log.WithError(err).WithField("link_id", id).Error("Failed to delete link")
+		return "Could not delete this link, please try again."
+	}
+
+	h.editMessage(ctx, b, msg, "<s>"+html.EscapeString(link.URL)+"</s>\nLink deleted.", nil)
+	return "Link deleted"
+}
+
+// deleteCancelCallback restores the regular card after a cancelled delete.
+func (h *Handler) deleteCancelCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	link, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	h.editMessage(ctx, b, msg, formatLinkCard(link), linkKeyboard(link))
+	return ""
+}
+
+// editTagsCallback prompts the user to reply with the new tags for a link.
+func (h *Handler) editTagsCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	link, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	current := "none"
+	if len(link.Tags) > 0 {
+		current = strings.Join(link.Tags, " ")
+	}
+	text := fmt.Sprintf("%s%s:\n%s\n\nCurrent tags: %s\nReply with the new tags separated by spaces, or - to clear them.",
+		tagPromptPrefix, id, link.URL, current)
+
+	_, err := b.SendMessage(ctx, &tgbot.SendMessageParams{
+		ChatID:             msg.Chat.ID,
+		Text:               text,
+		LinkPreviewOptions: disabledLinkPreview(),
+		ReplyMarkup: &models.ForceReply{
+			ForceReply:            true,
+			InputFieldPlaceholder: "go performance reading-list",
+		},
+	})
+	if err != nil {
+		h.log.WithError(err).WithField("link_id", id).Error("Failed to send tag prompt")
+		return "Could not start editing tags, please try again."
+	}
+	return ""
+}
+
+// handleTagReply applies tags sent as a reply to an "Edit tags" prompt.
+// It reports whether msg was such a reply and has been handled.
+func (h *Handler) handleTagReply(ctx context.Context, b *tgbot.Bot, msg *models.Message) bool {
+	reply := msg.ReplyToMessage
+	if reply == nil || reply.From == nil || !reply.From.IsBot {
+		return false
+	}
+	rest, ok := strings.CutPrefix(reply.Text, tagPromptPrefix)
+	if !ok {
+		return false
+	}
+	id, _, ok := strings.Cut(rest, ":")
+	if !ok {
+		return false
+	}
+
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"link_id": id,
+	})
+
+	link, err := h.repo.SetTags(ctx, msg.From.ID, id, parseTags(msg.Text))
+	if errors.Is(err, storage.ErrNotFound) {
+		h.sendText(ctx, b, msg.Chat.ID, "This link no longer exists.")
+		return true
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to save tags")
+		h.sendText(ctx, b, msg.Chat.ID, "Could not save the tags, please try again.")
+		return true
+	}
+
+	log.WithField("tags", link.Tags).Info("Updated link tags")
+	h.sendMessage(ctx, b, msg.Chat.ID, formatLinkCard(link), linkKeyboard(link))
+	return true
+}
+
+// parseTags splits user input into normalized, de-duplicated tags.
+// Tags are lower-cased, may be separated by spaces or commas and may carry a
+// leading '#'. A lone "-" yields no tags.
+func parseTags(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\t'
+	})
+
+	seen := make(map[string]struct{})
+	var tags []string
+	for _, f := range fields {
+		tag := strings.ToLower(strings.TrimLeft(f, "#"))
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if _, dup := seen[tag]; dup {
+			continue
+		}
+		seen[tag] = struct{}{}
+		tags = append(tags, tag)
+	}
+	return tags
+}
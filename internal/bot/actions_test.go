@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// TestParseTags tests normalization of user-entered tags.
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"go", "perf", "reading-list"}, parseTags("#Go, perf  reading-list #go"))
+	assert.Empty(t, parseTags("-"))
+	assert.Empty(t, parseTags("  "))
+}
+
+// TestCallbackDataFitsLimit ensures link action payloads stay under Telegram's 64-byte limit.
+func TestCallbackDataFitsLimit(t *testing.T) {
+	// Link IDs are 11 characters (see storage.newLinkID); use a generous upper bound.
+	id := "AAAAAAAAAAAAAAAAAAAAAAAA"
+	for _, action := range []string{actionToggleRead, actionDelete, actionDeleteConfirm, actionDeleteCancel, actionEditTags, actionRefresh,
+		actionArticleText, actionArticleMarkdown, actionArticleHTML, actionSnapshots, actionShowCard, actionDismissTags, actionCollect} {
+		assert.LessOrEqual(t, len(callbackData(action, id)), 64)
+	}
+	for _, kind := range domain.SnapshotKinds {
+		assert.LessOrEqual(t, len(callbackData(actionSnapshot, string(kind)+":"+id)), 64)
+	}
+	// Collection IDs are 12 characters (see storage.newCollectionID)
+	collectionID := strings.Repeat("C", 12)
+	assert.LessOrEqual(t, len(callbackData(actionCollectInto, id+":"+collectionID)), 64)
+	assert.LessOrEqual(t, len(callbackData(actionMemberRole, collectionID+":-9223372036854775808:e")), 64)
+	// Token IDs are 8 characters (see storage.CreateToken)
+	assert.LessOrEqual(t, len(callbackData(actionRevokeToken, strings.Repeat("T", 8))), 64)
+}
+
+// TestCursorCallbackDataFitsLimit ensures paging payloads built from real
+// cursors stay under Telegram's 64-byte limit, even for long user IDs.
+func TestCursorCallbackDataFitsLimit(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	repo, err := storage.NewBadgerRepository(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	ctx := context.Background()
+	userID := int64(9_007_199_254_740_991) // the largest Telegram user ID
+	c := domain.Collection{OwnerID: userID, Name: "Reading"}
+	require.NoError(t, repo.CreateCollection(ctx, &c))
+	for _, u := range []string{"https://example.com/1", "https://example.com/2"} {
+		link := domain.Link{URL: u, UserID: userID}
+		require.NoError(t, repo.SaveLink(ctx, &link))
+		_, err := repo.AddToCollection(ctx, userID, c.ID, link.ID)
+		require.NoError(t, err)
+	}
+
+	page, err := repo.ListLinks(ctx, userID, storage.ListOptions{Limit: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+	assert.LessOrEqual(t, len(callbackData(listAction, "r:"+page.NextCursor)), 64)
+	page, err = repo.ListLinks(ctx, userID, storage.ListOptions{Limit: 1, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.PrevCursor)
+	assert.LessOrEqual(t, len(callbackData(listAction, "r:"+page.PrevCursor)), 64)
+	markup := listKeyboard(listAction, "r", page)
+	require.NotNil(t, markup)
+	assert.Equal(t, "« Prev", markup.InlineKeyboard[0][0].Text, "Later pages should lead back")
+
+	page, err = repo.ListCollectionLinks(ctx, userID, c.ID, storage.ListOptions{Limit: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+	assert.LessOrEqual(t, len(callbackData(collectionAction, c.ID+":"+page.NextCursor)), 64)
+}
@@ -0,0 +1,233 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/readability"
+	"jetengine/internal/storage"
+)
+
+// Callback actions for reading the offline copy of an article.
+// Each payload is "{action}:{linkID}".
+const (
+	actionArticleText     = "art"
+	actionArticleMarkdown = "artmd"
+	actionArticleHTML     = "arthtml"
+)
+
+// Formats in which an article can be requested with /article.
+const (
+	articleFormatText     = "text"
+	articleFormatMarkdown = "md"
+	articleFormatHTML     = "html"
+)
+
+// maxArticleMessage is the longest article text sent as a message; longer
+// articles are sent as a .txt file instead of being split over many messages.
+const maxArticleMessage = 3800
+
+// articleHandler handles "/article <id> [text|md|html]" by sending the offline
+// copy of a saved link's article.
+func (h *Handler) articleHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/article",
+	})
+	log.Info("Received /article command")
+
+	args := strings.Fields(msg.Text)[1:]
+	if len(args) == 0 || len(args) > 2 {
+		h.sendText(ctx, b, msg.Chat.ID, "Usage: /article <code>&lt;link id&gt;</code> [text|md|html]\n"+
+			"Or tap 📄 <b>Offline copy</b> on a saved link.")
+		return
+	}
+	format := articleFormatText
+	if len(args) == 2 {
+		format = strings.ToLower(args[1])
+	}
+	if format != articleFormatText && format != articleFormatMarkdown && format != articleFormatHTML {
+		h.sendText(ctx, b, msg.Chat.ID, "Unknown format "+html.EscapeString(format)+", use text, md or html.")
+		return
+	}
+
+	if notice := h.sendArticle(ctx, b, msg.Chat.ID, msg.From.ID, args[0], format); notice != "" {
+		h.sendText(ctx, b, msg.Chat.ID, notice)
+	}
+}
+
+// articleTextCallback sends the text of an article from the button on a link card.
+func (h *Handler) articleTextCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	return h.articleCallback(ctx, b, query, id, articleFormatText)
+}
+
+// articleMarkdownCallback sends an article as a Markdown file.
+func (h *Handler) articleMarkdownCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	return h.articleCallback(ctx, b, query, id, articleFormatMarkdown)
+}
+
+// articleHTMLCallback sends an article as an HTML file.
+func (h *Handler) articleHTMLCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	return h.articleCallback(ctx, b, query, id, articleFormatHTML)
+}
+
+// articleCallback sends an article in the given format to the chat of the button.
+func (h *Handler) articleCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id, format string) string {
+	msg := query.Message.Message
+	if msg == nil {
+		return "This message is too old, use /article " + id + " instead."
+	}
+	return h.sendArticle(ctx, b, msg.Chat.ID, query.From.ID, id, format)
+}
+
+// sendArticle sends the offline copy of a link's article in the given format.
+// It returns a user-facing notice when the article cannot be sent.
+func (h *Handler) sendArticle(ctx context.Context, b *tgbot.Bot, chatID, userID int64, id, format string) string {
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"link_id": id,
+		"format":  format,
+	})
+
+	link, err := h.repo.GetLinkByID(ctx, userID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "This link no longer exists."
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to look up link")
+		return "Could not load this link, please try again."
+	}
+	article, err := h.repo.GetArticle(ctx, userID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "No offline copy was saved for this link."
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to load article")
+		return "Could not load the offline copy, please try again."
+	}
+
+	switch format {
+	case articleFormatMarkdown:
+		h.sendFile(ctx, b, chatID, linkFileName(link, ".md"), strings.NewReader(articleMarkdown(link, article)), "")
+	case articleFormatHTML:
+		h.sendFile(ctx, b, chatID, linkFileName(link, ".html"), strings.NewReader(articleDocument(link, article)), "")
+	default:
+		text := articleText(link, article)
+		if len([]rune(text)) > maxArticleMessage {
+			h.sendFile(ctx, b, chatID, linkFileName(link, ".txt"), strings.NewReader(text), "")
+			break
+		}
+		h.sendMessage(ctx, b, chatID, html.EscapeString(text), articleKeyboard(id))
+	}
+	log.Info("Sent offline copy of article")
+	return ""
+}
+
+// sendFile uploads data as a document with an optional HTML caption, logging any failure.
+func (h *Handler) sendFile(ctx context.Context, b *tgbot.Bot, chatID int64, name string, data io.Reader, caption string) {
+	_, err := b.SendDocument(ctx, &tgbot.SendDocumentParams{
+		ChatID:    chatID,
+		Document:  &models.InputFileUpload{Filename: name, Data: data},
+		Caption:   caption,
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		h.log.WithError(err).WithField("chat_id", chatID).Error("Failed to send document")
+	}
+}
+
+// articleKeyboard offers the other formats of an article below its text.
+func articleKeyboard(id string) *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+		{
+			{Text: "⬇ Markdown", CallbackData: callbackData(actionArticleMarkdown, id)},
+			{Text: "⬇ HTML", CallbackData: callbackData(actionArticleHTML, id)},
+		},
+	}}
+}
+
+// articleText renders an article as plain text headed by its title and source.
+func articleText(link domain.Link, article domain.Article) string {
+	return fmt.Sprintf("%s\n%s\n\n%s", linkTitle(link), link.URL, article.Text)
+}
+
+// articleMarkdown renders an article as a Markdown document.
+func articleMarkdown(link domain.Link, article domain.Article) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", linkTitle(link))
+	if byline := formatByline(link); byline != "" {
+		fmt.Fprintf(&sb, "_%s_\n\n", byline)
+	}
+	fmt.Fprintf(&sb, "Source: <%s>  \nSaved: %s\n\n---\n\n", link.URL, article.ExtractedAt.Format("2 Jan 2006"))
+	sb.WriteString(readability.Markdown(article.HTML))
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// articleDocument renders an article as a self-contained HTML page.
+func articleDocument(link domain.Link, article domain.Article) string {
+	title := html.EscapeString(linkTitle(link))
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html")
+	if link.Language != "" {
+		fmt.Fprintf(&sb, ` lang="%s"`, html.EscapeString(link.Language))
+	}
+	fmt.Fprintf(&sb, ">\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", title)
+	sb.WriteString("<style>body{max-width:42em;margin:2em auto;padding:0 1em;font:18px/1.6 Georgia,serif;color:#222}" +
+		"img{max-width:100%}pre{overflow:auto;background:#f4f4f4;padding:1em}blockquote{border-left:3px solid #ccc;margin-left:0;padding-left:1em;color:#555}" +
+		".source{font:14px sans-serif;color:#666}</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n", title)
+	fmt.Fprintf(&sb, "<p class=\"source\">%s<a href=\"%s\">%s</a> · saved %s</p>\n<hr>\n",
+		bylinePrefix(link), html.EscapeString(link.URL), html.EscapeString(link.URL), article.ExtractedAt.Format("2 Jan 2006"))
+	sb.WriteString(article.HTML)
+	sb.WriteString("\n</body>\n</html>\n")
+	return sb.String()
+}
+
+// bylinePrefix returns the escaped byline of a link followed by a separator, or "".
+func bylinePrefix(link domain.Link) string {
+	if byline := formatByline(link); byline != "" {
+		return html.EscapeString(byline) + " · "
+	}
+	return ""
+}
+
+// linkTitle returns the title of a link, or its URL if it has none.
+func linkTitle(link domain.Link) string {
+	if link.Title != "" {
+		return link.Title
+	}
+	return link.URL
+}
+
+// linkFileName derives a file name from the link title, e.g. "why-we-moved-to-badgerdb.md".
+func linkFileName(link domain.Link, ext string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(link.Title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+			dash = false
+		case !dash && sb.Len() > 0:
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	name := strings.Trim(truncate(sb.String(), 60), "-…")
+	if name == "" {
+		name = "article-" + link.ID
+	}
+	return name + ext
+}
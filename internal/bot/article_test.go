@@ -0,0 +1,27 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"jetengine/internal/domain"
+)
+
+// TestArticleFileName tests file names derived from link titles.
+func TestArticleFileName(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "Why we moved to BadgerDB", want: "why-we-moved-to-badgerdb.md"},
+		{title: "  C++ & Go: a comparison!  ", want: "c-go-a-comparison.md"},
+		{title: "Über Größe", want: "über-größe.md"},
+		{title: "", want: "article-abc123.md"},
+		{title: "!!!", want: "article-abc123.md"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, linkFileName(domain.Link{ID: "abc123", Title: tt.title}, ".md"), tt.title)
+	}
+	assert.LessOrEqual(t, len([]rune(linkFileName(domain.Link{Title: "a very long title that goes on and on and on for far longer than any file name should"}, ".md"))), 63)
+}
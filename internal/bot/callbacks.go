@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+)
+
+// callbackFunc handles a callback query for a single action.
+// arg is the callback data after the "{action}:" prefix. The returned text,
+// if any, is shown to the user as a short notification.
+type callbackFunc func(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, arg string) string
+
+// callbackRouter dispatches callback queries to handlers by action name.
+// Callback data has the form "{action}:{arg}" and must fit into Telegram's
+// 64-byte limit, so actions use short names and links are referenced by ID.
+type callbackRouter struct {
+	routes map[string]callbackFunc
+}
+
+// newCallbackRouter creates an empty callback router.
+func newCallbackRouter() *callbackRouter {
+	return &callbackRouter{routes: make(map[string]callbackFunc)}
+}
+
+// handle registers fn for the given action name.
+func (r *callbackRouter) handle(action string, fn callbackFunc) {
+	r.routes[action] = fn
+}
+
+// callbackData builds the callback payload for an action and its argument.
+func callbackData(action, arg string) string {
+	return action + ":" + arg
+}
+
+// callbackHandler is the single entry point for all callback queries.
+func (h *Handler) callbackHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	query := update.CallbackQuery
+	action, arg, _ := strings.Cut(query.Data, ":")
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": query.From.ID,
+		"action":  action,
+	})
+
+	fn, ok := h.callbacks.routes[action]
+	if !ok {
+		log.WithField("callback", query.Data).Warn("Received callback query for unknown action")
+		h.answerCallback(ctx, b, query.ID, "This button is no longer supported.")
+		return
+	}
+
+	log.Debug("Dispatching callback query")
+	h.answerCallback(ctx, b, query.ID, fn(ctx, b, query, arg))
+}
+
+// answerCallback acknowledges a callback query so Telegram stops the loading spinner.
+func (h *Handler) answerCallback(ctx context.Context, b *tgbot.Bot, queryID, text string) {
+	_, err := b.AnswerCallbackQuery(ctx, &tgbot.AnswerCallbackQueryParams{
+		CallbackQueryID: queryID,
+		Text:            text,
+	})
+	if err != nil {
+		h.log.WithError(err).Warn("Failed to answer callback query")
+	}
+}
+
+// editMessage replaces the text and keyboard of the message a callback query came from.
+func (h *Handler) editMessage(ctx context.Context, b *tgbot.Bot, msg *models.Message, text string, markup *models.InlineKeyboardMarkup) {
+	params := &tgbot.EditMessageTextParams{
+		ChatID:             msg.Chat.ID,
+		MessageID:          msg.ID,
+		Text:               text,
+		ParseMode:          models.ParseModeHTML,
+		LinkPreviewOptions: disabledLinkPreview(),
+	}
+	// A nil *InlineKeyboardMarkup inside the interface would be sent as "null".
+	if markup != nil {
+		params.ReplyMarkup = markup
+	}
+	if _, err := b.EditMessageText(ctx, params); err != nil {
+		h.log.WithError(err).WithField("message_id", msg.ID).Warn("Failed to edit message")
+	}
+}
@@ -0,0 +1,218 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/scraper"
+)
+
+// maxCardDescription caps the description length shown in a link card so the
+// reply stays readable and well under Telegram's 4096 character limit.
+const maxCardDescription = 300
+
+// formatLinkCard renders a saved link as an HTML message for Telegram.
+func formatLinkCard(link domain.Link) string {
+	var sb strings.Builder
+
+	title := link.Title
+	if title == "" {
+		title = link.URL
+	}
+	fmt.Fprintf(&sb, "<b>%s</b>\n", html.EscapeString(title))
+
+	switch {
+	case link.Pending:
+		sb.WriteString("<i>⏳ Fetching details…</i>\n")
+	case link.Dead:
+		fmt.Fprintf(&sb, "💀 %s\n", html.EscapeString(formatDeadLink(link)))
+	case link.FetchError != "":
+		fmt.Fprintf(&sb, "⚠ Could not fetch details\n<i>%s</i>\n", html.EscapeString(truncate(link.FetchError, maxCardDescription)))
+	}
+
+	if byline := formatByline(link); byline != "" {
+		fmt.Fprintf(&sb, "<i>%s</i>\n", html.EscapeString(byline))
+	}
+	if details := formatDetails(link.Extra); details != "" {
+		fmt.Fprintf(&sb, "%s\n", html.EscapeString(details))
+	}
+
+	if link.Description != "" {
+		fmt.Fprintf(&sb, "%s\n", html.EscapeString(truncate(link.Description, maxCardDescription)))
+	}
+
+	if len(link.Tags) > 0 {
+		fmt.Fprintf(&sb, "\n🏷 %s", html.EscapeString(formatTags(link.Tags)))
+	}
+	if link.Read {
+		sb.WriteString("\n✓ Read")
+	}
+	if link.ArticleWords > 0 {
+		fmt.Fprintf(&sb, "\n📄 Offline copy · %d min read", readingMinutes(link.ArticleWords))
+	}
+	if len(link.Snapshots) > 0 {
+		kinds := make([]string, len(link.Snapshots))
+		for i, snapshot := range link.Snapshots {
+			kinds[i] = string(snapshot.Kind)
+		}
+		fmt.Fprintf(&sb, "\n🗄 Archived: %s", strings.Join(kinds, ", "))
+	}
+
+	fmt.Fprintf(&sb, "\n%s", html.EscapeString(link.URL))
+	return sb.String()
+}
+
+// formatDeadLink describes why a link is considered dead.
+func formatDeadLink(link domain.Link) string {
+	text := "Page no longer available"
+	if link.HTTPStatus >= 400 {
+		text += fmt.Sprintf(" (HTTP %d)", link.HTTPStatus)
+	}
+	if !link.LastChecked.IsZero() {
+		text += " · checked " + link.LastChecked.Format("2 Jan 2006")
+	}
+	return text
+}
+
+// formatByline joins the site name, author and publication date of a link.
+func formatByline(link domain.Link) string {
+	var parts []string
+	if link.SiteName != "" {
+		parts = append(parts, link.SiteName)
+	}
+	if link.Author != "" {
+		parts = append(parts, link.Author)
+	}
+	if !link.PublishedAt.IsZero() {
+		parts = append(parts, link.PublishedAt.Format("2 Jan 2006"))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// formatDetails renders the site-specific details of a link, such as a video's
+// duration or a repository's stars, on one line.
+func formatDetails(extra map[string]string) string {
+	var parts []string
+	if d, err := time.ParseDuration(extra[scraper.ExtraDuration]); err == nil {
+		parts = append(parts, "▶ "+formatDuration(d))
+	}
+	if v := extra[scraper.ExtraViews]; v != "" {
+		parts = append(parts, formatCount(v)+" views")
+	}
+	if v := extra[scraper.ExtraCommunity]; v != "" {
+		parts = append(parts, v)
+	}
+	if v := extra[scraper.ExtraProgrammingLanguage]; v != "" {
+		parts = append(parts, v)
+	}
+	if v := extra[scraper.ExtraStars]; v != "" {
+		parts = append(parts, "★ "+formatCount(v))
+	}
+	if v := extra[scraper.ExtraForks]; v != "" {
+		parts = append(parts, "⑂ "+formatCount(v))
+	}
+	if v := extra[scraper.ExtraScore]; v != "" {
+		parts = append(parts, "▲ "+formatCount(v))
+	}
+	if v := extra[scraper.ExtraComments]; v != "" {
+		parts = append(parts, "💬 "+formatCount(v))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// formatDuration renders a duration as a clock, e.g. "4:13" or "1:04:13".
+func formatDuration(d time.Duration) string {
+	s := int(d.Round(time.Second).Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// formatCount abbreviates a decimal count, e.g. "12.3k" for "12345".
+// Values that are not integers are returned unchanged.
+func formatCount(s string) string {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return s
+	}
+	switch {
+	case n >= 1_000_000:
+		return strconv.FormatFloat(float64(n)/1e6, 'f', 1, 64) + "M"
+	case n >= 10_000:
+		return strconv.FormatFloat(float64(n)/1e3, 'f', 0, 64) + "k"
+	case n >= 1_000:
+		return strconv.FormatFloat(float64(n)/1e3, 'f', 1, 64) + "k"
+	}
+	return s
+}
+
+// readingMinutes estimates the reading time of an article, rounded up.
+func readingMinutes(words int) int {
+	const wordsPerMinute = 230
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
+
+// formatTags renders tags as space-separated hashtags.
+func formatTags(tags []string) string {
+	hashtags := make([]string, len(tags))
+	for i, tag := range tags {
+		hashtags[i] = "#" + tag
+	}
+	return strings.Join(hashtags, " ")
+}
+
+// linkKeyboard builds the inline action buttons shown under a link card.
+func linkKeyboard(link domain.Link) *models.InlineKeyboardMarkup {
+	id := link.ID
+	readText := "✓ Mark read"
+	if link.Read {
+		readText = "↺ Mark unread"
+	}
+	rows := [][]models.InlineKeyboardButton{
+		{
+			{Text: readText, CallbackData: callbackData(actionToggleRead, id)},
+			{Text: "🏷 Edit tags", CallbackData: callbackData(actionEditTags, id)},
+			{Text: "🗑 Delete", CallbackData: callbackData(actionDelete, id)},
+		},
+	}
+	var archived []models.InlineKeyboardButton
+	if link.ArticleWords > 0 {
+		archived = append(archived, models.InlineKeyboardButton{Text: "📄 Offline copy", CallbackData: callbackData(actionArticleText, id)})
+	}
+	archived = append(archived, models.InlineKeyboardButton{Text: "🗄 Snapshots", CallbackData: callbackData(actionSnapshots, id)})
+	archived = append(archived, models.InlineKeyboardButton{Text: "📁 Collect", CallbackData: callbackData(actionCollect, id)})
+	if !link.Pending {
+		archived = append(archived, models.InlineKeyboardButton{Text: "🔄 Refresh", CallbackData: callbackData(actionRefresh, id)})
+	}
+	rows = append(rows, archived)
+	if suggestions := suggestionRow(link); suggestions != nil {
+		rows = append(rows, suggestions)
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// deleteConfirmKeyboard builds the confirmation buttons for deleting a link.
+func deleteConfirmKeyboard(id string) *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+		{
+			{Text: "Yes, delete", CallbackData: callbackData(actionDeleteConfirm, id)},
+			{Text: "Cancel", CallbackData: callbackData(actionDeleteCancel, id)},
+		},
+	}}
+}
+
+// truncate shortens s to at most n runes, appending an ellipsis when cut.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(r[:n-1])) + "…"
+}
@@ -0,0 +1,376 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// Callback actions for collections.
+const (
+	// collectionAction pages through a collection; payload "coll:{collectionID}:{cursor}",
+	// where an empty cursor is the first page.
+	collectionAction = "coll"
+	// actionCollect shows the collections a link can be added to; payload "collect:{linkID}".
+	actionCollect = "collect"
+	// actionCollectInto adds a link to a collection; payload "collin:{linkID}:{collectionID}".
+	actionCollectInto = "collin"
+)
+
+// collectionsHeading starts the /collections overview, which is kept when a
+// collection is opened from it.
+const collectionsHeading = "Your collections"
+
+// maxCollectionName keeps collection names short enough for buttons and messages.
+const maxCollectionName = 64
+
+// newCollectionHandler handles /newcollection <name>.
+func (h *Handler) newCollectionHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/newcollection",
+	})
+	log.Info("Received /newcollection command")
+
+	name := commandArgs(msg.Text)
+	if name == "" || len([]rune(name)) > maxCollectionName {
+		h.sendText(ctx, b, msg.Chat.ID, fmt.Sprintf("Usage: /newcollection <code>&lt;name&gt;</code>\nNames can be up to %d characters long.", maxCollectionName))
+		return
+	}
+
+	c := domain.Collection{OwnerID: msg.From.ID, Name: name}
+	err := h.repo.CreateCollection(ctx, &c)
+	if errors.Is(err, storage.ErrCollectionExists) {
+		h.sendText(ctx, b, msg.Chat.ID, "You already have a collection named <b>"+html.EscapeString(name)+"</b>.")
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to create collection")
+		h.sendText(ctx, b, msg.Chat.ID, "Could not create the collection, please try again.")
+		return
+	}
+	h.sendText(ctx, b, msg.Chat.ID, fmt.Sprintf("📁 Created <b>%s</b>.\nAdd links with the 📁 Collect button on a link, or with /collect <code>&lt;link id&gt;</code> %s.",
+		html.EscapeString(c.Name), html.EscapeString(c.Name)))
+}
+
+// collectionsHandler handles /collections by listing the user's collections
+// with a button to browse each of them.
+func (h *Handler) collectionsHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/collections",
+	})
+	log.Info("Received /collections command")
+
+	collections, err := h.repo.ListCollections(ctx, msg.From.ID)
+	if err != nil {
+		log.WithError(err).Error("Failed to list collections")
+		h.sendText(ctx, b, msg.Chat.ID, "Sorry, I could not load your collections. Please try again later.")
+		return
+	}
+	if len(collections) == 0 {
+		h.sendText(ctx, b, msg.Chat.ID, "You have no collections yet. Create one with /newcollection <code>&lt;name&gt;</code>.")
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>" + collectionsHeading + "</b>\n\n")
+	var rows [][]models.InlineKeyboardButton
+	for _, c := range collections {
+		fmt.Fprintf(&sb, "📁 %s · %s%s\n", html.EscapeString(c.Name), formatLinkCount(c.LinkCount), formatSharedRole(c))
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: "📁 " + truncate(c.Name, 40), CallbackData: callbackData(collectionAction, c.ID+":")},
+		})
+	}
+	h.sendMessage(ctx, b, msg.Chat.ID, sb.String(), &models.InlineKeyboardMarkup{InlineKeyboard: rows})
+}
+
+// collectionHandler handles /collection <name> by sending the first page of
+// the links in a collection.
+func (h *Handler) collectionHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/collection",
+	})
+	log.Info("Received /collection command")
+
+	c, ok := h.findCollection(ctx, b, msg, commandArgs(msg.Text), "/collection <code>&lt;name&gt;</code>")
+	if !ok {
+		return
+	}
+	text, markup, err := h.renderCollectionPage(ctx, msg.From.ID, c.ID, "")
+	if err != nil {
+		log.WithError(err).Error("Failed to render collection")
+		h.sendText(ctx, b, msg.Chat.ID, "Sorry, I could not load this collection. Please try again later.")
+		return
+	}
+	h.sendMessage(ctx, b, msg.Chat.ID, text, markup)
+}
+
+// collectHandler handles /collect <link id> <collection> by adding a link to a collection.
+func (h *Handler) collectHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	h.changeCollection(ctx, b, update.Message, "/collect", true)
+}
+
+// uncollectHandler handles /uncollect <link id> <collection> by removing a link from a collection.
+func (h *Handler) uncollectHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	h.changeCollection(ctx, b, update.Message, "/uncollect", false)
+}
+
+// changeCollection adds a link to or removes it from the collection named
+// in a /collect or /uncollect command.
+func (h *Handler) changeCollection(ctx context.Context, b *tgbot.Bot, msg *models.Message, command string, add bool) {
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": command,
+	})
+	log.Info("Received " + command + " command")
+
+	usage := command + " <code>&lt;link id&gt;</code> <code>&lt;collection&gt;</code>"
+	linkID, name, _ := strings.Cut(commandArgs(msg.Text), " ")
+	if linkID == "" || strings.TrimSpace(name) == "" {
+		h.sendText(ctx, b, msg.Chat.ID, "Usage: "+usage)
+		return
+	}
+	c, ok := h.findCollection(ctx, b, msg, name, usage)
+	if !ok {
+		return
+	}
+
+	var err error
+	if add {
+		c, err = h.repo.AddToCollection(ctx, msg.From.ID, c.ID, linkID)
+	} else {
+		c, err = h.repo.RemoveFromCollection(ctx, msg.From.ID, c.ID, linkID)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		h.sendText(ctx, b, msg.Chat.ID, "No saved link has the ID <code>"+html.EscapeString(linkID)+"</code>.")
+		return
+	}
+	if errors.Is(err, storage.ErrPermissionDenied) {
+		h.sendText(ctx, b, msg.Chat.ID, "You can only view <b>"+html.EscapeString(c.Name)+"</b>; ask its owner to make you an editor.")
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to update collection")
+		h.sendText(ctx, b, msg.Chat.ID, "Could not update the collection, please try again.")
+		return
+	}
+
+	verb := "Added to"
+	if !add {
+		verb = "Removed from"
+	}
+	h.sendText(ctx, b, msg.Chat.ID, fmt.Sprintf("📁 %s <b>%s</b> · %s", verb, html.EscapeString(c.Name), formatLinkCount(c.LinkCount)))
+}
+
+// deleteCollectionHandler handles /deletecollection <name>. The links in the
+// collection are kept.
+func (h *Handler) deleteCollectionHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/deletecollection",
+	})
+	log.Info("Received /deletecollection command")
+
+	c, ok := h.findCollection(ctx, b, msg, commandArgs(msg.Text), "/deletecollection <code>&lt;name&gt;</code>")
+	if !ok {
+		return
+	}
+	if c.Role != domain.RoleOwner {
+		h.sendText(ctx, b, msg.Chat.ID, "Only the owner can delete <b>"+html.EscapeString(c.Name)+"</b>. Use /leave to leave it.")
+		return
+	}
+	if err := h.repo.DeleteCollection(ctx, msg.From.ID, c.ID); err != nil {
+		log.WithError(err).Error("Failed to delete collection")
+		h.sendText(ctx, b, msg.Chat.ID, "Could not delete the collection, please try again.")
+		return
+	}
+	h.sendText(ctx, b, msg.Chat.ID, "Deleted the collection <b>"+html.EscapeString(c.Name)+"</b>. Its links are still saved.")
+}
+
+// findCollection resolves the collection named in a command, replying with
+// the usage or a notice when there is none. It reports whether one was found.
+func (h *Handler) findCollection(ctx context.Context, b *tgbot.Bot, msg *models.Message, name, usage string) (domain.Collection, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		h.sendText(ctx, b, msg.Chat.ID, "Usage: "+usage)
+		return domain.Collection{}, false
+	}
+	c, err := h.repo.FindCollection(ctx, msg.From.ID, name)
+	if errors.Is(err, storage.ErrCollectionNotFound) {
+		h.sendText(ctx, b, msg.Chat.ID, "You have no collection named <b>"+html.EscapeString(name)+"</b>. See /collections.")
+		return domain.Collection{}, false
+	}
+	if err != nil {
+		h.log.WithError(err).WithField("user_id", msg.From.ID).Error("Failed to find collection")
+		h.sendText(ctx, b, msg.Chat.ID, "Sorry, I could not load your collections. Please try again later.")
+		return domain.Collection{}, false
+	}
+	return c, true
+}
+
+// collectionCallback handles the buttons of /collections and the navigation
+// buttons of a collection page by editing the message in place.
+func (h *Handler) collectionCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, arg string) string {
+	log := h.log.WithField("user_id", query.From.ID)
+
+	id, cursor, ok := strings.Cut(arg, ":")
+	if !ok {
+		log.WithField("arg", arg).Warn("Invalid collection callback data")
+		return "This list is outdated, use /collections again."
+	}
+
+	msg := query.Message.Message
+	if msg == nil {
+		log.Warn("Collection callback message is no longer accessible")
+		return "This message is too old, use /collections again."
+	}
+
+	text, markup, err := h.renderCollectionPage(ctx, query.From.ID, id, cursor)
+	switch {
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		return "This collection no longer exists."
+	case errors.Is(err, storage.ErrInvalidCursor):
+		log.WithField("arg", arg).Warn("Invalid collection cursor")
+		return "This list is outdated, use /collections again."
+	case err != nil:
+		log.WithError(err).Error("Failed to render collection")
+		return "Could not load this collection."
+	}
+
+	if cursor == "" && strings.HasPrefix(msg.Text, collectionsHeading) {
+		// Opened from /collections: keep the overview and send the collection
+		h.sendMessage(ctx, b, msg.Chat.ID, text, markup)
+		return ""
+	}
+	h.editMessage(ctx, b, msg, text, markup)
+	return ""
+}
+
+// renderCollectionPage builds the text and navigation keyboard for the page
+// of a collection's links starting at cursor.
+func (h *Handler) renderCollectionPage(ctx context.Context, userID int64, collectionID, cursor string) (string, *models.InlineKeyboardMarkup, error) {
+	c, err := h.repo.GetCollection(ctx, userID, collectionID)
+	if err != nil {
+		return "", nil, err
+	}
+	page, err := h.repo.ListCollectionLinks(ctx, userID, collectionID, storage.ListOptions{Limit: listPageSize, Cursor: cursor})
+	if err != nil {
+		return "", nil, err
+	}
+
+	heading := fmt.Sprintf("📁 <b>%s</b> · %s%s", html.EscapeString(c.Name), formatLinkCount(c.LinkCount), formatSharedRole(c))
+	if len(page.Links) == 0 {
+		return heading + "\n\nThis collection is empty. Add links with the 📁 Collect button on a link.", nil, nil
+	}
+	return formatLinkList(heading, page.Links, page.PrevCursor != ""), listKeyboard(collectionAction, collectionID, page), nil
+}
+
+// collectCallback replaces the buttons of a link card with the user's
+// collections, so the link can be added to one of them.
+func (h *Handler) collectCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	link, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	all, err := h.repo.ListCollections(ctx, query.From.ID)
+	if err != nil {
+		h.log.WithError(err).WithField("user_id", query.From.ID).Error("Failed to list collections")
+		return "Could not load your collections, please try again."
+	}
+	var collections []domain.Collection
+	for _, c := range all {
+		if c.Role.Allows(domain.RoleEditor) {
+			collections = append(collections, c)
+		}
+	}
+	if len(collections) == 0 {
+		return "You have no collections to add links to. Create one with /newcollection."
+	}
+
+	h.editMessage(ctx, b, msg, formatLinkCard(link)+"\n\n<b>Add to which collection?</b>", collectKeyboard(link, collections))
+	return ""
+}
+
+// collectIntoCallback adds a link to the chosen collection and restores its card.
+func (h *Handler) collectIntoCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, arg string) string {
+	id, collectionID, ok := strings.Cut(arg, ":")
+	if !ok || collectionID == "" {
+		h.log.WithField("arg", arg).Warn("Invalid collect callback data")
+		return ""
+	}
+	link, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	c, err := h.repo.AddToCollection(ctx, query.From.ID, collectionID, id)
+	if errors.Is(err, storage.ErrCollectionNotFound) {
+		return "This collection no longer exists."
+	}
+	if errors.Is(err, storage.ErrPermissionDenied) {
+		return "You can no longer add links to this collection."
+	}
+	if err != nil {
+		h.log.WithError(err).WithFields(logrus.Fields{
+			"user_id":       query.From.ID,
+			"link_id":       id,
+			"collection_id": collectionID,
+		}).Error("Failed to add link to collection")
+		return "Could not add the link, please try again."
+	}
+	h.editMessage(ctx, b, msg, formatLinkCard(link), linkKeyboard(link))
+	return "Added to " + c.Name
+}
+
+// collectKeyboard offers one button per collection, plus a way back to the card.
+func collectKeyboard(link domain.Link, collections []domain.Collection) *models.InlineKeyboardMarkup {
+	var rows [][]models.InlineKeyboardButton
+	for _, c := range collections {
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: "📁 " + truncate(c.Name, 40), CallbackData: callbackData(actionCollectInto, link.ID+":"+c.ID)},
+		})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: "« Back", CallbackData: callbackData(actionShowCard, link.ID)},
+	})
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// formatLinkCount renders a number of links, such as "1 link" or "3 links".
+func formatLinkCount(n int) string {
+	if n == 1 {
+		return "1 link"
+	}
+	return fmt.Sprintf("%d links", n)
+}
+
+// formatSharedRole notes the role of the user in a collection shared with
+// them, or returns "" for their own collections.
+func formatSharedRole(c domain.Collection) string {
+	if c.Role == domain.RoleOwner || c.Role == "" {
+		return ""
+	}
+	return " · shared, " + string(c.Role)
+}
+
+// commandArgs returns the text after the command of a message, trimmed.
+func commandArgs(text string) string {
+	_, args, _ := strings.Cut(strings.TrimSpace(text), " ")
+	return strings.TrimSpace(args)
+}
@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"jetengine/internal/domain"
+)
+
+// TestCollectKeyboard tests the collection picker shown on a link card.
+func TestCollectKeyboard(t *testing.T) {
+	link := domain.Link{ID: "abc"}
+	collections := []domain.Collection{{ID: "c1", Name: "Research"}, {ID: "c2", Name: "Onboarding"}}
+
+	rows := collectKeyboard(link, collections).InlineKeyboard
+	if assert.Len(t, rows, 3) {
+		assert.Equal(t, "📁 Research", rows[0][0].Text)
+		assert.Equal(t, "collin:abc:c1", rows[0][0].CallbackData)
+		assert.Equal(t, "collin:abc:c2", rows[1][0].CallbackData)
+		assert.Equal(t, "card:abc", rows[2][0].CallbackData)
+	}
+}
+
+// TestCommandArgs tests splitting the arguments off a command.
+func TestCommandArgs(t *testing.T) {
+	assert.Equal(t, "Q3 research", commandArgs("/collection  Q3 research "))
+	assert.Equal(t, "", commandArgs("/collections"))
+	assert.Equal(t, "1 link", formatLinkCount(1))
+	assert.Equal(t, "0 links", formatLinkCount(0))
+}
+
+// TestParseInviteArgs tests the optional role of /invite.
+func TestParseInviteArgs(t *testing.T) {
+	name, role := parseInviteArgs("Q3 research editor")
+	assert.Equal(t, "Q3 research", name)
+	assert.Equal(t, domain.RoleEditor, role)
+
+	name, role = parseInviteArgs("Reading list")
+	assert.Equal(t, "Reading list", name)
+	assert.Equal(t, domain.RoleViewer, role, "Invitees should be viewers by default")
+}
@@ -0,0 +1,34 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+)
+
+// LinkDead tells the user that the page of one of their links is gone, in
+// the chat the link was saved from.
+func (h *Handler) LinkDead(ctx context.Context, link domain.Link) {
+	text := "One of your saved links seems to be gone:\n\n" + formatLinkCard(link)
+	if link.ArticleWords > 0 || len(link.Snapshots) > 0 {
+		text += "\n\nYou can still read what was archived when you saved it."
+	}
+	chatID := notifyChat(link)
+	h.sendMessage(ctx, h.bot, chatID, text, linkKeyboard(link))
+	h.log.WithFields(logrus.Fields{
+		"user_id": link.UserID,
+		"link_id": link.ID,
+		"chat_id": chatID,
+	}).Info("Notified user about dead link")
+}
+
+// notifyChat returns the chat to notify about a link: the chat it was saved
+// from, or else the private chat with its owner, whose ID is the user ID.
+func notifyChat(link domain.Link) int64 {
+	if link.ChatID != 0 {
+		return link.ChatID
+	}
+	return link.UserID
+}
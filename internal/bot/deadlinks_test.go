@@ -0,0 +1,15 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"jetengine/internal/domain"
+)
+
+// TestNotifyChat tests that notifications go to the chat a link was saved from.
+func TestNotifyChat(t *testing.T) {
+	assert.Equal(t, int64(-100123), notifyChat(domain.Link{UserID: 42, ChatID: -100123}))
+	assert.Equal(t, int64(42), notifyChat(domain.Link{UserID: 42}), "Links saved elsewhere should fall back to the private chat")
+}
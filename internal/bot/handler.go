@@ -2,29 +2,48 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html"
+	"strings"
+	"time"
 
 	tgbot "github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/sirupsen/logrus"
 
+	"jetengine/internal/archive"
 	"jetengine/internal/config"
+	"jetengine/internal/domain"
+	"jetengine/internal/queue"
 	"jetengine/internal/scraper"
 	"jetengine/internal/storage"
 )
 
 // Handler holds dependencies for the Telegram bot handlers.
 type Handler struct {
-	bot     *tgbot.Bot
-	cfg     config.Config
-	repo    storage.Repository
-	scraper scraper.Scraper
-	log     logrus.FieldLogger
+	bot      *tgbot.Bot
+	cfg      config.Config
+	repo     storage.Repository
+	scraper  scraper.Scraper
+	archiver *archive.Archiver
+	queue    *queue.Queue
+	log      logrus.FieldLogger
+
+	// username is the bot's username without "@", so commands addressed to
+	// other bots in a group are ignored. It is empty if it is unknown.
+	username  string
+	callbacks *callbackRouter
 }
 
 // NewHandler creates a new bot handler instance.
-func NewHandler(cfg config.Config, repo storage.Repository, scraper scraper.Scraper, logger logrus.FieldLogger) (*Handler, error) {
+// Saved links are scraped by the jobs on jobQueue, see ScrapeJobs.
+// The archiver may be nil, in which case snapshots are not offered.
+func NewHandler(cfg config.Config, repo storage.Repository, scraper scraper.Scraper, jobQueue *queue.Queue, archiver *archive.Archiver, logger logrus.FieldLogger) (*Handler, error) {
 	log := logger.WithField("component", "bot_handler")
+	if repo == nil || scraper == nil || jobQueue == nil {
+		return nil, errors.New("bot handler requires a repository, scraper and job queue")
+	}
 
 	// Create the bot instance (without default handler for now)
 	b, err := tgbot.New(cfg.TelegramBotToken)
@@ -34,18 +53,33 @@ func NewHandler(cfg config.Config, repo storage.Repository, scraper scraper.Scra
 	}
 
 	h := &Handler{
-		bot:     b,
-		cfg:     cfg,
-		repo:    repo,
-		scraper: scraper,
-		log:     log,
+		bot:       b,
+		cfg:       cfg,
+		repo:      repo,
+		scraper:   scraper,
+		archiver:  archiver,
+		queue:     jobQueue,
+		log:       log,
+		username:  strings.TrimPrefix(cfg.TelegramBotUsername, "@"),
+		callbacks: newCallbackRouter(),
+	}
+	if h.username == "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		me, err := b.GetMe(ctx)
+		cancel()
+		if err != nil {
+			log.WithError(err).Warn("Failed to get bot username, accepting commands addressed to any bot")
+		} else {
+			h.username = me.Username
+		}
 	}
 
 	// Register command handlers
 	h.registerHandlers()
 
-	// Register the default handler for text messages
-	h.bot.RegisterHandler(tgbot.HandlerTypeMessageText, "", tgbot.MatchTypeContains, h.defaultHandler)
+	// Everything else is handled by the default handler; handlers are tried
+	// in order, so it must come last
+	h.bot.RegisterHandlerMatchFunc(isContentMessage, h.defaultHandler)
 
 	log.Info("Telegram bot handler initialized")
 	return h, nil
@@ -53,9 +87,102 @@ func NewHandler(cfg config.Config, repo storage.Repository, scraper scraper.Scra
 
 // registerHandlers sets up the command and message handlers.
 func (h *Handler) registerHandlers() {
-	h.bot.RegisterHandler(tgbot.HandlerTypeMessageText, "/start", tgbot.MatchTypeExact, h.startHandler)
+	h.registerCommand("start", h.startHandler)
 	h.log.Info("Registered /start command handler")
-	// Add more handlers here later (e.g., /mylist)
+	h.registerCommand("mylist", h.myListHandler)
+	h.log.Info("Registered /mylist command handler")
+	h.registerCommand("article", h.articleHandler)
+	h.log.Info("Registered /article command handler")
+	h.registerCommand("snapshot", h.snapshotHandler)
+	h.log.Info("Registered /snapshot command handler")
+	h.registerCommand("search", h.searchHandler)
+	h.log.Info("Registered /search command handler")
+	h.registerCommand("tag", h.tagHandler)
+	h.registerCommand("untag", h.untagHandler)
+	h.registerCommand("tags", h.tagsHandler)
+	h.registerCommand("rename_tag", h.renameTagHandler)
+	h.log.Info("Registered tag command handlers")
+	h.registerCommand("newcollection", h.newCollectionHandler)
+	h.registerCommand("collections", h.collectionsHandler)
+	h.registerCommand("collection", h.collectionHandler)
+	h.registerCommand("collect", h.collectHandler)
+	h.registerCommand("uncollect", h.uncollectHandler)
+	h.registerCommand("deletecollection", h.deleteCollectionHandler)
+	h.registerCommand("invite", h.inviteHandler)
+	h.registerCommand("members", h.membersHandler)
+	h.registerCommand("leave", h.leaveHandler)
+	h.log.Info("Registered collection command handlers")
+	h.registerCommand("token", h.tokenHandler)
+	h.registerCommand("tokens", h.tokensHandler)
+	h.log.Info("Registered API token command handlers")
+
+	// Callback queries from inline keyboards are dispatched by action name
+	h.callbacks.handle(listAction, h.listCallback)
+	h.callbacks.handle(searchAction, h.searchCallback)
+	h.callbacks.handle(collectionAction, h.collectionCallback)
+	h.callbacks.handle(actionToggleRead, h.toggleReadCallback)
+	h.callbacks.handle(actionDelete, h.deleteCallback)
+	h.callbacks.handle(actionDeleteConfirm, h.deleteConfirmCallback)
+	h.callbacks.handle(actionDeleteCancel, h.deleteCancelCallback)
+	h.callbacks.handle(actionEditTags, h.editTagsCallback)
+	h.callbacks.handle(actionRefresh, h.refreshCallback)
+	h.callbacks.handle(actionArticleText, h.articleTextCallback)
+	h.callbacks.handle(actionArticleMarkdown, h.articleMarkdownCallback)
+	h.callbacks.handle(actionArticleHTML, h.articleHTMLCallback)
+	h.callbacks.handle(actionSnapshots, h.snapshotsCallback)
+	h.callbacks.handle(actionSnapshot, h.snapshotCallback)
+	h.callbacks.handle(actionShowCard, h.showCardCallback)
+	h.callbacks.handle(actionAcceptTag, h.acceptTagCallback)
+	h.callbacks.handle(actionDismissTags, h.dismissTagsCallback)
+	h.callbacks.handle(actionCollect, h.collectCallback)
+	h.callbacks.handle(actionCollectInto, h.collectIntoCallback)
+	h.callbacks.handle(actionMemberRole, h.memberRoleCallback)
+	h.callbacks.handle(actionMemberRemove, h.memberRemoveCallback)
+	h.callbacks.handle(actionRevokeToken, h.revokeTokenCallback)
+	h.bot.RegisterHandler(tgbot.HandlerTypeCallbackQueryData, "", tgbot.MatchTypePrefix, h.callbackHandler)
+	h.log.Info("Registered callback query handler")
+}
+
+// registerCommand routes messages starting with /name, or /name@{bot
+// username}, to handler.
+func (h *Handler) registerCommand(name string, handler tgbot.HandlerFunc) {
+	h.bot.RegisterHandlerMatchFunc(func(update *models.Update) bool {
+		if update.Message == nil {
+			return false
+		}
+		command, ok := commandName(update.Message, h.username)
+		return ok && command == name
+	}, handler)
+}
+
+// commandName returns the command a message starts with, without the '/'
+// and an "@username" suffix. ok is false if the message does not start with
+// a command, or if the command is addressed to a bot other than username.
+// An empty username accepts any suffix.
+func commandName(msg *models.Message, username string) (name string, ok bool) {
+	for _, e := range msg.Entities {
+		if e.Type != models.MessageEntityTypeBotCommand || e.Offset != 0 {
+			continue
+		}
+		// Commands are ASCII, so the UTF-16 length of the entity is its byte length
+		if e.Length < 2 || e.Length > len(msg.Text) {
+			return "", false
+		}
+		name, target, addressed := strings.Cut(msg.Text[1:e.Length], "@")
+		if addressed && username != "" && !strings.EqualFold(target, username) {
+			return "", false
+		}
+		return name, true
+	}
+	return "", false
+}
+
+// isContentMessage reports whether an update is a message the default
+// handler looks at: text, or a photo with or without a caption. Stickers and
+// service messages such as members joining a group are ignored.
+func isContentMessage(update *models.Update) bool {
+	msg := update.Message
+	return msg != nil && (msg.Text != "" || msg.Caption != "" || len(msg.Photo) > 0)
 }
 
 // Start begins polling for updates from Telegram.
@@ -66,7 +193,8 @@ func (h *Handler) Start(ctx context.Context) {
 	h.log.Info("Telegram bot polling stopped.")
 }
 
-// startHandler handles the /start command.
+// startHandler handles the /start command. Deep links carry a payload after
+// the command, such as an invite to a shared collection.
 func (h *Handler) startHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
 	userID := update.Message.From.ID
 	log := h.log.WithFields(logrus.Fields{
@@ -75,8 +203,19 @@ func (h *Handler) startHandler(ctx context.Context, b *tgbot.Bot, update *models
 	})
 	log.Info("Received /start command")
 
+	if token, ok := strings.CutPrefix(commandArgs(update.Message.Text), invitePayloadPrefix); ok {
+		h.acceptInvite(ctx, b, update.Message, token)
+		return
+	}
+
 	// Send a welcome message
-	welcomeMessage := "Welcome to JetEngine! Send me a website link, and I'll save its metadata for you."
+	welcomeMessage := "Welcome to JetEngine! Send me a website link, and I'll save its metadata for you.\n" +
+		"Use /mylist to browse your saved links (/mylist unread for the ones you have not read), " +
+		"/search to find them, /article to read the offline copy of one, " +
+		"and /snapshot to get a screenshot, PDF or web archive of a page.\n" +
+		"Add #hashtags after a link to tag it; /tags lists your tags.\n" +
+		"Group links into folders with /newcollection, browse them with /collections and share them with /invite.\n" +
+		"Create a token for the REST API with /token."
 	_, err := b.SendMessage(ctx, &tgbot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
 		Text:   welcomeMessage,
@@ -87,24 +226,120 @@ func (h *Handler) startHandler(ctx context.Context, b *tgbot.Bot, update *models
 	}
 }
 
+// defaultHandler handles any text message that is not a command.
+// It extracts all URLs from the message, scrapes their metadata and saves them.
 func (h *Handler) defaultHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
-	if h.repo == nil || h.scraper == nil || h.log == nil {
-		// Log or handle the error gracefully
-		fmt.Println("Handler dependencies are not initialized")
+	msg := update.Message
+	if msg == nil || msg.From == nil {
+		return
+	}
+
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"chat_id": msg.Chat.ID,
+	})
+
+	if h.handleTagReply(ctx, b, msg) {
+		return
+	}
+
+	urls := extractURLs(msg)
+	if len(urls) == 0 {
+		log.Debug("Received message without URLs")
+		// In groups, most messages are chatter that is not meant for the bot
+		if msg.Chat.Type == models.ChatTypePrivate {
+			h.sendText(ctx, b, msg.Chat.ID, noURLReply(msg))
+		}
+		return
+	}
+	log.WithField("url_count", len(urls)).Info("Received message with URLs")
+
+	// Hashtags in the message tag every link it contains
+	tags := extractHashtags(msg)
+	for _, linkURL := range urls {
+		h.saveURL(ctx, b, msg, linkURL, tags)
+	}
+}
+
+// noURLReply returns the answer to a message without URLs. Photos are
+// only saved through a URL in their caption.
+func noURLReply(msg *models.Message) string {
+	if len(msg.Photo) > 0 {
+		return "I can only save links, not photos. Put the URL of the page in the caption to save it."
+	}
+	return "Send me a URL to save, or use /start or /mylist."
+}
+
+// saveURL saves a placeholder link for a single URL with the given tags,
+// replies with its card and enqueues a job that scrapes the page and fills in
+// the card later. A page saved before keeps its tags and read state, gains
+// the new tags and is only scraped again.
+func (h *Handler) saveURL(ctx context.Context, b *tgbot.Bot, msg *models.Message, linkURL string, tags []string) {
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"url":     linkURL,
+	})
+
+	link := domain.Link{
+		URL:       linkURL,
+		UserID:    msg.From.ID,
+		ChatID:    msg.Chat.ID,
+		Timestamp: time.Now(),
+		Tags:      tags,
+		Pending:   true,
+	}
+	existed, err := h.repo.AddLink(ctx, &link)
+	if err != nil {
+		log.WithError(err).Error("Failed to save link")
+		h.sendText(ctx, b, msg.Chat.ID, "Sorry, I could not save "+html.EscapeString(linkURL)+". Please try again later.")
 		return
 	}
-	// For now, just log that we received a message
-	// In Step 6, this will parse URLs, call the scraper, and save to repo.
-	h.log.WithFields(logrus.Fields{
-		"user_id": update.Message.From.ID,
-		"text":    update.Message.Text,
-	}).Debug("Received unhandled message (default handler)")
-
-	// Optionally, send a placeholder response
-	// _, _ = b.SendMessage(ctx, &tgbot.SendMessageParams{
-	// 	ChatID: update.Message.Chat.ID,
-	// 	Text:   "Send me a URL to save, or use /start or /mylist.",
-	// })
+
+	job := domain.ScrapeJob{
+		UserID:  link.UserID,
+		LinkID:  link.ID,
+		URL:     link.URL,
+		ChatID:  msg.Chat.ID,
+		Refresh: existed,
+	}
+	if card := h.sendMessage(ctx, b, msg.Chat.ID, formatLinkCard(link), linkKeyboard(link)); card != nil {
+		job.MessageID = card.ID
+	}
+	if err := h.queue.Enqueue(ctx, &job); err != nil {
+		log.WithError(err).Error("Failed to enqueue scrape job")
+		h.sendText(ctx, b, msg.Chat.ID, "The link was saved, but I could not start fetching its details. Please send it again later.")
+	}
+}
+
+// sendText sends an HTML-formatted message to a chat, logging any failure.
+func (h *Handler) sendText(ctx context.Context, b *tgbot.Bot, chatID int64, text string) {
+	h.sendMessage(ctx, b, chatID, text, nil)
+}
+
+// sendMessage sends an HTML-formatted message with an optional inline keyboard.
+// It returns the sent message, or nil if sending failed.
+func (h *Handler) sendMessage(ctx context.Context, b *tgbot.Bot, chatID int64, text string, markup *models.InlineKeyboardMarkup) *models.Message {
+	params := &tgbot.SendMessageParams{
+		ChatID:             chatID,
+		Text:               text,
+		ParseMode:          models.ParseModeHTML,
+		LinkPreviewOptions: disabledLinkPreview(),
+	}
+	// A nil *InlineKeyboardMarkup inside the interface would be sent as "null".
+	if markup != nil {
+		params.ReplyMarkup = markup
+	}
+	sent, err := b.SendMessage(ctx, params)
+	if err != nil {
+		h.log.WithError(err).WithField("chat_id", chatID).Error("Failed to send message")
+		return nil
+	}
+	return sent
 }
 
-// TODO: Implement callbackHandler for inline buttons in Step 7
+// disabledLinkPreview returns options that suppress Telegram's link preview,
+// which would otherwise repeat the link card or show only the first link of a list.
+func disabledLinkPreview() *models.LinkPreviewOptions {
+	disabled := true
+	return &models.LinkPreviewOptions{IsDisabled: &disabled}
+}
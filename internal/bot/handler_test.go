@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// command builds a message whose text starts with a bot command of the given length.
+func command(text string, length int) *models.Message {
+	return &models.Message{
+		Text:     text,
+		Entities: []models.MessageEntity{{Type: models.MessageEntityTypeBotCommand, Offset: 0, Length: length}},
+	}
+}
+
+// TestCommandName tests that commands addressed to this bot match and those for other bots do not.
+func TestCommandName(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      *models.Message
+		username string
+		want     string
+		ok       bool
+	}{
+		{"plain", command("/tag abc go", 4), "JetEngineBot", "tag", true},
+		{"addressed to this bot", command("/tag@jetenginebot abc go", 17), "JetEngineBot", "tag", true},
+		{"addressed to another bot", command("/tag@OtherBot abc", 13), "JetEngineBot", "", false},
+		{"unknown username", command("/mylist@AnyBot", 14), "", "mylist", true},
+		{"not at the start", &models.Message{Text: "see /tag", Entities: []models.MessageEntity{{Type: models.MessageEntityTypeBotCommand, Offset: 4, Length: 4}}}, "", "", false},
+		{"no command", &models.Message{Text: "https://example.com"}, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := commandName(tt.msg, tt.username)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestIsContentMessage tests that only text and photo messages reach the default handler.
+func TestIsContentMessage(t *testing.T) {
+	assert.True(t, isContentMessage(&models.Update{Message: &models.Message{Text: "hello"}}))
+	assert.True(t, isContentMessage(&models.Update{Message: &models.Message{Photo: []models.PhotoSize{{FileID: "f"}}}}))
+	assert.False(t, isContentMessage(&models.Update{Message: &models.Message{Sticker: &models.Sticker{FileID: "s"}}}))
+	assert.False(t, isContentMessage(&models.Update{Message: &models.Message{NewChatMembers: []models.User{{ID: 1}}}}))
+	assert.False(t, isContentMessage(&models.Update{CallbackQuery: &models.CallbackQuery{Data: "read:x"}}))
+}
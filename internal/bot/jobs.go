@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/queue"
+	"jetengine/internal/scraper"
+	"jetengine/internal/storage"
+)
+
+// scrapeJobs fills in placeholder links from the job queue and updates their
+// cards in place once the metadata is known.
+type scrapeJobs struct {
+	h *Handler
+}
+
+// ScrapeJobs returns the processor for the scrape jobs enqueued by the handler.
+func (h *Handler) ScrapeJobs() queue.Processor {
+	return scrapeJobs{h: h}
+}
+
+// Process scrapes the page of a job and stores the metadata on its link.
+// Links deleted in the meantime are skipped.
+func (p scrapeJobs) Process(ctx context.Context, job domain.ScrapeJob) error {
+	h := p.h
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": job.UserID,
+		"link_id": job.LinkID,
+		"url":     job.URL,
+	})
+
+	if job.Refresh {
+		ctx = scraper.WithRefresh(ctx)
+	}
+	meta, err := h.scraper.ScrapeMetadata(ctx, job.URL)
+	if err != nil {
+		if scraper.IsPermanentFailure(err) && ctx.Err() == nil {
+			return queue.Permanent(err)
+		}
+		return err
+	}
+
+	// Re-read the link so changes made while scraping, like tags, are kept
+	link, err := h.repo.GetLinkByID(ctx, job.UserID, job.LinkID)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Info("Link was deleted before scraping finished")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	meta.ApplyTo(&link)
+	link.Pending = false
+	link.FetchError = ""
+	link.LastChecked = time.Now()
+	if !job.Refresh {
+		// Suggestions are offered once, on save; a refresh keeps the user's choice
+		link.SuggestedTags = h.suggestTags(ctx, link)
+	}
+	if err := h.repo.UpdateLink(ctx, &link); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// Keep an offline copy of the article; the link itself is saved either way
+	if meta.Article != nil {
+		article := *meta.Article
+		article.UserID = link.UserID
+		article.LinkID = link.ID
+		if err := h.repo.SaveArticle(ctx, &article); err != nil {
+			log.WithError(err).Error("Failed to save article")
+		} else {
+			link.ArticleWords = article.WordCount
+		}
+	}
+
+	p.updateCard(ctx, job, link)
+	return nil
+}
+
+// Dead records on the link that its metadata could not be fetched.
+func (p scrapeJobs) Dead(ctx context.Context, job domain.ScrapeJob) {
+	h := p.h
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": job.UserID,
+		"link_id": job.LinkID,
+	})
+
+	link, err := h.repo.GetLinkByID(ctx, job.UserID, job.LinkID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.WithError(err).Error("Failed to look up link of failed job")
+		}
+		return
+	}
+	link.Pending = false
+	link.FetchError = job.LastError
+	if err := h.repo.UpdateLink(ctx, &link); err != nil {
+		log.WithError(err).Error("Failed to record fetch error on link")
+		return
+	}
+
+	p.updateCard(ctx, job, link)
+}
+
+// updateCard edits the card sent when the job was enqueued, if there is one.
+func (p scrapeJobs) updateCard(ctx context.Context, job domain.ScrapeJob, link domain.Link) {
+	if job.MessageID == 0 {
+		return
+	}
+	card := &models.Message{ID: job.MessageID, Chat: models.Chat{ID: job.ChatID}}
+	p.h.editMessage(ctx, p.h.bot, card, formatLinkCard(link), linkKeyboard(link))
+}
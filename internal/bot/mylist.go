@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// listPageSize is the number of links shown on a single /mylist page.
+const listPageSize = 5
+
+// listAction is the callback action of /mylist navigation buttons.
+// The full payload is "list:{filter}:{cursor}", so the state survives bot
+// restarts; an empty cursor is the first page.
+const listAction = "list"
+
+// listFilters maps the /mylist arguments to their short callback form and read filter.
+var listFilters = map[string]struct {
+	code string
+	read storage.ReadFilter
+}{
+	"":       {"", storage.AnyReadState},
+	"unread": {"u", storage.OnlyUnread},
+	"read":   {"r", storage.OnlyRead},
+}
+
+// myListHandler handles the /mylist command by sending the first page of links.
+// "/mylist unread" and "/mylist read" filter the list by read state.
+func (h *Handler) myListHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/mylist",
+	})
+	log.Info("Received /mylist command")
+
+	_, arg, _ := strings.Cut(strings.TrimSpace(msg.Text), " ")
+	filter, ok := listFilters[strings.ToLower(strings.TrimSpace(arg))]
+	if !ok {
+		h.sendText(ctx, b, msg.Chat.ID, "Usage: /mylist [unread|read]")
+		return
+	}
+
+	text, markup, err := h.renderListPage(ctx, msg.From.ID, filter.code, "")
+	if err != nil {
+		log.WithError(err).Error("Failed to render link list")
+		h.sendText(ctx, b, msg.Chat.ID, "Sorry, I could not load your links. Please try again later.")
+		return
+	}
+
+	h.sendMessage(ctx, b, msg.Chat.ID, text, markup)
+}
+
+// listCallback handles the navigation buttons of a /mylist message by
+// editing the message in place with the requested page.
+func (h *Handler) listCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, arg string) string {
+	log := h.log.WithField("user_id", query.From.ID)
+
+	code, cursor, ok := strings.Cut(arg, ":")
+	if !ok {
+		log.WithField("arg", arg).Warn("Invalid list callback data")
+		return "This list is outdated, use /mylist again."
+	}
+
+	msg := query.Message.Message
+	if msg == nil {
+		log.Warn("List callback message is no longer accessible")
+		return "This message is too old, use /mylist again."
+	}
+
+	text, markup, err := h.renderListPage(ctx, query.From.ID, code, cursor)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		log.WithField("arg", arg).Warn("Invalid list cursor")
+		return "This list is outdated, use /mylist again."
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to render link list")
+		return "Could not load your links."
+	}
+
+	h.editMessage(ctx, b, msg, text, markup)
+	return ""
+}
+
+// renderListPage builds the text and navigation keyboard for the page of a
+// user's links starting at cursor. code is the short form of the read filter.
+func (h *Handler) renderListPage(ctx context.Context, userID int64, code, cursor string) (string, *models.InlineKeyboardMarkup, error) {
+	opts := storage.ListOptions{Limit: listPageSize, Cursor: cursor}
+	for _, f := range listFilters {
+		if f.code == code {
+			opts.Read = f.read
+		}
+	}
+
+	page, err := h.repo.ListLinks(ctx, userID, opts)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list links: %w", err)
+	}
+	if len(page.Links) == 0 {
+		if opts.Read != storage.AnyReadState {
+			return "No links match this filter.", nil, nil
+		}
+		return "You have no saved links yet. Send me a URL to get started.", nil, nil
+	}
+
+	heading := "<b>Your links</b>"
+	switch opts.Read {
+	case storage.OnlyUnread:
+		heading += " (unread)"
+	case storage.OnlyRead:
+		heading += " (read)"
+	}
+	return formatLinkList(heading, page.Links, page.PrevCursor != ""), listKeyboard(listAction, code, page), nil
+}
+
+// formatLinkList renders a page of links under an HTML heading. Each link
+// shows its ID for commands such as /tag and /collect.
+// continued reports whether the page follows earlier ones.
+func formatLinkList(heading string, links []domain.Link, continued bool) string {
+	var sb strings.Builder
+	sb.WriteString(heading)
+	if continued {
+		sb.WriteString(", continued")
+	}
+	sb.WriteString("\n\n")
+	for _, link := range links {
+		title := link.Title
+		if title == "" {
+			title = link.URL
+		}
+		fmt.Fprintf(&sb, "• <a href=\"%s\">%s</a> · <code>%s</code>\n", html.EscapeString(link.URL), html.EscapeString(truncate(title, 80)), link.ID)
+	}
+	return sb.String()
+}
+
+// listKeyboard builds the Prev/Next navigation row for a page of links. The
+// buttons carry "{action}:{state}:{cursor}", where state identifies the list.
+// It returns nil when there is nowhere to go.
+func listKeyboard(action, state string, page storage.LinkPage) *models.InlineKeyboardMarkup {
+	var row []models.InlineKeyboardButton
+	if page.PrevCursor != "" {
+		row = append(row, models.InlineKeyboardButton{Text: "« Prev", CallbackData: callbackData(action, state+":"+page.PrevCursor)})
+	}
+	if page.NextCursor != "" {
+		row = append(row, models.InlineKeyboardButton{Text: "Next »", CallbackData: callbackData(action, state+":"+page.NextCursor)})
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}}
+}
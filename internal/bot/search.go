@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/search"
+	"jetengine/internal/storage"
+)
+
+// searchAction is the callback action of /search navigation buttons; payload
+// "search:{offset}". The query itself is read back from the first line of the
+// results message, which keeps the payload small and survives bot restarts.
+const searchAction = "search"
+
+// searchPrefix starts the first line of a results message, followed by the query.
+const searchPrefix = "🔎 "
+
+// searchUsage explains the /search syntax.
+const searchUsage = "Usage: /search <code>&lt;words&gt;</code> [tag:<code>&lt;tag&gt;</code>] [site:<code>&lt;domain&gt;</code>]\n" +
+	"Finds saved links whose title, description, URL, tags or offline copy contain all the words."
+
+// searchHandler handles the /search command by sending the first page of results.
+func (h *Handler) searchHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/search",
+	})
+	log.Info("Received /search command")
+
+	_, text, _ := strings.Cut(strings.TrimSpace(msg.Text), " ")
+	q := search.ParseQuery(text)
+	if q.IsEmpty() {
+		h.sendText(ctx, b, msg.Chat.ID, searchUsage)
+		return
+	}
+
+	page, markup, err := h.renderSearchPage(ctx, msg.From.ID, q, 0)
+	if err != nil {
+		log.WithError(err).Error("Failed to search links")
+		h.sendText(ctx, b, msg.Chat.ID, "Sorry, the search failed. Please try again later.")
+		return
+	}
+	h.sendMessage(ctx, b, msg.Chat.ID, page, markup)
+}
+
+// searchCallback handles the Prev/Next buttons of a results message by
+// editing it in place with the requested page.
+func (h *Handler) searchCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, arg string) string {
+	log := h.log.WithField("user_id", query.From.ID)
+
+	offset, err := strconv.Atoi(arg)
+	if err != nil || offset < 0 {
+		log.WithField("arg", arg).Warn("Invalid search callback data")
+		return ""
+	}
+
+	msg := query.Message.Message
+	if msg == nil {
+		log.Warn("Search callback message is no longer accessible")
+		return "This message is too old, search again."
+	}
+	firstLine, _, _ := strings.Cut(msg.Text, "\n")
+	text, ok := strings.CutPrefix(firstLine, searchPrefix)
+	q := search.ParseQuery(text)
+	if !ok || q.IsEmpty() {
+		log.Warn("Search query missing from results message")
+		return "This message is too old, search again."
+	}
+
+	page, markup, err := h.renderSearchPage(ctx, query.From.ID, q, offset)
+	if err != nil {
+		log.WithError(err).Error("Failed to search links")
+		return "The search failed."
+	}
+	h.editMessage(ctx, b, msg, page, markup)
+	return ""
+}
+
+// renderSearchPage builds the text and navigation keyboard for the results
+// of q starting at offset.
+func (h *Handler) renderSearchPage(ctx context.Context, userID int64, q search.Query, offset int) (string, *models.InlineKeyboardMarkup, error) {
+	res, err := h.repo.SearchLinks(ctx, userID, q, offset, listPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+	return formatSearchResults(q, res, offset), searchKeyboard(offset, res.Total), nil
+}
+
+// formatSearchResults renders a page of results as an HTML message. The first
+// line holds the query for searchCallback.
+func formatSearchResults(q search.Query, res storage.SearchResult, offset int) string {
+	var sb strings.Builder
+	sb.WriteString(searchPrefix + html.EscapeString(q.String()) + "\n")
+	switch {
+	case res.Total == 0:
+		sb.WriteString("No saved links match.")
+		return sb.String()
+	case res.Total == 1:
+		sb.WriteString("1 link found\n\n")
+	default:
+		fmt.Fprintf(&sb, "%d links found\n\n", res.Total)
+	}
+	for i, link := range res.Links {
+		fmt.Fprintf(&sb, "%d. %s\n", offset+i+1, formatSearchResult(link))
+	}
+	return sb.String()
+}
+
+// formatSearchResult renders one result with its site and ID for /article.
+func formatSearchResult(link domain.Link) string {
+	title := link.Title
+	if title == "" {
+		title = link.URL
+	}
+	line := fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(link.URL), html.EscapeString(truncate(title, 80)))
+	if u, err := url.Parse(link.URL); err == nil && u.Host != "" {
+		line += " · " + html.EscapeString(strings.TrimPrefix(u.Hostname(), "www."))
+	}
+	return line + " · <code>" + link.ID + "</code>"
+}
+
+// searchKeyboard builds the Prev/Next navigation row for a results page.
+// It returns nil when all results fit on one page.
+func searchKeyboard(offset, total int) *models.InlineKeyboardMarkup {
+	var row []models.InlineKeyboardButton
+	if offset > 0 {
+		row = append(row, models.InlineKeyboardButton{Text: "« Prev", CallbackData: callbackData(searchAction, strconv.Itoa(max(offset-listPageSize, 0)))})
+	}
+	if offset+listPageSize < total {
+		row = append(row, models.InlineKeyboardButton{Text: "Next »", CallbackData: callbackData(searchAction, strconv.Itoa(offset+listPageSize))})
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}}
+}
@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/search"
+	"jetengine/internal/storage"
+)
+
+// TestFormatSearchResults tests that the query can be read back from a results message.
+func TestFormatSearchResults(t *testing.T) {
+	q := search.ParseQuery("<b>tips</b> tag:go site:example.com")
+	res := storage.SearchResult{
+		Links: []domain.Link{{ID: "abc", URL: "https://www.example.com/go", Title: "Go tips"}},
+		Total: 6,
+	}
+	text := formatSearchResults(q, res, 5)
+	assert.Contains(t, text, "6. <a href=\"https://www.example.com/go\">Go tips</a> · example.com · <code>abc</code>")
+
+	// Telegram shows the message as plain text, which is what callbacks see
+	firstLine, _, _ := strings.Cut(html.UnescapeString(text), "\n")
+	parsed, ok := strings.CutPrefix(firstLine, searchPrefix)
+	assert.True(t, ok)
+	assert.Equal(t, q, search.ParseQuery(parsed))
+
+	assert.Nil(t, searchKeyboard(0, listPageSize))
+	kb := searchKeyboard(listPageSize, listPageSize+1)
+	if assert.NotNil(t, kb) {
+		assert.Equal(t, "search:0", kb.InlineKeyboard[0][0].CallbackData)
+		assert.Len(t, kb.InlineKeyboard[0], 1)
+	}
+}
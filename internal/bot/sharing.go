@@ -0,0 +1,295 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// invitePayloadPrefix marks /start payloads that redeem a collection invite.
+// Invite links have the form https://t.me/{bot}?start=join_{token}.
+const invitePayloadPrefix = "join_"
+
+// Callback actions for managing the members of a shared collection.
+const (
+	// actionMemberRole changes a member's role; payload "mrole:{collectionID}:{userID}:{e|v}".
+	actionMemberRole = "mrole"
+	// actionMemberRemove removes a member; payload "mkick:{collectionID}:{userID}".
+	actionMemberRemove = "mkick"
+)
+
+// roleCodes maps the short callback form of the roles a member can be given.
+var roleCodes = map[string]domain.CollectionRole{
+	"e": domain.RoleEditor,
+	"v": domain.RoleViewer,
+}
+
+// inviteHandler handles /invite <collection> [editor|viewer] by creating a
+// one-time invite link. Invitees are viewers unless editor is given.
+func (h *Handler) inviteHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/invite",
+	})
+	log.Info("Received /invite command")
+
+	name, role := parseInviteArgs(commandArgs(msg.Text))
+	c, ok := h.findCollection(ctx, b, msg, name, "/invite <code>&lt;collection&gt;</code> [editor|viewer]")
+	if !ok {
+		return
+	}
+
+	invite, err := h.repo.CreateCollectionInvite(ctx, msg.From.ID, c.ID, role, h.cfg.CollectionInviteTTL)
+	if errors.Is(err, storage.ErrPermissionDenied) {
+		h.sendText(ctx, b, msg.Chat.ID, "Only the owner of <b>"+html.EscapeString(c.Name)+"</b> can invite people.")
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to create invite")
+		h.sendText(ctx, b, msg.Chat.ID, "Could not create the invite, please try again.")
+		return
+	}
+	me, err := b.GetMe(ctx)
+	if err != nil {
+		log.WithError(err).Error("Failed to get bot username")
+		h.sendText(ctx, b, msg.Chat.ID, "Could not create the invite, please try again.")
+		return
+	}
+
+	link := fmt.Sprintf("https://t.me/%s?start=%s%s", me.Username, invitePayloadPrefix, invite.Token)
+	h.sendText(ctx, b, msg.Chat.ID, fmt.Sprintf("Invite to 📁 <b>%s</b> as %s:\n%s\n\nThe link works once and expires on %s.",
+		html.EscapeString(c.Name), role, html.EscapeString(link), invite.ExpiresAt.UTC().Format("Jan 2, 15:04 MST")))
+}
+
+// parseInviteArgs splits the arguments of /invite into the collection name
+// and an optional trailing role.
+func parseInviteArgs(args string) (string, domain.CollectionRole) {
+	if i := strings.LastIndex(args, " "); i >= 0 {
+		switch role := domain.CollectionRole(strings.ToLower(args[i+1:])); role {
+		case domain.RoleEditor, domain.RoleViewer:
+			return strings.TrimSpace(args[:i]), role
+		}
+	}
+	return args, domain.RoleViewer
+}
+
+// acceptInvite redeems the invite token of a /start deep link and lets the
+// owner of the collection know who joined.
+func (h *Handler) acceptInvite(ctx context.Context, b *tgbot.Bot, msg *models.Message, token string) {
+	log := h.log.WithField("user_id", msg.From.ID)
+
+	name := displayName(msg.From)
+	c, err := h.repo.AcceptCollectionInvite(ctx, msg.From.ID, name, token)
+	if errors.Is(err, storage.ErrInviteNotFound) || errors.Is(err, storage.ErrCollectionNotFound) {
+		h.sendText(ctx, b, msg.Chat.ID, "This invite has already been used or has expired. Ask for a new one.")
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to accept invite")
+		h.sendText(ctx, b, msg.Chat.ID, "Could not accept the invite, please try again.")
+		return
+	}
+
+	h.sendText(ctx, b, msg.Chat.ID, fmt.Sprintf("You joined 📁 <b>%s</b> as %s. Browse it with /collection %s.",
+		html.EscapeString(c.Name), c.Role, html.EscapeString(c.Name)))
+	if c.OwnerID != msg.From.ID {
+		// Private chats share the ID of the user
+		h.sendText(ctx, b, c.OwnerID, fmt.Sprintf("%s joined 📁 <b>%s</b> as %s.",
+			html.EscapeString(name), html.EscapeString(c.Name), c.Role))
+	}
+}
+
+// displayName returns the name shown for a user in member lists.
+func displayName(user *models.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" && user.Username != "" {
+		name = "@" + user.Username
+	}
+	return name
+}
+
+// membersHandler handles /members <collection> by listing its members. The
+// owner gets buttons to change roles and remove members.
+func (h *Handler) membersHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/members",
+	})
+	log.Info("Received /members command")
+
+	c, ok := h.findCollection(ctx, b, msg, commandArgs(msg.Text), "/members <code>&lt;collection&gt;</code>")
+	if !ok {
+		return
+	}
+	text, markup, err := h.renderMembers(ctx, msg.From.ID, c)
+	if err != nil {
+		log.WithError(err).Error("Failed to list members")
+		h.sendText(ctx, b, msg.Chat.ID, "Sorry, I could not load the members. Please try again later.")
+		return
+	}
+	h.sendMessage(ctx, b, msg.Chat.ID, text, markup)
+}
+
+// renderMembers builds the member list of a collection as seen by userID.
+func (h *Handler) renderMembers(ctx context.Context, userID int64, c domain.Collection) (string, *models.InlineKeyboardMarkup, error) {
+	members, err := h.repo.ListCollectionMembers(ctx, userID, c.ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "👥 <b>%s</b> members\n\n", html.EscapeString(c.Name))
+	var rows [][]models.InlineKeyboardButton
+	for _, m := range members {
+		name := m.Name
+		if name == "" {
+			name = fmt.Sprintf("User %d", m.UserID)
+		}
+		you := ""
+		if m.UserID == userID {
+			you = " (you)"
+		}
+		fmt.Fprintf(&sb, "• %s%s · %s\n", html.EscapeString(name), you, m.Role)
+
+		if c.Role != domain.RoleOwner || m.Role == domain.RoleOwner {
+			continue
+		}
+		state := c.ID + ":" + strconv.FormatInt(m.UserID, 10)
+		short := truncate(name, 16)
+		toggle := models.InlineKeyboardButton{Text: "Make " + short + " editor", CallbackData: callbackData(actionMemberRole, state+":e")}
+		if m.Role == domain.RoleEditor {
+			toggle = models.InlineKeyboardButton{Text: "Make " + short + " viewer", CallbackData: callbackData(actionMemberRole, state+":v")}
+		}
+		rows = append(rows, []models.InlineKeyboardButton{
+			toggle,
+			{Text: "Remove " + short, CallbackData: callbackData(actionMemberRemove, state)},
+		})
+	}
+	if c.Role == domain.RoleOwner {
+		sb.WriteString("\nInvite people with /invite " + html.EscapeString(c.Name) + " [editor|viewer].")
+	} else {
+		sb.WriteString("\nLeave with /leave " + html.EscapeString(c.Name) + ".")
+	}
+
+	if len(rows) == 0 {
+		return sb.String(), nil, nil
+	}
+	return sb.String(), &models.InlineKeyboardMarkup{InlineKeyboard: rows}, nil
+}
+
+// memberRoleCallback changes the role of a member and refreshes the member list.
+func (h *Handler) memberRoleCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, arg string) string {
+	collectionID, rest, _ := strings.Cut(arg, ":")
+	member, code, _ := strings.Cut(rest, ":")
+	memberID, err := strconv.ParseInt(member, 10, 64)
+	if err != nil {
+		h.log.WithField("arg", arg).Warn("Invalid member callback data")
+		return ""
+	}
+	role, ok := roleCodes[code]
+	if !ok {
+		h.log.WithField("arg", arg).Warn("Invalid member callback data")
+		return ""
+	}
+
+	err = h.repo.SetCollectionMemberRole(ctx, query.From.ID, collectionID, memberID, role)
+	if notice := memberNotice(err); notice != "" {
+		return notice
+	}
+	if err != nil {
+		h.log.WithError(err).WithField("user_id", query.From.ID).Error("Failed to change member role")
+		return "Could not change the role, please try again."
+	}
+	return h.refreshMembers(ctx, b, query, collectionID, "Now "+string(role))
+}
+
+// memberRemoveCallback removes a member and refreshes the member list.
+func (h *Handler) memberRemoveCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, arg string) string {
+	collectionID, member, _ := strings.Cut(arg, ":")
+	memberID, err := strconv.ParseInt(member, 10, 64)
+	if err != nil {
+		h.log.WithField("arg", arg).Warn("Invalid member callback data")
+		return ""
+	}
+
+	err = h.repo.RemoveCollectionMember(ctx, query.From.ID, collectionID, memberID)
+	if notice := memberNotice(err); notice != "" {
+		return notice
+	}
+	if err != nil {
+		h.log.WithError(err).WithField("user_id", query.From.ID).Error("Failed to remove member")
+		return "Could not remove the member, please try again."
+	}
+	return h.refreshMembers(ctx, b, query, collectionID, "Member removed")
+}
+
+// memberNotice translates the expected errors of member changes into a
+// notification text, or returns "" for other errors.
+func memberNotice(err error) string {
+	switch {
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		return "This collection no longer exists."
+	case errors.Is(err, storage.ErrMemberNotFound):
+		return "This person is no longer a member."
+	case errors.Is(err, storage.ErrPermissionDenied):
+		return "Only the owner can manage members."
+	}
+	return ""
+}
+
+// refreshMembers edits a member list in place and returns notice.
+func (h *Handler) refreshMembers(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, collectionID, notice string) string {
+	msg := query.Message.Message
+	if msg == nil {
+		return notice
+	}
+	c, err := h.repo.GetCollection(ctx, query.From.ID, collectionID)
+	if err != nil {
+		return "This collection no longer exists."
+	}
+	text, markup, err := h.renderMembers(ctx, query.From.ID, c)
+	if err != nil {
+		h.log.WithError(err).WithField("user_id", query.From.ID).Error("Failed to list members")
+		return notice
+	}
+	h.editMessage(ctx, b, msg, text, markup)
+	return notice
+}
+
+// leaveHandler handles /leave <collection>, removing the user from a
+// collection shared with them together with the links they added.
+func (h *Handler) leaveHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/leave",
+	})
+	log.Info("Received /leave command")
+
+	c, ok := h.findCollection(ctx, b, msg, commandArgs(msg.Text), "/leave <code>&lt;collection&gt;</code>")
+	if !ok {
+		return
+	}
+	if c.Role == domain.RoleOwner {
+		h.sendText(ctx, b, msg.Chat.ID, "You own <b>"+html.EscapeString(c.Name)+"</b>. Use /deletecollection to remove it.")
+		return
+	}
+	if err := h.repo.RemoveCollectionMember(ctx, msg.From.ID, c.ID, msg.From.ID); err != nil {
+		log.WithError(err).Error("Failed to leave collection")
+		h.sendText(ctx, b, msg.Chat.ID, "Could not leave the collection, please try again.")
+		return
+	}
+	h.sendText(ctx, b, msg.Chat.ID, "You left 📁 <b>"+html.EscapeString(c.Name)+"</b>.")
+}
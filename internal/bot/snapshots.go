@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// Callback actions for archiving snapshots of a link.
+const (
+	// actionSnapshots shows the snapshot choices for a link; payload "snaps:{linkID}".
+	actionSnapshots = "snaps"
+	// actionSnapshot sends one snapshot, capturing it first if needed; payload "snap:{kind}:{linkID}".
+	actionSnapshot = "snap"
+	// actionShowCard restores the regular link card; payload "card:{linkID}".
+	actionShowCard = "card"
+)
+
+// snapshotLabels are the button labels of the snapshot kinds.
+var snapshotLabels = map[domain.SnapshotKind]string{
+	domain.SnapshotScreenshot: "📸 Screenshot",
+	domain.SnapshotPDF:        "📑 PDF",
+	domain.SnapshotMHTML:      "🗂 Web archive (MHTML)",
+}
+
+// snapshotExtensions are the file extensions of the snapshot kinds.
+var snapshotExtensions = map[domain.SnapshotKind]string{
+	domain.SnapshotScreenshot: ".png",
+	domain.SnapshotPDF:        ".pdf",
+	domain.SnapshotMHTML:      ".mhtml",
+}
+
+// snapshotHandler handles "/snapshot <id> [screenshot|pdf|mhtml]" by sending
+// an archived snapshot of a saved link.
+func (h *Handler) snapshotHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/snapshot",
+	})
+	log.Info("Received /snapshot command")
+
+	args := strings.Fields(msg.Text)[1:]
+	if len(args) == 0 || len(args) > 2 {
+		h.sendText(ctx, b, msg.Chat.ID, "Usage: /snapshot <code>&lt;link id&gt;</code> [screenshot|pdf|mhtml]\n"+
+			"Or tap 🗄 <b>Snapshots</b> on a saved link.")
+		return
+	}
+	kind := domain.SnapshotScreenshot
+	if len(args) == 2 {
+		kind = domain.SnapshotKind(strings.ToLower(args[1]))
+	}
+	if _, ok := snapshotLabels[kind]; !ok {
+		h.sendText(ctx, b, msg.Chat.ID, "Unknown snapshot kind "+html.EscapeString(string(kind))+", use screenshot, pdf or mhtml.")
+		return
+	}
+
+	h.sendSnapshot(ctx, b, msg.Chat.ID, msg.From.ID, args[0], kind)
+}
+
+// snapshotsCallback replaces the buttons of a link card with the snapshot choices.
+func (h *Handler) snapshotsCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	if h.archiver == nil {
+		return "Snapshots are not available."
+	}
+	link, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	h.editMessage(ctx, b, msg, formatLinkCard(link)+"\n\n<b>Which snapshot do you want?</b>", snapshotKeyboard(link))
+	return ""
+}
+
+// snapshotCallback sends a snapshot in the background, since capturing a page
+// can take longer than Telegram waits for a callback answer.
+func (h *Handler) snapshotCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, arg string) string {
+	if h.archiver == nil {
+		return "Snapshots are not available."
+	}
+	kindArg, id, _ := strings.Cut(arg, ":")
+	kind := domain.SnapshotKind(kindArg)
+	if _, ok := snapshotLabels[kind]; !ok {
+		h.log.WithField("arg", arg).Warn("Invalid snapshot callback data")
+		return ""
+	}
+	link, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	go h.sendSnapshot(ctx, b, msg.Chat.ID, query.From.ID, id, kind)
+	if _, ok := link.Snapshot(kind); ok {
+		return "Sending snapshot…"
+	}
+	return "Capturing snapshot, this can take a moment…"
+}
+
+// showCardCallback restores the regular buttons of a link card.
+func (h *Handler) showCardCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	link, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	h.editMessage(ctx, b, msg, formatLinkCard(link), linkKeyboard(link))
+	return ""
+}
+
+// sendSnapshot sends a snapshot of a link as a document, capturing it first
+// if the link has none of that kind yet.
+func (h *Handler) sendSnapshot(ctx context.Context, b *tgbot.Bot, chatID, userID int64, id string, kind domain.SnapshotKind) {
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"link_id": id,
+		"kind":    kind,
+	})
+	if h.archiver == nil {
+		h.sendText(ctx, b, chatID, "Snapshots are not available.")
+		return
+	}
+
+	link, snapshot, err := h.archiver.Snapshot(ctx, userID, id, kind)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.sendText(ctx, b, chatID, "This link no longer exists.")
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to archive snapshot")
+		h.sendText(ctx, b, chatID, "Could not capture a snapshot of this page, please try again later.")
+		return
+	}
+
+	content, err := h.archiver.Open(snapshot)
+	if err != nil {
+		log.WithError(err).Error("Failed to open snapshot")
+		h.sendText(ctx, b, chatID, "Could not load the snapshot, please try again.")
+		return
+	}
+	defer content.Close()
+
+	h.sendFile(ctx, b, chatID, linkFileName(link, snapshotExtensions[kind]), content, formatSnapshotCaption(link, snapshot))
+	log.Info("Sent snapshot")
+}
+
+// snapshotKeyboard builds the snapshot choices for a link. Kinds that were
+// already captured are marked, and are sent without capturing them again.
+func snapshotKeyboard(link domain.Link) *models.InlineKeyboardMarkup {
+	var rows [][]models.InlineKeyboardButton
+	for _, kind := range domain.SnapshotKinds {
+		label := snapshotLabels[kind]
+		if _, ok := link.Snapshot(kind); ok {
+			label += " ✓"
+		}
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: label, CallbackData: callbackData(actionSnapshot, string(kind)+":"+link.ID)},
+		})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: "« Back", CallbackData: callbackData(actionShowCard, link.ID)},
+	})
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// formatSnapshotCaption describes a snapshot document.
+func formatSnapshotCaption(link domain.Link, snapshot domain.Snapshot) string {
+	return fmt.Sprintf("%s of <a href=\"%s\">%s</a>\nCaptured %s",
+		snapshotLabels[snapshot.Kind], html.EscapeString(link.URL), html.EscapeString(truncate(linkTitle(link), 200)),
+		snapshot.CapturedAt.Format("2 Jan 2006 15:04"))
+}
@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+	"jetengine/internal/tagger"
+)
+
+// Callback actions for tag suggestions on a link card.
+const (
+	// actionAcceptTag adds a suggested tag; payload "tagok:{linkID}:{tag}".
+	actionAcceptTag = "tagok"
+	// actionDismissTags hides the suggestions of a link; payload "tagnone:{linkID}".
+	actionDismissTags = "tagnone"
+)
+
+const (
+	// maxSuggestions is the number of tag buttons offered on a card.
+	maxSuggestions = 3
+	// suggestionHistory caps how many of the user's newest links the tagger learns from.
+	suggestionHistory = 500
+)
+
+// suggestTags proposes tags for a freshly scraped link from its content and
+// the user's tagging history. Failures only cost the suggestions.
+func (h *Handler) suggestTags(ctx context.Context, link domain.Link) []string {
+	var history []domain.Link
+	opts := storage.ListOptions{Limit: 100}
+	for len(history) < suggestionHistory {
+		page, err := h.repo.ListLinks(ctx, link.UserID, opts)
+		if err != nil {
+			h.log.WithError(err).WithField("user_id", link.UserID).Warn("Failed to load links for tag suggestions")
+			return nil
+		}
+		history = append(history, page.Links...)
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	var tags []string
+	for _, tag := range tagger.Suggest(link, history, maxSuggestions) {
+		// Tags too long for the button payload are left out
+		if len(callbackData(actionAcceptTag, link.ID+":"+tag)) <= 64 {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// openSuggestions returns the suggested tags the link does not have yet.
+func openSuggestions(link domain.Link) []string {
+	var open []string
+	for _, tag := range link.SuggestedTags {
+		if !slices.Contains(link.Tags, tag) {
+			open = append(open, tag)
+		}
+	}
+	return open
+}
+
+// suggestionRow builds the buttons offering a link's open tag suggestions,
+// or nil if there are none.
+func suggestionRow(link domain.Link) []models.InlineKeyboardButton {
+	open := openSuggestions(link)
+	if len(open) == 0 || link.Pending {
+		return nil
+	}
+	var row []models.InlineKeyboardButton
+	for _, tag := range open {
+		row = append(row, models.InlineKeyboardButton{Text: "+ #" + tag, CallbackData: callbackData(actionAcceptTag, link.ID+":"+tag)})
+	}
+	return append(row, models.InlineKeyboardButton{Text: "✕", CallbackData: callbackData(actionDismissTags, link.ID)})
+}
+
+// acceptTagCallback adds a suggested tag to a link and refreshes its card.
+func (h *Handler) acceptTagCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, arg string) string {
+	id, tag, ok := strings.Cut(arg, ":")
+	if !ok || tag == "" {
+		h.log.WithField("arg", arg).Warn("Invalid tag suggestion callback data")
+		return ""
+	}
+	_, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	link, err := h.repo.TagLink(ctx, query.From.ID, id, []string{tag}, nil)
+	if err != nil {
+		h.log.WithError(err).WithFields(logrus.Fields{
+			"user_id": query.From.ID,
+			"link_id": id,
+		}).Error("Failed to accept suggested tag")
+		return "Could not add the tag, please try again."
+	}
+	h.editMessage(ctx, b, msg, formatLinkCard(link), linkKeyboard(link))
+	return "Tagged #" + tag
+}
+
+// dismissTagsCallback drops the open tag suggestions of a link.
+func (h *Handler) dismissTagsCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, id string) string {
+	_, msg, notice := h.linkActionLookup(ctx, query, id)
+	if notice != "" {
+		return notice
+	}
+
+	link, err := h.repo.DismissTagSuggestions(ctx, query.From.ID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "This link no longer exists."
+	}
+	if err != nil {
+		h.log.WithError(err).WithFields(logrus.Fields{
+			"user_id": query.From.ID,
+			"link_id": id,
+		}).Error("Failed to dismiss tag suggestions")
+		return "Could not update this link, please try again."
+	}
+	h.editMessage(ctx, b, msg, formatLinkCard(link), linkKeyboard(link))
+	return ""
+}
@@ -0,0 +1,24 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"jetengine/internal/domain"
+)
+
+// TestSuggestionRow tests that only open suggestions are offered.
+func TestSuggestionRow(t *testing.T) {
+	link := domain.Link{ID: "abc", Tags: []string{"go"}, SuggestedTags: []string{"go", "perf"}}
+	row := suggestionRow(link)
+	if assert.Len(t, row, 2) {
+		assert.Equal(t, "+ #perf", row[0].Text)
+		assert.Equal(t, "tagok:abc:perf", row[0].CallbackData)
+		assert.Equal(t, "tagnone:abc", row[1].CallbackData)
+	}
+
+	link.Tags = append(link.Tags, "perf")
+	assert.Nil(t, suggestionRow(link), "Accepted suggestions should disappear")
+	assert.Len(t, linkKeyboard(link).InlineKeyboard, 2)
+}
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/storage"
+)
+
+// hashtagPattern matches hashtags that start the text or follow whitespace,
+// so fragments like https://example.com/#top are not taken for tags.
+var hashtagPattern = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_-]+)`)
+
+// extractHashtags collects the normalized hashtags of a message, which are
+// applied as tags to the links saved from it.
+func extractHashtags(msg *models.Message) []string {
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+	var words []string
+	for _, m := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		words = append(words, m[1])
+	}
+	return parseTags(strings.Join(words, " "))
+}
+
+// tagHandler handles /tag <link id> <tags...> by adding tags to a link.
+func (h *Handler) tagHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	h.changeTags(ctx, b, update.Message, "/tag", true)
+}
+
+// untagHandler handles /untag <link id> <tags...> by removing tags from a link.
+func (h *Handler) untagHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	h.changeTags(ctx, b, update.Message, "/untag", false)
+}
+
+// changeTags adds or removes the tags given to a /tag or /untag command and
+// replies with the updated link card.
+func (h *Handler) changeTags(ctx context.Context, b *tgbot.Bot, msg *models.Message, command string, add bool) {
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": command,
+	})
+	log.Info("Received " + command + " command")
+
+	args := strings.Fields(msg.Text)[1:]
+	var tags []string
+	if len(args) > 1 {
+		tags = parseTags(strings.Join(args[1:], " "))
+	}
+	if len(tags) == 0 {
+		h.sendText(ctx, b, msg.Chat.ID, "Usage: "+command+" <code>&lt;link id&gt;</code> <code>&lt;tag&gt;</code>...")
+		return
+	}
+
+	var addTags, removeTags []string
+	if add {
+		addTags = tags
+	} else {
+		removeTags = tags
+	}
+	link, err := h.repo.TagLink(ctx, msg.From.ID, args[0], addTags, removeTags)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.sendText(ctx, b, msg.Chat.ID, "No saved link has the ID <code>"+html.EscapeString(args[0])+"</code>.")
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to update link tags")
+		h.sendText(ctx, b, msg.Chat.ID, "Could not save the tags, please try again.")
+		return
+	}
+	h.sendMessage(ctx, b, msg.Chat.ID, formatLinkCard(link), linkKeyboard(link))
+}
+
+// tagsHandler handles /tags by listing the user's tags with their link counts.
+func (h *Handler) tagsHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/tags",
+	})
+	log.Info("Received /tags command")
+
+	tags, err := h.repo.Tags(ctx, msg.From.ID)
+	if err != nil {
+		log.WithError(err).Error("Failed to list tags")
+		h.sendText(ctx, b, msg.Chat.ID, "Sorry, I could not load your tags. Please try again later.")
+		return
+	}
+	h.sendText(ctx, b, msg.Chat.ID, formatTagList(tags))
+}
+
+// formatTagList renders a user's tags as an HTML message.
+func formatTagList(tags []storage.TagCount) string {
+	if len(tags) == 0 {
+		return "You have not tagged any links yet. Add #hashtags when saving a link, or use /tag."
+	}
+	var sb strings.Builder
+	sb.WriteString("<b>Your tags</b>\n\n")
+	for _, t := range tags {
+		noun := "links"
+		if t.Links == 1 {
+			noun = "link"
+		}
+		fmt.Fprintf(&sb, "#%s · %d %s\n", html.EscapeString(t.Tag), t.Links, noun)
+	}
+	sb.WriteString("\nUse /search tag:<code>&lt;tag&gt;</code> to see the links with a tag.")
+	return sb.String()
+}
+
+// renameTagHandler handles /rename_tag <old> <new>, merging the tags if the new one exists.
+func (h *Handler) renameTagHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/rename_tag",
+	})
+	log.Info("Received /rename_tag command")
+
+	args := parseTags(strings.Join(strings.Fields(msg.Text)[1:], " "))
+	if len(args) != 2 {
+		h.sendText(ctx, b, msg.Chat.ID, "Usage: /rename_tag <code>&lt;old&gt;</code> <code>&lt;new&gt;</code>\n"+
+			"If the new tag is already in use, the two are merged.")
+		return
+	}
+	from, to := args[0], args[1]
+
+	n, err := h.repo.RenameTag(ctx, msg.From.ID, from, to)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.sendText(ctx, b, msg.Chat.ID, "None of your links is tagged #"+html.EscapeString(from)+".")
+		return
+	}
+	if errors.Is(err, storage.ErrTooManyLinks) {
+		h.sendText(ctx, b, msg.Chat.ID, "#"+html.EscapeString(from)+" is on too many links to rename at once.")
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to rename tag")
+		h.sendText(ctx, b, msg.Chat.ID, "Could not rename the tag, please try again.")
+		return
+	}
+	noun := "links"
+	if n == 1 {
+		noun = "link"
+	}
+	h.sendText(ctx, b, msg.Chat.ID, fmt.Sprintf("Renamed #%s to #%s on %d %s.", html.EscapeString(from), html.EscapeString(to), n, noun))
+}
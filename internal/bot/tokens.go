@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// actionRevokeToken revokes a personal API token; payload "tokrevoke:{tokenID}".
+const actionRevokeToken = "tokrevoke"
+
+// maxTokenName limits the length of API token names, in characters.
+const maxTokenName = 32
+
+// tokenDateLayout formats the dates shown in the token list.
+const tokenDateLayout = "Jan 2, 2006"
+
+// tokenHandler handles /token <name> [read|write] by minting a personal API
+// token. Tokens can only read unless write is given. The secret is shown once.
+func (h *Handler) tokenHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/token",
+	})
+	log.Info("Received /token command")
+
+	if msg.Chat.Type != models.ChatTypePrivate {
+		h.sendText(ctx, b, msg.Chat.ID, "For your safety, API tokens can only be created in a private chat with me.")
+		return
+	}
+	name, scopes := parseTokenArgs(commandArgs(msg.Text))
+	if name == "" {
+		h.sendText(ctx, b, msg.Chat.ID, "Usage: /token <code>&lt;name&gt;</code> [read|write]\nTokens can only read unless you add write.")
+		return
+	}
+	if len([]rune(name)) > maxTokenName {
+		h.sendText(ctx, b, msg.Chat.ID, fmt.Sprintf("Token names can be at most %d characters long.", maxTokenName))
+		return
+	}
+
+	token := domain.APIToken{UserID: msg.From.ID, Name: name, Scopes: scopes}
+	if h.cfg.APITokenTTL > 0 {
+		token.ExpiresAt = time.Now().Add(h.cfg.APITokenTTL)
+	}
+	secret, err := h.repo.CreateToken(ctx, &token)
+	if err != nil {
+		log.WithError(err).Error("Failed to create API token")
+		h.sendText(ctx, b, msg.Chat.ID, "Could not create the token, please try again.")
+		return
+	}
+
+	expiry := "It never expires."
+	if !token.ExpiresAt.IsZero() {
+		expiry = "It expires on " + token.ExpiresAt.UTC().Format(tokenDateLayout) + "."
+	}
+	h.sendText(ctx, b, msg.Chat.ID, fmt.Sprintf("🔑 API token <b>%s</b> (%s):\n<code>%s</code>\n\n"+
+		"Send it as <code>Authorization: Bearer …</code>. It is shown only this once, so store it safely. %s\n"+
+		"See and revoke your tokens with /tokens.",
+		html.EscapeString(name), formatScopes(token.Scopes), secret, expiry))
+}
+
+// parseTokenArgs splits the arguments of /token into the token name and the
+// scopes given by an optional trailing read or write. Write implies read.
+func parseTokenArgs(args string) (string, []domain.TokenScope) {
+	name, scopes := args, []domain.TokenScope{domain.ScopeRead}
+	if i := strings.LastIndex(args, " "); i >= 0 {
+		switch domain.TokenScope(strings.ToLower(args[i+1:])) {
+		case domain.ScopeRead:
+			name = args[:i]
+		case domain.ScopeWrite:
+			name, scopes = args[:i], []domain.TokenScope{domain.ScopeRead, domain.ScopeWrite}
+		}
+	}
+	return strings.TrimSpace(name), scopes
+}
+
+// formatScopes lists scopes for display, such as "read, write".
+func formatScopes(scopes []domain.TokenScope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, ", ")
+}
+
+// tokensHandler handles /tokens by listing the user's API tokens with a
+// button to revoke each.
+func (h *Handler) tokensHandler(ctx context.Context, b *tgbot.Bot, update *models.Update) {
+	msg := update.Message
+	log := h.log.WithFields(logrus.Fields{
+		"user_id": msg.From.ID,
+		"command": "/tokens",
+	})
+	log.Info("Received /tokens command")
+
+	text, markup, err := h.renderTokens(ctx, msg.From.ID)
+	if err != nil {
+		log.WithError(err).Error("Failed to list API tokens")
+		h.sendText(ctx, b, msg.Chat.ID, "Sorry, I could not load your tokens. Please try again later.")
+		return
+	}
+	h.sendMessage(ctx, b, msg.Chat.ID, text, markup)
+}
+
+// renderTokens builds the token list of a user.
+func (h *Handler) renderTokens(ctx context.Context, userID int64) (string, *models.InlineKeyboardMarkup, error) {
+	tokens, err := h.repo.ListTokens(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(tokens) == 0 {
+		return "You have no API tokens. Create one with /token <code>&lt;name&gt;</code> [read|write].", nil, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🔑 <b>Your API tokens</b>\n\n")
+	rows := make([][]models.InlineKeyboardButton, 0, len(tokens))
+	for _, t := range tokens {
+		lastUsed := "never used"
+		if !t.LastUsedAt.IsZero() {
+			lastUsed = "last used " + t.LastUsedAt.UTC().Format(tokenDateLayout)
+		}
+		expiry := "never expires"
+		if !t.ExpiresAt.IsZero() {
+			expiry = "expires " + t.ExpiresAt.UTC().Format(tokenDateLayout)
+		}
+		fmt.Fprintf(&sb, "• <b>%s</b> · %s\n  created %s · %s · %s\n",
+			html.EscapeString(t.Name), formatScopes(t.Scopes), t.CreatedAt.UTC().Format(tokenDateLayout), lastUsed, expiry)
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: "Revoke " + truncate(t.Name, 24), CallbackData: callbackData(actionRevokeToken, t.ID)},
+		})
+	}
+	return sb.String(), &models.InlineKeyboardMarkup{InlineKeyboard: rows}, nil
+}
+
+// revokeTokenCallback revokes an API token and refreshes the token list.
+func (h *Handler) revokeTokenCallback(ctx context.Context, b *tgbot.Bot, query *models.CallbackQuery, arg string) string {
+	log := h.log.WithFields(logrus.Fields{
+		"user_id":  query.From.ID,
+		"token_id": arg,
+	})
+
+	notice := "Token revoked"
+	err := h.repo.RevokeToken(ctx, query.From.ID, arg)
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		notice = "This token was already revoked."
+	} else if err != nil {
+		log.WithError(err).Error("Failed to revoke API token")
+		return "Could not revoke the token, please try again."
+	}
+
+	if msg := query.Message.Message; msg != nil {
+		text, markup, err := h.renderTokens(ctx, query.From.ID)
+		if err != nil {
+			log.WithError(err).Error("Failed to list API tokens")
+			return notice
+		}
+		h.editMessage(ctx, b, msg, text, markup)
+	}
+	return notice
+}
@@ -0,0 +1,23 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"jetengine/internal/domain"
+)
+
+// TestParseTokenArgs tests the optional scope of /token.
+func TestParseTokenArgs(t *testing.T) {
+	name, scopes := parseTokenArgs("home server write")
+	assert.Equal(t, "home server", name)
+	assert.Equal(t, []domain.TokenScope{domain.ScopeRead, domain.ScopeWrite}, scopes, "Write should imply read")
+
+	name, scopes = parseTokenArgs("laptop")
+	assert.Equal(t, "laptop", name)
+	assert.Equal(t, []domain.TokenScope{domain.ScopeRead}, scopes, "Tokens should only read by default")
+
+	name, _ = parseTokenArgs("read")
+	assert.Equal(t, "read", name, "A lone scope is taken as the name")
+}
@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// urlPattern matches bare http(s) URLs in plain text. It is only used as a
+// fallback for messages Telegram did not annotate with entities.
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// extractURLs collects every unique URL from a message, in order of appearance.
+// It looks at the text (or media caption), Telegram's url/text_link entities,
+// and falls back to a regex scan of the raw text. Forwarded messages carry the
+// original text and entities, so they are handled the same way.
+func extractURLs(msg *models.Message) []string {
+	if msg == nil {
+		return nil
+	}
+
+	text, entities := msg.Text, msg.Entities
+	if text == "" {
+		text, entities = msg.Caption, msg.CaptionEntities
+	}
+
+	seen := make(map[string]struct{})
+	var urls []string
+	add := func(raw string) {
+		u, ok := normalizeURL(raw)
+		if !ok {
+			return
+		}
+		if _, dup := seen[u]; dup {
+			return
+		}
+		seen[u] = struct{}{}
+		urls = append(urls, u)
+	}
+
+	for _, e := range entities {
+		switch e.Type {
+		case models.MessageEntityTypeURL:
+			add(entityText(text, e))
+		case models.MessageEntityTypeTextLink:
+			add(e.URL)
+		}
+	}
+
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		add(match)
+	}
+
+	return urls
+}
+
+// entityText returns the substring of text covered by entity e.
+// Telegram reports entity offsets and lengths in UTF-16 code units.
+func entityText(text string, e models.MessageEntity) string {
+	units := utf16.Encode([]rune(text))
+	start, end := e.Offset, e.Offset+e.Length
+	if start < 0 || end > len(units) || start >= end {
+		return ""
+	}
+	return string(utf16.Decode(units[start:end]))
+}
+
+// normalizeURL trims trailing punctuation, adds a scheme to bare hosts
+// (Telegram marks "example.com" as a url entity) and validates the result.
+func normalizeURL(raw string) (string, bool) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), ".,;:!?]}'\"")
+	// Keep a closing parenthesis only when it is balanced, e.g. Wikipedia's Foo_(bar).
+	for strings.HasSuffix(raw, ")") && strings.Count(raw, "(") < strings.Count(raw, ")") {
+		raw = strings.TrimRight(strings.TrimSuffix(raw, ")"), ".,;:!?]}'\"")
+	}
+	if raw == "" {
+		return "", false
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	return u.String(), true
+}
@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExtractURLs tests URL extraction from text, entities and captions.
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *models.Message
+		want []string
+	}{
+		{
+			name: "nil message",
+			msg:  nil,
+			want: nil,
+		},
+		{
+			name: "plain text without entities",
+			msg:  &models.Message{Text: "look at https://example.com/a, and https://example.org."},
+			want: []string{"https://example.com/a", "https://example.org"},
+		},
+		{
+			name: "url entity with utf-16 offsets",
+			msg: &models.Message{
+				Text: "🚀 example.com/rocket",
+				Entities: []models.MessageEntity{
+					{Type: models.MessageEntityTypeURL, Offset: 3, Length: 18},
+				},
+			},
+			want: []string{"https://example.com/rocket"},
+		},
+		{
+			name: "text link entity",
+			msg: &models.Message{
+				Text: "read this",
+				Entities: []models.MessageEntity{
+					{Type: models.MessageEntityTypeTextLink, Offset: 0, Length: 9, URL: "https://example.com/hidden"},
+				},
+			},
+			want: []string{"https://example.com/hidden"},
+		},
+		{
+			name: "duplicates are removed",
+			msg: &models.Message{
+				Text: "https://example.com https://example.com",
+				Entities: []models.MessageEntity{
+					{Type: models.MessageEntityTypeURL, Offset: 0, Length: 19},
+				},
+			},
+			want: []string{"https://example.com"},
+		},
+		{
+			name: "forwarded media caption",
+			msg: &models.Message{
+				ForwardOrigin: &models.MessageOrigin{},
+				Caption:       "via https://en.wikipedia.org/wiki/Go_(programming_language))",
+			},
+			want: []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"},
+		},
+		{
+			name: "non-http schemes are ignored",
+			msg:  &models.Message{Text: "ftp://example.com"},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, extractURLs(tt.msg))
+		})
+	}
+}
+
+// TestExtractHashtags tests that hashtags are found but URL fragments are not.
+func TestExtractHashtags(t *testing.T) {
+	msg := &models.Message{Text: "#Read https://example.com/#top #go #perf, #Go\n#reading-list"}
+	assert.Equal(t, []string{"read", "go", "perf", "reading-list"}, extractHashtags(msg))
+	assert.Empty(t, extractHashtags(&models.Message{Caption: "https://example.com/page#section"}))
+}
+
+// TestNoURLReply tests that photos without a URL get a photo-specific answer.
+func TestNoURLReply(t *testing.T) {
+	photo := &models.Message{Caption: "sunset", Photo: []models.PhotoSize{{FileID: "f"}}}
+	assert.Contains(t, noURLReply(photo), "caption")
+	assert.Equal(t, "Send me a URL to save, or use /start or /mylist.", noURLReply(&models.Message{Text: "hello"}))
+}
@@ -0,0 +1,123 @@
+// Package canonical normalizes URLs so that trivially different spellings of
+// the same page (host case, default ports, fragments, tracking parameters,
+// query order) map to a single canonical form.
+package canonical
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// trackingParams lists query parameters that only identify the referrer or
+// campaign and never change the content of the page.
+var trackingParams = map[string]struct{}{
+	"fbclid":  {},
+	"gclid":   {},
+	"dclid":   {},
+	"gbraid":  {},
+	"wbraid":  {},
+	"msclkid": {},
+	"yclid":   {},
+	"igshid":  {},
+	"mc_cid":  {},
+	"mc_eid":  {},
+	"_ga":     {},
+	"_gl":     {},
+	"ref_src": {},
+	"si":      {},
+}
+
+// trackingPrefixes lists prefixes of tracking query parameter families.
+var trackingPrefixes = []string{"utm_", "pk_", "mtm_"}
+
+// defaultPorts maps schemes to the port that is implied when none is given.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// URL returns the canonical form of rawURL:
+//   - scheme and host are lower-cased, a trailing dot on the host is removed
+//   - default ports (:80 for http, :443 for https) are removed
+//   - an empty path becomes "/"
+//   - the fragment is dropped
+//   - known tracking parameters (utm_*, fbclid, gclid, ...) are removed
+//   - the remaining query parameters are sorted by key
+func URL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid url %q: scheme and host are required", rawURL)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.RawQuery = canonicalQuery(u.Query())
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// canonicalQuery removes tracking parameters and encodes the rest sorted by key.
+// The relative order of repeated values for the same key is preserved.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		if isTrackingParam(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		for _, v := range q[k] {
+			if sb.Len() > 0 {
+				sb.WriteByte('&')
+			}
+			sb.WriteString(url.QueryEscape(k))
+			if v != "" {
+				sb.WriteByte('=')
+				sb.WriteString(url.QueryEscape(v))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// isTrackingParam reports whether a query parameter is a known tracking parameter.
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	if _, ok := trackingParams[key]; ok {
+		return true
+	}
+	for _, prefix := range trackingPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
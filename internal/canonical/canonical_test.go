@@ -0,0 +1,44 @@
+package canonical
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestURL tests the canonicalization rules.
+func TestURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://Example.com/a?utm_source=x#frag", "https://example.com/a"},
+		{"https://example.com/a", "https://example.com/a"},
+		{"HTTP://EXAMPLE.COM:80", "http://example.com/"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+		{"https://example.com./a", "https://example.com/a"},
+		{"https://example.com/a?b=2&a=1&fbclid=abc&gclid=def", "https://example.com/a?a=1&b=2"},
+		{"https://example.com/a?tag=x&tag=a", "https://example.com/a?tag=x&tag=a"},
+		{"https://example.com/a?", "https://example.com/a"},
+		{"https://example.com/Case/Sensitive", "https://example.com/Case/Sensitive"},
+		{"https://[::1]:443/", "https://[::1]/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := URL(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestURL_Invalid tests that URLs without scheme or host are rejected.
+func TestURL_Invalid(t *testing.T) {
+	for _, in := range []string{"", "example.com/a", "https://", "://bad"} {
+		_, err := URL(in)
+		assert.Error(t, err, in)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -12,9 +13,72 @@ import (
 type Config struct {
 	TelegramBotToken string `mapstructure:"TELEGRAM_BOT_TOKEN"`
 	BadgerDBPath     string `mapstructure:"BADGERDB_PATH"`
+	// BlobStorePath is the directory for page snapshots, kept outside the database.
+	BlobStorePath string `mapstructure:"BLOB_STORE_PATH"`
+	// BlobSweepInterval is how often snapshots no link references are deleted (0 disables the sweeper).
+	BlobSweepInterval time.Duration `mapstructure:"BLOB_SWEEP_INTERVAL"`
+
+	// ScraperMaxPages limits how many browser pages are scraped concurrently.
+	ScraperMaxPages int `mapstructure:"SCRAPER_MAX_PAGES"`
+	// ScraperPageTimeout bounds loading and scraping a single page.
+	ScraperPageTimeout time.Duration `mapstructure:"SCRAPER_PAGE_TIMEOUT"`
+	// ScraperExtractArticles enables saving an offline copy of the article body of each link.
+	ScraperExtractArticles bool `mapstructure:"SCRAPER_EXTRACT_ARTICLES"`
+	// ScraperCacheTTL is how long scrape results are shared between users saving the same page (0 disables the cache).
+	ScraperCacheTTL time.Duration `mapstructure:"SCRAPER_CACHE_TTL"`
+	// ScraperHostConcurrency limits how many requests run at once against a single site (0 = unlimited).
+	ScraperHostConcurrency int `mapstructure:"SCRAPER_HOST_CONCURRENCY"`
+	// ScraperHostDelay is the minimum time between two requests to the same site.
+	ScraperHostDelay time.Duration `mapstructure:"SCRAPER_HOST_DELAY"`
+	// ScraperRateLimit is the number of requests per second allowed across all sites (0 = unlimited).
+	ScraperRateLimit float64 `mapstructure:"SCRAPER_RATE_LIMIT"`
+	// ScraperRateBurst is how many requests may exceed ScraperRateLimit at once.
+	ScraperRateBurst int `mapstructure:"SCRAPER_RATE_BURST"`
+	// ScraperRespectRobots skips pages that robots.txt disallows and honors its Crawl-delay.
+	ScraperRespectRobots bool `mapstructure:"SCRAPER_RESPECT_ROBOTS"`
+	// ScraperAllowPrivateNetworks lets the scrapers fetch loopback, private and link-local addresses; keep it off unless all users are trusted.
+	ScraperAllowPrivateNetworks bool `mapstructure:"SCRAPER_ALLOW_PRIVATE_NETWORKS"`
+
+	// QueueWorkers is how many scrape jobs run concurrently in the background.
+	QueueWorkers int `mapstructure:"QUEUE_WORKERS"`
+	// QueueMaxAttempts is how often a scrape job is tried before it is given up.
+	QueueMaxAttempts int `mapstructure:"QUEUE_MAX_ATTEMPTS"`
+	// QueueRetryBackoff is the delay before retrying a failed job; it doubles with every attempt.
+	QueueRetryBackoff time.Duration `mapstructure:"QUEUE_RETRY_BACKOFF"`
+	// QueueMaxBackoff caps the delay between retries.
+	QueueMaxBackoff time.Duration `mapstructure:"QUEUE_MAX_BACKOFF"`
+
+	// RefreshInterval is how often a batch of saved links is re-checked (0 disables the scheduler).
+	RefreshInterval time.Duration `mapstructure:"REFRESH_INTERVAL"`
+	// RefreshMaxAge is how long a link check stays valid before the link is checked again.
+	RefreshMaxAge time.Duration `mapstructure:"REFRESH_MAX_AGE"`
+	// RefreshBatchSize is the maximum number of links checked per run.
+	RefreshBatchSize int `mapstructure:"REFRESH_BATCH_SIZE"`
+	// RefreshDeadAfter is how many failed checks in a row mark a link as dead.
+	RefreshDeadAfter int `mapstructure:"REFRESH_DEAD_AFTER"`
+
+	// CollectionInviteTTL is how long an invite link to a shared collection can be redeemed.
+	CollectionInviteTTL time.Duration `mapstructure:"COLLECTION_INVITE_TTL"`
+
+	// ServerAddr is the listen address of the REST API, such as ":8080" (empty disables the API).
+	ServerAddr string `mapstructure:"SERVER_ADDR"`
+	// ServerShutdownTimeout is how long running API requests may take to finish on shutdown.
+	ServerShutdownTimeout time.Duration `mapstructure:"SERVER_SHUTDOWN_TIMEOUT"`
+	// APIKeys grants static API access as comma-separated "{userID}:{key}" pairs.
+	APIKeys string `mapstructure:"API_KEYS"`
+	// APITokenTTL is how long personal API tokens minted with /token stay valid (0 means forever).
+	APITokenTTL time.Duration `mapstructure:"API_TOKEN_TTL"`
+	// SessionTTL is how long a browser login through Telegram lasts.
+	SessionTTL time.Duration `mapstructure:"SESSION_TTL"`
+	// SessionCookieSecure restricts the session cookie to HTTPS; disable it only for local development.
+	SessionCookieSecure bool `mapstructure:"SESSION_COOKIE_SECURE"`
+	// TelegramBotUsername is the bot's username without "@"; it enables the Telegram Login Widget of the web UI.
+	TelegramBotUsername string `mapstructure:"TELEGRAM_BOT_USERNAME"`
+	// TelegramAuthMaxAge is how old Telegram login data may be when a browser logs in with it.
+	TelegramAuthMaxAge time.Duration `mapstructure:"TELEGRAM_AUTH_MAX_AGE"`
+
 	// Add other configuration fields as needed
 	// e.g., LogLevel string `mapstructure:"LOG_LEVEL"`
-	// e.g., ServerPort string `mapstructure:"SERVER_PORT"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -26,6 +90,36 @@ func LoadConfig(path string) (config Config, err error) {
 	// Set the type of the config file
 	viper.SetConfigType("yaml") // or json, toml, etc.
 
+	// Defaults also make the keys known to viper, so they can be set from the environment
+	viper.SetDefault("SCRAPER_MAX_PAGES", 4)
+	viper.SetDefault("SCRAPER_PAGE_TIMEOUT", 30*time.Second)
+	viper.SetDefault("SCRAPER_EXTRACT_ARTICLES", true)
+	viper.SetDefault("SCRAPER_CACHE_TTL", 24*time.Hour)
+	viper.SetDefault("SCRAPER_HOST_CONCURRENCY", 2)
+	viper.SetDefault("SCRAPER_HOST_DELAY", time.Second)
+	viper.SetDefault("SCRAPER_RATE_LIMIT", 5.0)
+	viper.SetDefault("SCRAPER_RATE_BURST", 10)
+	viper.SetDefault("SCRAPER_RESPECT_ROBOTS", true)
+	viper.SetDefault("SCRAPER_ALLOW_PRIVATE_NETWORKS", false)
+	viper.SetDefault("BLOB_SWEEP_INTERVAL", 24*time.Hour)
+	viper.SetDefault("REFRESH_INTERVAL", time.Hour)
+	viper.SetDefault("REFRESH_MAX_AGE", 7*24*time.Hour)
+	viper.SetDefault("REFRESH_BATCH_SIZE", 50)
+	viper.SetDefault("REFRESH_DEAD_AFTER", 3)
+	viper.SetDefault("QUEUE_WORKERS", 2)
+	viper.SetDefault("QUEUE_MAX_ATTEMPTS", 5)
+	viper.SetDefault("QUEUE_RETRY_BACKOFF", 10*time.Second)
+	viper.SetDefault("QUEUE_MAX_BACKOFF", 10*time.Minute)
+	viper.SetDefault("COLLECTION_INVITE_TTL", 7*24*time.Hour)
+	viper.SetDefault("SERVER_ADDR", ":8080")
+	viper.SetDefault("SERVER_SHUTDOWN_TIMEOUT", 10*time.Second)
+	viper.SetDefault("API_KEYS", "")
+	viper.SetDefault("API_TOKEN_TTL", 90*24*time.Hour)
+	viper.SetDefault("SESSION_TTL", 30*24*time.Hour)
+	viper.SetDefault("SESSION_COOKIE_SECURE", true)
+	viper.SetDefault("TELEGRAM_AUTH_MAX_AGE", 24*time.Hour)
+	viper.SetDefault("TELEGRAM_BOT_USERNAME", "")
+
 	// Allow reading from environment variables
 	viper.AutomaticEnv()
 	// Optional: Set a prefix for environment variables to avoid conflicts
@@ -61,6 +155,36 @@ func LoadConfig(path string) (config Config, err error) {
 		config.BadgerDBPath = "./badger_data"
 		fmt.Println("BADGERDB_PATH not set, using default:", config.BadgerDBPath)
 	}
+	if config.BlobStorePath == "" {
+		config.BlobStorePath = "./blob_data"
+	}
+	if config.ScraperMaxPages < 1 {
+		return Config{}, fmt.Errorf("SCRAPER_MAX_PAGES must be at least 1, got %d", config.ScraperMaxPages)
+	}
+	if config.ScraperHostConcurrency < 0 || config.ScraperRateLimit < 0 {
+		return Config{}, fmt.Errorf("SCRAPER_HOST_CONCURRENCY and SCRAPER_RATE_LIMIT must not be negative")
+	}
+	if config.QueueWorkers < 1 {
+		return Config{}, fmt.Errorf("QUEUE_WORKERS must be at least 1, got %d", config.QueueWorkers)
+	}
+	if config.QueueMaxAttempts < 1 {
+		return Config{}, fmt.Errorf("QUEUE_MAX_ATTEMPTS must be at least 1, got %d", config.QueueMaxAttempts)
+	}
+	if config.RefreshBatchSize < 1 || config.RefreshDeadAfter < 1 {
+		return Config{}, fmt.Errorf("REFRESH_BATCH_SIZE and REFRESH_DEAD_AFTER must be at least 1")
+	}
+	if config.CollectionInviteTTL <= 0 {
+		return Config{}, fmt.Errorf("COLLECTION_INVITE_TTL must be positive, got %s", config.CollectionInviteTTL)
+	}
+	if config.ServerShutdownTimeout <= 0 {
+		return Config{}, fmt.Errorf("SERVER_SHUTDOWN_TIMEOUT must be positive, got %s", config.ServerShutdownTimeout)
+	}
+	if config.APITokenTTL < 0 {
+		return Config{}, fmt.Errorf("API_TOKEN_TTL must not be negative, got %s", config.APITokenTTL)
+	}
+	if config.SessionTTL <= 0 || config.TelegramAuthMaxAge <= 0 {
+		return Config{}, fmt.Errorf("SESSION_TTL and TELEGRAM_AUTH_MAX_AGE must be positive")
+	}
 	// --- End Validation ---
 
 	return config, nil
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// Article is the readable main content of a saved page, kept as an offline
+// copy so the link stays useful after the site changes or disappears.
+type Article struct {
+	// LinkID is the ID of the link the article belongs to.
+	LinkID string `json:"link_id" bson:"link_id"`
+
+	// UserID is the Telegram User ID of the user who saved the link.
+	UserID int64 `json:"user_id" bson:"user_id"`
+
+	// HTML is the cleaned-up article body, limited to basic formatting tags.
+	HTML string `json:"html" bson:"html"`
+
+	// Text is the plain text of the article.
+	Text string `json:"text" bson:"text"`
+
+	// WordCount is the number of words in Text.
+	WordCount int `json:"word_count" bson:"word_count"`
+
+	// ExtractedAt indicates when the article was extracted.
+	ExtractedAt time.Time `json:"extracted_at" bson:"extracted_at"`
+}
@@ -0,0 +1,93 @@
+package domain
+
+import "time"
+
+// Collection is a named group of links, such as "Q3 research" or "onboarding".
+// Unlike tags, collections are created explicitly and a link is added to them
+// one by one; a link may belong to any number of collections. A collection
+// can be shared with other users, who then see the links of all members.
+type Collection struct {
+	// ID is a short random identifier assigned by the repository on creation.
+	ID string `json:"id" bson:"id"`
+
+	// OwnerID is the Telegram User ID of the user who created the collection.
+	OwnerID int64 `json:"owner_id" bson:"owner_id"`
+
+	// Name is the display name, unique among the collections of a user (ignoring case).
+	Name string `json:"name" bson:"name"`
+
+	// LinkCount is the number of links in the collection.
+	LinkCount int `json:"link_count" bson:"link_count"`
+
+	// CreatedAt indicates when the collection was created.
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+
+	// Role is the role of the user the collection was loaded for.
+	// It is not stored with the collection.
+	Role CollectionRole `json:"role,omitempty" bson:"-"`
+}
+
+// CollectionRole is the access a member has to a shared collection.
+type CollectionRole string
+
+// Collection roles, from least to most privileged.
+const (
+	// RoleViewer can browse the links in a collection.
+	RoleViewer CollectionRole = "viewer"
+	// RoleEditor can also add and remove links.
+	RoleEditor CollectionRole = "editor"
+	// RoleOwner can also rename and delete the collection and manage its members.
+	// Every collection has exactly one owner, the user who created it.
+	RoleOwner CollectionRole = "owner"
+)
+
+// roleRanks orders the roles by privilege.
+var roleRanks = map[CollectionRole]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// Valid reports whether r is one of the known roles.
+func (r CollectionRole) Valid() bool {
+	return roleRanks[r] > 0
+}
+
+// Allows reports whether a member with role r may do what requires role need.
+func (r CollectionRole) Allows(need CollectionRole) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[need]
+}
+
+// CollectionMember is a user with access to a collection.
+type CollectionMember struct {
+	// UserID is the Telegram User ID of the member.
+	UserID int64 `json:"user_id" bson:"user_id"`
+
+	// Name is the display name of the member when they joined, for member lists.
+	Name string `json:"name,omitempty" bson:"name,omitempty"`
+
+	// Role is the access the member has.
+	Role CollectionRole `json:"role" bson:"role"`
+
+	// JoinedAt indicates when the member joined the collection.
+	JoinedAt time.Time `json:"joined_at" bson:"joined_at"`
+}
+
+// CollectionInvite is a one-time invitation to join a collection. It is
+// shared as a deep link that carries the token as the /start payload.
+type CollectionInvite struct {
+	// Token is the secret that redeems the invite.
+	Token string `json:"token" bson:"token"`
+
+	// CollectionID is the collection the invite grants access to.
+	CollectionID string `json:"collection_id" bson:"collection_id"`
+
+	// Role is the role the invited user gets.
+	Role CollectionRole `json:"role" bson:"role"`
+
+	// CreatedBy is the Telegram User ID of the member who created the invite.
+	CreatedBy int64 `json:"created_by" bson:"created_by"`
+
+	// ExpiresAt indicates when the invite can no longer be redeemed.
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// JobStatus is the state of a background scrape job.
+type JobStatus string
+
+const (
+	// JobPending jobs wait in the queue until their RunAt time.
+	JobPending JobStatus = "pending"
+	// JobRunning jobs are being processed by a worker. If the worker does not
+	// finish before RunAt, e.g. because the process stopped, the job runs again.
+	JobRunning JobStatus = "running"
+	// JobDead jobs failed too often, or permanently, and are no longer retried.
+	JobDead JobStatus = "dead"
+)
+
+// ScrapeJob asks a worker to fetch the metadata of a saved link in the background.
+type ScrapeJob struct {
+	// ID is assigned by the queue when the job is enqueued.
+	ID string `json:"id" bson:"id"`
+
+	// UserID and LinkID identify the placeholder link to fill in.
+	UserID int64  `json:"user_id" bson:"user_id"`
+	LinkID string `json:"link_id" bson:"link_id"`
+
+	// URL is the page to scrape.
+	URL string `json:"url" bson:"url"`
+
+	// Refresh makes the worker scrape the page again instead of using a cached result.
+	Refresh bool `json:"refresh,omitempty" bson:"refresh,omitempty"`
+
+	// ChatID and MessageID locate the link card to update once the job finishes.
+	// MessageID is 0 if no card was sent.
+	ChatID    int64 `json:"chat_id,omitempty" bson:"chat_id,omitempty"`
+	MessageID int   `json:"message_id,omitempty" bson:"message_id,omitempty"`
+
+	// Status is the state of the job.
+	Status JobStatus `json:"status" bson:"status"`
+
+	// Attempts counts how often the job was started.
+	Attempts int `json:"attempts" bson:"attempts"`
+
+	// LastError is the error of the latest failed attempt.
+	LastError string `json:"last_error,omitempty" bson:"last_error,omitempty"`
+
+	// RunAt is when the job is due: the next attempt of a pending job, or the
+	// end of the lease of a running one.
+	RunAt time.Time `json:"run_at" bson:"run_at"`
+
+	// CreatedAt and UpdatedAt record when the job was enqueued and last changed.
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
@@ -4,9 +4,20 @@ import "time"
 
 // Link represents the core data structure for a saved website link.
 type Link struct {
+	// ID is a short, stable identifier assigned by the repository when the link is first saved.
+	// It is compact enough to be used in Telegram callback data and API paths.
+	ID string `json:"id" bson:"id"`
+
 	// URL is the unique identifier for the link (and the primary key conceptually).
 	URL string `json:"url" bson:"url"`
 
+	// ResolvedURL is the final URL after redirects, if the scraper recorded one.
+	ResolvedURL string `json:"resolved_url,omitempty" bson:"resolved_url,omitempty"`
+
+	// CanonicalURL is the normalized form of the link used for de-duplication.
+	// It is derived by the repository from ResolvedURL (or URL) on save.
+	CanonicalURL string `json:"canonical_url,omitempty" bson:"canonical_url,omitempty"`
+
 	// Title scraped from the website's <title> tag.
 	Title string `json:"title" bson:"title"`
 
@@ -16,17 +27,79 @@ type Link struct {
 	// UserID is the Telegram User ID of the user who saved the link.
 	UserID int64 `json:"user_id" bson:"user_id"`
 
+	// ChatID is the Telegram chat the link was first saved from, where
+	// notifications about it are sent. It is 0 for links saved elsewhere,
+	// such as through the API.
+	ChatID int64 `json:"chat_id,omitempty" bson:"chat_id,omitempty"`
+
 	// Timestamp indicates when the link was saved.
 	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
 
 	// Tags is an optional list of tags for categorizing the link.
 	Tags []string `json:"tags,omitempty" bson:"tags,omitempty"`
 
+	// SuggestedTags are tags proposed automatically when the link was saved,
+	// offered to the user until they accept or dismiss them.
+	SuggestedTags []string `json:"suggested_tags,omitempty" bson:"suggested_tags,omitempty"`
+
 	// Read indicates whether the user has marked the link as read.
 	Read bool `json:"read" bson:"read"`
 
 	// PreviewImageURL is an optional URL to a preview image (e.g., Open Graph image).
 	PreviewImageURL string `json:"preview_image_url,omitempty" bson:"preview_image_url,omitempty"`
+
+	// SiteName is the name of the website (e.g., og:site_name).
+	SiteName string `json:"site_name,omitempty" bson:"site_name,omitempty"`
+
+	// Author is the author of the page content, if declared.
+	Author string `json:"author,omitempty" bson:"author,omitempty"`
+
+	// PublishedAt is when the page content was published, if declared.
+	PublishedAt time.Time `json:"published_at,omitzero" bson:"published_at,omitempty"`
+
+	// Language is the declared language of the page (e.g., "en" or "en-US").
+	Language string `json:"language,omitempty" bson:"language,omitempty"`
+
+	// ContentType is the media type of the document (e.g., "text/html" or "application/pdf").
+	ContentType string `json:"content_type,omitempty" bson:"content_type,omitempty"`
+
+	// FaviconURL is an optional URL to the site's icon.
+	FaviconURL string `json:"favicon_url,omitempty" bson:"favicon_url,omitempty"`
+
+	// Keywords are the keywords declared by the page (keywords meta tag, article:tag).
+	Keywords []string `json:"keywords,omitempty" bson:"keywords,omitempty"`
+
+	// Extra holds site-specific details such as a video's duration or a repository's stars.
+	Extra map[string]string `json:"extra,omitempty" bson:"extra,omitempty"`
+
+	// ArticleWords is the word count of the offline copy of the article, or 0 if none was saved.
+	ArticleWords int `json:"article_words,omitempty" bson:"article_words,omitempty"`
+
+	// Snapshots are archived copies of the page (screenshot, PDF, MHTML), at most one per kind.
+	Snapshots []Snapshot `json:"snapshots,omitempty" bson:"snapshots,omitempty"`
+
+	// Pending is true while the page metadata is still being fetched in the background.
+	Pending bool `json:"pending,omitempty" bson:"pending,omitempty"`
+
+	// FetchError explains why the page metadata could not be fetched, if fetching gave up.
+	FetchError string `json:"fetch_error,omitempty" bson:"fetch_error,omitempty"`
+
+	// LastChecked is when the page was last scraped, on save or by the refresh scheduler.
+	LastChecked time.Time `json:"last_checked,omitzero" bson:"last_checked,omitempty"`
+
+	// HTTPStatus is the HTTP status of the page at the last check, if known.
+	HTTPStatus int `json:"http_status,omitempty" bson:"http_status,omitempty"`
+
+	// RedirectURL is where the page redirected to at the last check, if it
+	// moved since it was saved. Unlike ResolvedURL it does not change how the
+	// link is stored.
+	RedirectURL string `json:"redirect_url,omitempty" bson:"redirect_url,omitempty"`
+
+	// CheckFailures counts the consecutive checks that failed to reach the page.
+	CheckFailures int `json:"check_failures,omitempty" bson:"check_failures,omitempty"`
+
+	// Dead is set when the page is gone, or could not be reached several checks in a row.
+	Dead bool `json:"dead,omitempty" bson:"dead,omitempty"`
 }
 
 // Note: Add methods (e.g., validation) and corresponding unit tests in internal/domain/link_test.go as needed.
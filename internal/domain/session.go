@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// Session is a browser login of a user, created after Telegram vouched for
+// them. Only a hash of the session ID kept in the cookie is stored.
+type Session struct {
+	// UserID is the Telegram User ID of the logged in user.
+	UserID int64 `json:"user_id" bson:"user_id"`
+
+	// Name is the display name of the user at login.
+	Name string `json:"name" bson:"name"`
+
+	// CSRFToken must accompany every request of the session that changes data.
+	CSRFToken string `json:"csrf_token" bson:"csrf_token"`
+
+	// CreatedAt indicates when the user logged in.
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+
+	// ExpiresAt indicates when the session ends.
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// Expired reports whether the session has ended at now.
+func (s Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// SnapshotKind identifies the format of an archived page snapshot.
+type SnapshotKind string
+
+const (
+	// SnapshotScreenshot is a full-page PNG screenshot.
+	SnapshotScreenshot SnapshotKind = "screenshot"
+	// SnapshotPDF is the page printed to PDF.
+	SnapshotPDF SnapshotKind = "pdf"
+	// SnapshotMHTML is a single-file MHTML archive of the page and its resources.
+	SnapshotMHTML SnapshotKind = "mhtml"
+)
+
+// SnapshotKinds lists all snapshot kinds in display order.
+var SnapshotKinds = []SnapshotKind{SnapshotScreenshot, SnapshotPDF, SnapshotMHTML}
+
+// Snapshot references an archived copy of a page kept in the blob store.
+type Snapshot struct {
+	// Kind is the format of the snapshot.
+	Kind SnapshotKind `json:"kind" bson:"kind"`
+
+	// Digest is the hex SHA-256 of the content, which is also its address in the blob store.
+	Digest string `json:"digest" bson:"digest"`
+
+	// Size is the size of the content in bytes.
+	Size int64 `json:"size" bson:"size"`
+
+	// MediaType is the media type of the content (e.g., "image/png").
+	MediaType string `json:"media_type" bson:"media_type"`
+
+	// CapturedAt indicates when the snapshot was taken.
+	CapturedAt time.Time `json:"captured_at" bson:"captured_at"`
+}
+
+// Snapshot returns the snapshot of the given kind, if the link has one.
+func (l Link) Snapshot(kind SnapshotKind) (Snapshot, bool) {
+	for _, s := range l.Snapshots {
+		if s.Kind == kind {
+			return s, true
+		}
+	}
+	return Snapshot{}, false
+}
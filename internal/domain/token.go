@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// TokenScope is a permission granted to a personal API token.
+type TokenScope string
+
+const (
+	// ScopeRead allows reading links, tags and search results.
+	ScopeRead TokenScope = "read"
+	// ScopeWrite allows saving, changing and deleting links and tags.
+	ScopeWrite TokenScope = "write"
+)
+
+// APIToken is a personal token a user minted in Telegram to use the REST API.
+// Only a hash of the secret is stored; the secret itself is shown once.
+type APIToken struct {
+	// ID is a short public identifier, used to list and revoke the token.
+	ID string `json:"id" bson:"id"`
+
+	// UserID is the Telegram User ID of the user the token acts for.
+	UserID int64 `json:"user_id" bson:"user_id"`
+
+	// Name is a label chosen by the user, such as "laptop" or "zapier".
+	Name string `json:"name" bson:"name"`
+
+	// Scopes are the permissions of the token.
+	Scopes []TokenScope `json:"scopes" bson:"scopes"`
+
+	// CreatedAt indicates when the token was minted.
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+
+	// LastUsedAt indicates when the token last authenticated a request, if ever.
+	// It is updated at most once a minute.
+	LastUsedAt time.Time `json:"last_used_at,omitzero" bson:"last_used_at,omitempty"`
+
+	// ExpiresAt indicates when the token stops working; zero means never.
+	ExpiresAt time.Time `json:"expires_at,omitzero" bson:"expires_at,omitempty"`
+}
+
+// HasScope reports whether the token grants scope.
+func (t APIToken) HasScope(scope TokenScope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Expired reports whether the token has expired at now.
+func (t APIToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
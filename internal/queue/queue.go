@@ -0,0 +1,229 @@
+// Package queue runs background scrape jobs from a persistent job store with a
+// pool of workers. Failed jobs are retried with exponential backoff until they
+// run out of attempts and are moved to the dead-letter state.
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// Processor does the work of the jobs taken from a Queue.
+type Processor interface {
+	// Process runs a job. If it returns an error, the job is retried later
+	// unless the error was marked with Permanent.
+	Process(ctx context.Context, job domain.ScrapeJob) error
+
+	// Dead is called once a job was given up, so the user can be told.
+	Dead(ctx context.Context, job domain.ScrapeJob)
+}
+
+// Options configures a Queue. Zero values select the defaults.
+type Options struct {
+	// Workers is how many jobs run concurrently. Default 2.
+	Workers int
+	// MaxAttempts is how often a job is tried before it is given up. Default 5.
+	MaxAttempts int
+	// Backoff is the delay before the first retry; it doubles with every
+	// further attempt. Default 10 seconds.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries. Default 10 minutes.
+	MaxBackoff time.Duration
+	// Lease bounds a single attempt. A job whose worker disappeared, e.g.
+	// because the process crashed, runs again once its lease expired. Default 5 minutes.
+	Lease time.Duration
+	// PollInterval is how often the store is checked for jobs that became due. Default 5 seconds.
+	PollInterval time.Duration
+}
+
+// Queue hands jobs from a persistent store to a bounded set of workers.
+type Queue struct {
+	store storage.JobQueue
+	opts  Options
+	log   logrus.FieldLogger
+	wake  chan struct{}
+}
+
+// New creates a queue on top of store. Jobs are only processed once Run is called.
+func New(store storage.JobQueue, opts Options, logger logrus.FieldLogger) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = 2
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 10 * time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Minute
+	}
+	opts.MaxBackoff = max(opts.MaxBackoff, opts.Backoff)
+	if opts.Lease <= 0 {
+		opts.Lease = 5 * time.Minute
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	return &Queue{
+		store: store,
+		opts:  opts,
+		log:   logger.WithField("component", "queue"),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Enqueue stores a new job and wakes up the dispatcher, so a free worker
+// picks it up right away.
+func (q *Queue) Enqueue(ctx context.Context, job *domain.ScrapeJob) error {
+	if err := q.store.EnqueueJob(ctx, job); err != nil {
+		return err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+		// A wake-up is already pending
+	}
+	return nil
+}
+
+// Run processes due jobs with p until ctx is cancelled, then waits for the
+// running jobs to return. Jobs interrupted by the shutdown run again on the
+// next start without counting the attempt.
+func (q *Queue) Run(ctx context.Context, p Processor) {
+	q.log.WithField("workers", q.opts.Workers).Info("Starting job queue")
+	var wg sync.WaitGroup
+	defer func() {
+		wg.Wait()
+		q.log.Info("Job queue stopped")
+	}()
+
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+
+	// Each running job holds a slot, so jobs are only claimed for free workers
+	slots := make(chan struct{}, q.opts.Workers)
+	for {
+	claim:
+		for {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			job, err := q.store.ClaimJob(ctx, time.Now(), q.opts.Lease)
+			if err != nil {
+				<-slots
+				if !errors.Is(err, storage.ErrNoJob) {
+					q.log.WithError(err).Error("Failed to claim job")
+				}
+				break claim
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				q.process(ctx, p, job)
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// process runs one attempt of a job and records its outcome.
+func (q *Queue) process(ctx context.Context, p Processor, job domain.ScrapeJob) {
+	log := q.log.WithFields(logrus.Fields{
+		"job_id":  job.ID,
+		"user_id": job.UserID,
+		"link_id": job.LinkID,
+		"attempt": job.Attempts,
+	})
+	log.Info("Processing job")
+
+	attemptCtx, cancel := context.WithTimeout(ctx, q.opts.Lease)
+	err := p.Process(attemptCtx, job)
+	cancel()
+
+	// Record the outcome even when the queue is shutting down
+	storeCtx := context.WithoutCancel(ctx)
+	switch {
+	case err == nil:
+		if err := q.store.DeleteJob(storeCtx, job.ID); err != nil {
+			log.WithError(err).Error("Failed to remove finished job")
+		}
+		log.Info("Job finished")
+		return
+
+	case ctx.Err() != nil:
+		log.Info("Job interrupted by shutdown, rescheduling")
+		job.Attempts--
+		job.RunAt = time.Now()
+
+	case IsPermanent(err) || job.Attempts >= q.opts.MaxAttempts:
+		log.WithError(err).Warn("Job failed, giving up")
+		job.Status = domain.JobDead
+		job.LastError = err.Error()
+		if err := q.store.UpdateJob(storeCtx, &job); err != nil {
+			log.WithError(err).Error("Failed to move job to the dead-letter state")
+		}
+		p.Dead(storeCtx, job)
+		return
+
+	default:
+		delay := q.backoff(job.Attempts)
+		log.WithError(err).WithField("retry_in", delay).Warn("Job failed, retrying later")
+		job.LastError = err.Error()
+		job.RunAt = time.Now().Add(delay)
+	}
+
+	job.Status = domain.JobPending
+	if err := q.store.UpdateJob(storeCtx, &job); err != nil {
+		log.WithError(err).Error("Failed to reschedule job")
+	}
+}
+
+// backoff returns the delay before the next attempt after the given number of attempts.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.opts.Backoff
+	for i := 1; i < attempts && delay < q.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, q.opts.MaxBackoff)
+}
+
+// permanentError marks an error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as permanent, so a failed job is given up right away
+// instead of being retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
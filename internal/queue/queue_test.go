@@ -0,0 +1,157 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// fakeProcessor fails each job a configured number of times, then succeeds.
+type fakeProcessor struct {
+	mu       sync.Mutex
+	err      error
+	failFor  int
+	attempts map[string]int
+	done     chan domain.ScrapeJob
+	dead     chan domain.ScrapeJob
+}
+
+func newFakeProcessor(failFor int, err error) *fakeProcessor {
+	return &fakeProcessor{
+		err:      err,
+		failFor:  failFor,
+		attempts: map[string]int{},
+		done:     make(chan domain.ScrapeJob, 10),
+		dead:     make(chan domain.ScrapeJob, 10),
+	}
+}
+
+func (p *fakeProcessor) Process(ctx context.Context, job domain.ScrapeJob) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts[job.URL]++
+	if p.attempts[job.URL] <= p.failFor {
+		return p.err
+	}
+	p.done <- job
+	return nil
+}
+
+func (p *fakeProcessor) Dead(ctx context.Context, job domain.ScrapeJob) {
+	p.dead <- job
+}
+
+func newTestQueue(t *testing.T) (*Queue, *storage.BadgerRepository) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	repo, err := storage.NewBadgerRepository(t.TempDir(), logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+
+	q := New(repo, Options{
+		Workers:      2,
+		MaxAttempts:  3,
+		Backoff:      10 * time.Millisecond,
+		MaxBackoff:   20 * time.Millisecond,
+		PollInterval: 5 * time.Millisecond,
+	}, logger)
+	return q, repo
+}
+
+// runQueue runs q in the background until the test ends.
+func runQueue(t *testing.T, q *Queue, p Processor) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.Run(ctx, p)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// receive waits for a job on ch.
+func receive(t *testing.T, ch <-chan domain.ScrapeJob) domain.ScrapeJob {
+	t.Helper()
+	select {
+	case job := <-ch:
+		return job
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for job")
+		return domain.ScrapeJob{}
+	}
+}
+
+// TestQueue_Retry tests that failed jobs are retried until they succeed.
+func TestQueue_Retry(t *testing.T) {
+	q, repo := newTestQueue(t)
+	p := newFakeProcessor(2, errors.New("temporary"))
+	runQueue(t, q, p)
+
+	require.NoError(t, q.Enqueue(context.Background(), &domain.ScrapeJob{URL: "https://example.com"}))
+
+	job := receive(t, p.done)
+	assert.Equal(t, 3, job.Attempts)
+	assert.Equal(t, "temporary", job.LastError)
+
+	// The finished job is removed from the store
+	require.Eventually(t, func() bool {
+		_, err := repo.ClaimJob(context.Background(), time.Now().Add(time.Hour), time.Minute)
+		return errors.Is(err, storage.ErrNoJob)
+	}, time.Second, 5*time.Millisecond)
+}
+
+// TestQueue_DeadLetter tests that jobs are given up after too many or permanent failures.
+func TestQueue_DeadLetter(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		attempts int
+	}{
+		{name: "out of attempts", err: errors.New("temporary"), attempts: 3},
+		{name: "permanent", err: Permanent(errors.New("gone")), attempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, repo := newTestQueue(t)
+			p := newFakeProcessor(10, tt.err)
+			runQueue(t, q, p)
+
+			require.NoError(t, q.Enqueue(context.Background(), &domain.ScrapeJob{URL: "https://example.com"}))
+
+			job := receive(t, p.dead)
+			assert.Equal(t, domain.JobDead, job.Status)
+			assert.Equal(t, tt.attempts, job.Attempts)
+
+			dead, err := repo.DeadJobs(context.Background())
+			require.NoError(t, err)
+			require.Len(t, dead, 1)
+			assert.Equal(t, tt.err.Error(), dead[0].LastError)
+		})
+	}
+}
+
+// TestQueue_Backoff tests the exponential retry delays.
+func TestQueue_Backoff(t *testing.T) {
+	q := New(nil, Options{Backoff: time.Second, MaxBackoff: 5 * time.Second}, logrus.New())
+	assert.Equal(t, time.Second, q.backoff(1))
+	assert.Equal(t, 2*time.Second, q.backoff(2))
+	assert.Equal(t, 4*time.Second, q.backoff(3))
+	assert.Equal(t, 5*time.Second, q.backoff(4))
+	assert.Equal(t, 5*time.Second, q.backoff(40))
+}
@@ -0,0 +1,242 @@
+package readability
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// lineBreak stands in for <br> while whitespace is collapsed.
+const lineBreak = "\x00"
+
+// Text converts cleaned article HTML to plain text, with blank lines between
+// paragraphs and bullets for list items.
+func Text(articleHTML string) string {
+	return convert(articleHTML, false)
+}
+
+// Markdown converts cleaned article HTML to Markdown.
+func Markdown(articleHTML string) string {
+	return convert(articleHTML, true)
+}
+
+// convert parses an HTML fragment and renders it as Markdown or plain text.
+func convert(articleHTML string, markdown bool) string {
+	nodes, err := html.ParseFragment(strings.NewReader(articleHTML), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return ""
+	}
+	root := &html.Node{Type: html.ElementNode, Data: "div"}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	c := converter{markdown: markdown}
+	return strings.Join(c.blocks(root), "\n\n")
+}
+
+// converter renders an HTML tree as a list of text blocks.
+type converter struct {
+	markdown bool
+}
+
+// blocks renders the children of n. Runs of inline content become paragraphs.
+func (c converter) blocks(n *html.Node) []string {
+	var out []string
+	var inline strings.Builder
+	flush := func() {
+		if p := collapse(inline.String()); p != "" {
+			out = append(out, p)
+		}
+		inline.Reset()
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || !isBlock(child.Data) {
+			inline.WriteString(c.inline(child))
+			continue
+		}
+		flush()
+
+		switch child.Data {
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			heading := collapse(c.inlineChildren(child))
+			if heading == "" {
+				continue
+			}
+			if c.markdown {
+				heading = strings.Repeat("#", int(child.Data[1]-'0')) + " " + heading
+			}
+			out = append(out, heading)
+		case "pre":
+			code := strings.Trim(rawText(child), "\n")
+			if c.markdown {
+				code = "```\n" + code + "\n```"
+			}
+			out = append(out, code)
+		case "blockquote":
+			if quote := strings.Join(c.blocks(child), "\n\n"); quote != "" {
+				out = append(out, prefixLines(quote, "> ", "> "))
+			}
+		case "ul", "ol":
+			if list := c.list(child); list != "" {
+				out = append(out, list)
+			}
+		case "hr":
+			if c.markdown {
+				out = append(out, "---")
+			} else {
+				out = append(out, "* * *")
+			}
+		case "tr":
+			out = append(out, c.row(child))
+		default:
+			out = append(out, c.blocks(child)...)
+		}
+	}
+	flush()
+	return out
+}
+
+// list renders a <ul> or <ol>, indenting nested blocks under their bullet.
+func (c converter) list(n *html.Node) string {
+	var items []string
+	i := 0
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.Data != "li" {
+			continue
+		}
+		i++
+		marker := "• "
+		switch {
+		case n.Data == "ol":
+			marker = fmt.Sprintf("%d. ", i)
+		case c.markdown:
+			marker = "- "
+		}
+		item := strings.Join(c.blocks(li), "\n")
+		if item == "" {
+			continue
+		}
+		items = append(items, prefixLines(item, marker, strings.Repeat(" ", len([]rune(marker)))))
+	}
+	return strings.Join(items, "\n")
+}
+
+// row renders a table row with its cells separated by pipes.
+func (c converter) row(n *html.Node) string {
+	var cells []string
+	for cell := n.FirstChild; cell != nil; cell = cell.NextSibling {
+		if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+			cells = append(cells, collapse(c.inlineChildren(cell)))
+		}
+	}
+	if c.markdown {
+		return "| " + strings.Join(cells, " | ") + " |"
+	}
+	return strings.Join(cells, " | ")
+}
+
+// inline renders inline content.
+func (c converter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return n.Data
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.Data {
+	case "br":
+		return lineBreak
+	case "img":
+		if !c.markdown {
+			return ""
+		}
+		return fmt.Sprintf("![%s](%s)", attr(n, "alt"), attr(n, "src"))
+	}
+
+	text := c.inlineChildren(n)
+	if !c.markdown || strings.TrimSpace(text) == "" {
+		return text
+	}
+	switch n.Data {
+	case "strong", "b":
+		return "**" + strings.TrimSpace(text) + "**"
+	case "em", "i":
+		return "_" + strings.TrimSpace(text) + "_"
+	case "code":
+		return "`" + strings.TrimSpace(text) + "`"
+	case "a":
+		if href := attr(n, "href"); href != "" {
+			return "[" + strings.TrimSpace(text) + "](" + href + ")"
+		}
+	}
+	return text
+}
+
+// inlineChildren renders the children of n as inline content.
+func (c converter) inlineChildren(n *html.Node) string {
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(c.inline(child))
+	}
+	return sb.String()
+}
+
+// isBlock reports whether an element starts a new block.
+func isBlock(tag string) bool {
+	switch tag {
+	case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol", "li", "dl", "dt", "dd",
+		"blockquote", "pre", "hr", "figure", "figcaption", "table", "thead", "tbody", "tr",
+		"section", "article", "main":
+		return true
+	}
+	return false
+}
+
+// collapse collapses whitespace runs into single spaces and turns line break markers into newlines.
+func collapse(s string) string {
+	lines := strings.Split(s, lineBreak)
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// rawText returns the text of n with whitespace preserved, as needed for <pre>.
+func rawText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			sb.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.Data == "br":
+			sb.WriteByte('\n')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// prefixLines prefixes the first line of s with first and all other lines with rest.
+func prefixLines(s, first, rest string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = first + line
+		case line == "" && strings.TrimSpace(rest) == "":
+			// Keep blank lines free of trailing whitespace
+		default:
+			lines[i] = rest + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,396 @@
+// Package readability extracts the main readable content of an HTML page,
+// dropping navigation, sidebars, comments and other boilerplate. It follows
+// the scoring approach of Arc90's Readability: paragraphs award points to
+// their ancestors, and the best scoring container becomes the article.
+package readability
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// MinWords is the shortest body accepted as an article. Anything shorter is
+// usually a listing, a login wall or an error page rather than something to read.
+const MinWords = 80
+
+// Article is the readable content of a page.
+type Article struct {
+	// HTML is the cleaned-up article body, limited to basic formatting tags.
+	HTML string
+	// Text is the plain text of the article with paragraphs separated by blank lines.
+	Text string
+	// WordCount is the number of words in Text.
+	WordCount int
+}
+
+var (
+	// unlikelyRE matches class names and IDs of boilerplate containers.
+	unlikelyRE = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|newsletter|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental|ad-break|agegate|tweet|twitter|widget`)
+	// maybeRE matches class names and IDs that rescue an otherwise unlikely container.
+	maybeRE = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	// positiveRE and negativeRE adjust the score of a container by its class name and ID.
+	positiveRE = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeRE = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// skipTags are never part of an article.
+var skipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "iframe": true,
+	"form": true, "nav": true, "header": true, "footer": true, "aside": true,
+	"svg": true, "canvas": true, "button": true, "input": true, "select": true,
+	"textarea": true, "object": true, "embed": true, "dialog": true, "menu": true,
+}
+
+// keepTags are kept in the cleaned HTML; all other elements are unwrapped.
+var keepTags = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"blockquote": true, "pre": true, "code": true, "em": true, "i": true, "strong": true, "b": true,
+	"a": true, "img": true, "br": true, "hr": true, "figure": true, "figcaption": true,
+	"table": true, "thead": true, "tbody": true, "tr": true, "th": true, "td": true, "sup": true, "sub": true,
+}
+
+// Extract finds the main article of a parsed page. pageURL is used to make
+// links and images absolute. It reports false when the page has no article
+// of at least MinWords words. doc is not modified.
+func Extract(doc *html.Node, pageURL string) (Article, bool) {
+	body := find(doc, "body")
+	if body == nil {
+		return Article{}, false
+	}
+	base, _ := url.Parse(pageURL)
+
+	s := &scorer{scores: make(map[*html.Node]float64)}
+	s.score(body)
+	top := s.best()
+	if top == nil {
+		top = body
+	}
+
+	var sb strings.Builder
+	for _, n := range s.siblings(top) {
+		render(&sb, n, base)
+	}
+	cleaned := sb.String()
+
+	text := Text(cleaned)
+	words := len(strings.Fields(text))
+	if words < MinWords {
+		return Article{}, false
+	}
+	return Article{HTML: cleaned, Text: text, WordCount: words}, true
+}
+
+// scorer assigns content scores to the containers of paragraphs.
+type scorer struct {
+	scores     map[*html.Node]float64
+	candidates []*html.Node
+}
+
+// score walks the tree and awards points to the ancestors of each paragraph.
+func (s *scorer) score(n *html.Node) {
+	if n.Type == html.ElementNode {
+		if skipped(n) {
+			return
+		}
+		switch n.Data {
+		case "p", "pre", "td", "blockquote":
+			s.scoreParagraph(n)
+		case "div":
+			if !hasBlockChildren(n) {
+				s.scoreParagraph(n)
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		s.score(c)
+	}
+}
+
+// scoreParagraph awards points for one paragraph to its parent and, halved, to its grandparent.
+func (s *scorer) scoreParagraph(p *html.Node) {
+	text := innerText(p)
+	if len(text) < 25 {
+		return
+	}
+	points := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+
+	ancestor := p.Parent
+	for level := 0; level < 3 && ancestor != nil && ancestor.Type == html.ElementNode; level++ {
+		if _, ok := s.scores[ancestor]; !ok {
+			s.scores[ancestor] = initialScore(ancestor)
+			s.candidates = append(s.candidates, ancestor)
+		}
+		s.scores[ancestor] += points / float64(level+1)
+		ancestor = ancestor.Parent
+	}
+}
+
+// best returns the candidate with the highest score, weighted by how little of its text is links.
+func (s *scorer) best() *html.Node {
+	var top *html.Node
+	var topScore float64
+	for _, c := range s.candidates {
+		s.scores[c] *= 1 - linkDensity(c)
+		if top == nil || s.scores[c] > topScore {
+			top, topScore = c, s.scores[c]
+		}
+	}
+	return top
+}
+
+// siblings returns top together with those of its siblings that look like
+// part of the same article, such as paragraphs split across containers.
+func (s *scorer) siblings(top *html.Node) []*html.Node {
+	if top.Parent == nil || top.Data == "body" {
+		return []*html.Node{top}
+	}
+	threshold := math.Max(10, s.scores[top]*0.2)
+	var nodes []*html.Node
+	for c := top.Parent.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || skipped(c) {
+			continue
+		}
+		score, scored := s.scores[c]
+		switch {
+		case c == top:
+			nodes = append(nodes, c)
+		case scored && score >= threshold:
+			nodes = append(nodes, c)
+		case c.Data == "p":
+			text := innerText(c)
+			if (len(text) > 80 && linkDensity(c) < 0.25) || (len(text) > 0 && linkDensity(c) == 0 && strings.HasSuffix(text, ".")) {
+				nodes = append(nodes, c)
+			}
+		}
+	}
+	return nodes
+}
+
+// initialScore rates a container by its tag and its class name and ID.
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.Data {
+	case "article", "main":
+		score = 10
+	case "div":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+	return score + classWeight(n)
+}
+
+// classWeight rewards content-like and penalizes boilerplate-like class names and IDs.
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, v := range []string{attr(n, "class"), attr(n, "id")} {
+		if v == "" {
+			continue
+		}
+		if negativeRE.MatchString(v) {
+			weight -= 25
+		}
+		if positiveRE.MatchString(v) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// skipped reports whether an element is boilerplate that must be ignored entirely.
+func skipped(n *html.Node) bool {
+	if skipTags[n.Data] || attr(n, "hidden") != "" || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	switch n.Data {
+	case "html", "body", "article", "main":
+		return false
+	}
+	if role := attr(n, "role"); role == "navigation" || role == "complementary" || role == "dialog" {
+		return true
+	}
+	match := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyRE.MatchString(match) && !maybeRE.MatchString(match)
+}
+
+// hasBlockChildren reports whether a div contains block elements, as opposed
+// to only text and inline elements, in which case it is treated as a paragraph.
+func hasBlockChildren(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.Data {
+		case "a", "abbr", "b", "br", "code", "em", "i", "img", "kbd", "mark", "q", "s", "small", "span", "strong", "sub", "sup", "time", "u":
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// linkDensity returns the share of the text of n that is inside links.
+func linkDensity(n *html.Node) float64 {
+	total := len(innerText(n))
+	if total == 0 {
+		return 0
+	}
+	var linked int
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			linked += len(innerText(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(linked) / float64(total)
+}
+
+// innerText returns the visible text of n with whitespace collapsed.
+func innerText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+		case html.ElementNode:
+			if skipTags[n.Data] {
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// render writes the cleaned HTML of n: boilerplate is dropped, kept tags lose
+// all attributes except link targets and image sources, and other elements
+// are replaced by their children.
+func render(sb *strings.Builder, n *html.Node, base *url.URL) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if skipped(n) || isLinkList(n) {
+		return
+	}
+	if !keepTags[n.Data] {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			render(sb, c, base)
+		}
+		return
+	}
+
+	switch n.Data {
+	case "img":
+		src := firstNonEmpty(attr(n, "src"), attr(n, "data-src"))
+		if src == "" || strings.HasPrefix(src, "data:") {
+			return
+		}
+		sb.WriteString(`<img src="` + html.EscapeString(resolve(base, src)) + `" alt="` + html.EscapeString(attr(n, "alt")) + `">`)
+		return
+	case "br", "hr":
+		sb.WriteString("<" + n.Data + ">")
+		return
+	case "p":
+		if strings.TrimSpace(innerText(n)) == "" && find(n, "img") == nil {
+			return
+		}
+	case "a":
+		href := attr(n, "href")
+		if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			break
+		}
+		sb.WriteString(`<a href="` + html.EscapeString(resolve(base, href)) + `">`)
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			render(sb, c, base)
+		}
+		sb.WriteString("</a>")
+		return
+	}
+
+	sb.WriteString("<" + n.Data + ">")
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		render(sb, c, base)
+	}
+	sb.WriteString("</" + n.Data + ">")
+}
+
+// isLinkList reports whether n is a list or container that is mostly links,
+// such as "related articles" or tag clouds inside the article container.
+func isLinkList(n *html.Node) bool {
+	switch n.Data {
+	case "ul", "ol", "div", "section", "table":
+	default:
+		return false
+	}
+	text := innerText(n)
+	return len(text) < 500 && linkDensity(n) > 0.5
+}
+
+// find returns the first element named tag in the tree rooted at n.
+func find(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := find(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// attr returns the value of an attribute of n, or "" if it is not set.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// resolve makes ref absolute against base, returning ref unchanged if either cannot be parsed.
+func resolve(base *url.URL, ref string) string {
+	if base == nil {
+		return ref
+	}
+	r, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(r).String()
+}
+
+// firstNonEmpty returns the first non-empty string.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package readability
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+// TestExtract tests that the article body is found and boilerplate is dropped.
+func TestExtract(t *testing.T) {
+	f, err := os.Open("testdata/blog.html")
+	require.NoError(t, err)
+	defer f.Close()
+	doc, err := html.Parse(f)
+	require.NoError(t, err)
+
+	article, ok := Extract(doc, "https://eng.example.com/posts/badger")
+	require.True(t, ok)
+
+	assert.Contains(t, article.Text, "BadgerDB is an embeddable, persistent key-value store")
+	assert.Contains(t, article.Text, "Key design")
+	assert.Contains(t, article.Text, "• No network round trips for reads.")
+	for _, boilerplate := range []string{"Popular posts", "cookies", "Great post", "All rights reserved", "Tweet", "Jobs", "dataLayer"} {
+		assert.NotContains(t, article.Text, boilerplate)
+	}
+	assert.Equal(t, len(strings.Fields(article.Text)), article.WordCount)
+
+	assert.Contains(t, article.HTML, `<a href="https://eng.example.com/docs/badger">internal guide</a>`, "Links should be absolute")
+	assert.Contains(t, article.HTML, `<img src="https://eng.example.com/img/latency.png" alt="Latency before and after">`)
+	assert.NotContains(t, article.HTML, "class=")
+	assert.NotContains(t, article.HTML, "<div")
+}
+
+// TestExtract_TooShort tests that pages without a real article are rejected.
+func TestExtract_TooShort(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body><nav><a href="/">Home</a></nav><p>Page not found.</p></body></html>`))
+	require.NoError(t, err)
+
+	_, ok := Extract(doc, "https://example.com/missing")
+	assert.False(t, ok)
+}
+
+// TestMarkdown tests conversion of cleaned article HTML to Markdown and plain text.
+func TestMarkdown(t *testing.T) {
+	in := `<h2>Title</h2><p>Some <strong>bold</strong> and <em>italic</em> text with <a href="https://example.com/">a link</a>,<br>and <code>code</code>.</p>` +
+		`<ul><li>One</li><li>Two<ol><li>Nested</li></ol></li></ul><pre><code>x := 1
+y := 2</code></pre><blockquote><p>Quoted</p></blockquote><img src="https://example.com/i.png" alt="Pic">`
+
+	assert.Equal(t, "## Title\n\n"+
+		"Some **bold** and _italic_ text with [a link](https://example.com/),\nand `code`.\n\n"+
+		"- One\n- Two\n  1. Nested\n\n"+
+		"```\nx := 1\ny := 2\n```\n\n"+
+		"> Quoted\n\n"+
+		"![Pic](https://example.com/i.png)", Markdown(in))
+
+	assert.Equal(t, "Title\n\n"+
+		"Some bold and italic text with a link,\nand code.\n\n"+
+		"• One\n• Two\n  1. Nested\n\n"+
+		"x := 1\ny := 2\n\n"+
+		"> Quoted", Text(in))
+}
@@ -0,0 +1,196 @@
+// Package refresh periodically re-checks saved links: it updates metadata that
+// changed since a link was saved, records the HTTP status of the page and
+// flags links whose page is gone as dead.
+package refresh
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/scraper"
+	"jetengine/internal/storage"
+)
+
+// Notifier tells users about their links.
+type Notifier interface {
+	// LinkDead is called once when a link is found dead.
+	LinkDead(ctx context.Context, link domain.Link)
+}
+
+// Options configures a Checker. Zero values select the defaults.
+type Options struct {
+	// Interval is how often a batch of links is checked. Default 1 hour.
+	Interval time.Duration
+	// MaxAge is how long a check stays valid; older links are checked again. Default 7 days.
+	MaxAge time.Duration
+	// BatchSize is the maximum number of links checked per run. Default 50.
+	BatchSize int
+	// DeadAfter is how many checks in a row may fail to reach a page before
+	// it is considered dead. Pages answering 404 or 410 are dead right away. Default 3.
+	DeadAfter int
+}
+
+// Checker re-checks the least recently checked links in batches.
+type Checker struct {
+	repo     storage.Repository
+	scraper  scraper.Scraper
+	notifier Notifier
+	opts     Options
+	log      logrus.FieldLogger
+}
+
+// New creates a Checker. The notifier may be nil.
+func New(repo storage.Repository, s scraper.Scraper, notifier Notifier, opts Options, logger logrus.FieldLogger) *Checker {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = 7 * 24 * time.Hour
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	if opts.DeadAfter <= 0 {
+		opts.DeadAfter = 3
+	}
+	return &Checker{
+		repo:     repo,
+		scraper:  s,
+		notifier: notifier,
+		opts:     opts,
+		log:      logger.WithField("component", "refresh"),
+	}
+}
+
+// Run checks a batch of links right away and then every Interval, until ctx is cancelled.
+func (c *Checker) Run(ctx context.Context) {
+	c.log.WithFields(logrus.Fields{
+		"interval":   c.opts.Interval,
+		"batch_size": c.opts.BatchSize,
+	}).Info("Starting link refresh scheduler")
+
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := c.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			c.log.WithError(err).Error("Link refresh run failed")
+		}
+		select {
+		case <-ctx.Done():
+			c.log.Info("Link refresh scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce checks one batch of links that are due and returns how many were checked.
+func (c *Checker) RunOnce(ctx context.Context) (int, error) {
+	links, err := c.repo.LinksToCheck(ctx, time.Now().Add(-c.opts.MaxAge), c.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	checked := 0
+	for _, link := range links {
+		if ctx.Err() != nil {
+			return checked, ctx.Err()
+		}
+		c.check(ctx, link)
+		checked++
+	}
+	if checked > 0 {
+		c.log.WithField("checked", checked).Info("Checked saved links")
+	}
+	return checked, nil
+}
+
+// check scrapes the page of a link again and records the outcome on the link.
+func (c *Checker) check(ctx context.Context, link domain.Link) {
+	log := c.log.WithFields(logrus.Fields{
+		"user_id": link.UserID,
+		"link_id": link.ID,
+		"url":     link.URL,
+	})
+
+	meta, scrapeErr := c.scraper.ScrapeMetadata(scraper.WithRefresh(ctx), link.URL)
+	if scrapeErr != nil && ctx.Err() != nil {
+		// Shutting down; the link is checked again on the next run
+		return
+	}
+
+	// Re-read the link so changes made while scraping, like tags, are kept
+	current, err := c.repo.GetLinkByID(ctx, link.UserID, link.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to reload link")
+		return
+	}
+	wasDead := current.Dead
+	apply(&current, meta, scrapeErr, c.opts.DeadAfter, time.Now())
+
+	if err := c.repo.UpdateLink(ctx, &current); err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.WithError(err).Error("Failed to store link check")
+		}
+		return
+	}
+
+	switch {
+	case current.Dead && !wasDead:
+		log.WithError(scrapeErr).WithField("status", current.HTTPStatus).Info("Link is dead")
+		if c.notifier != nil {
+			c.notifier.LinkDead(ctx, current)
+		}
+	case !current.Dead && wasDead:
+		log.Info("Dead link is reachable again")
+	case scrapeErr != nil:
+		log.WithError(scrapeErr).WithField("failures", current.CheckFailures).Warn("Link check failed")
+	}
+}
+
+// apply records the result of checking a link at now. On success the fresh
+// metadata replaces the old one; on failure, including pages served with an
+// error status, the old metadata is kept. A page that moved is not re-keyed;
+// its new address is only recorded in RedirectURL.
+func apply(link *domain.Link, meta scraper.Metadata, scrapeErr error, deadAfter int, now time.Time) {
+	link.LastChecked = now
+	if scrapeErr == nil && meta.StatusCode != 0 && (meta.StatusCode < 200 || meta.StatusCode > 299) {
+		scrapeErr = &scraper.StatusError{StatusCode: meta.StatusCode}
+	}
+	if scrapeErr == nil {
+		resolved := link.ResolvedURL
+		meta.ApplyTo(link)
+		link.ResolvedURL = resolved
+		link.RedirectURL = ""
+		if meta.URL != "" && meta.URL != link.URL && meta.URL != resolved {
+			link.RedirectURL = meta.URL
+		}
+		link.CheckFailures = 0
+		link.Dead = false
+		link.FetchError = ""
+		return
+	}
+
+	var statusErr *scraper.StatusError
+	gone := false
+	if errors.As(scrapeErr, &statusErr) {
+		link.HTTPStatus = statusErr.StatusCode
+		gone = statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone
+	}
+	if errors.Is(scrapeErr, scraper.ErrDisallowed) {
+		// The page may well be there; we are just not allowed to look
+		return
+	}
+	link.CheckFailures++
+	if gone || link.CheckFailures >= deadAfter {
+		link.Dead = true
+	}
+}
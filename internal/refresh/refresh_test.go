@@ -0,0 +1,143 @@
+package refresh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/scraper"
+	"jetengine/internal/storage"
+)
+
+// fakeScraper answers with a fixed result per URL.
+type fakeScraper struct {
+	results map[string]scraper.Metadata
+	errs    map[string]error
+}
+
+func (f *fakeScraper) ScrapeMetadata(ctx context.Context, url string) (scraper.Metadata, error) {
+	if err := f.errs[url]; err != nil {
+		return scraper.Metadata{}, err
+	}
+	return f.results[url], nil
+}
+
+func (f *fakeScraper) Close() error { return nil }
+
+// fakeNotifier records dead link notifications.
+type fakeNotifier struct {
+	dead []domain.Link
+}
+
+func (n *fakeNotifier) LinkDead(ctx context.Context, link domain.Link) {
+	n.dead = append(n.dead, link)
+}
+
+func newTestRepo(t *testing.T) *storage.BadgerRepository {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	repo, err := storage.NewBadgerRepository(t.TempDir(), logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+// TestChecker tests metadata updates and dead-link detection.
+func TestChecker(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	old := time.Now().Add(-30 * 24 * time.Hour)
+
+	changed := domain.Link{URL: "https://example.com/changed", Title: "Old title", UserID: 1, Timestamp: old, Tags: []string{"keep"}}
+	gone := domain.Link{URL: "https://example.com/gone", Title: "Gone", UserID: 1, Timestamp: old}
+	flaky := domain.Link{URL: "https://example.com/flaky", Title: "Flaky", UserID: 2, Timestamp: old}
+	broken := domain.Link{URL: "https://example.com/broken", Title: "Broken", UserID: 2, Timestamp: old}
+	recent := domain.Link{URL: "https://example.com/recent", Title: "Recent", UserID: 2, Timestamp: time.Now()}
+	for _, link := range []*domain.Link{&changed, &gone, &flaky, &broken, &recent} {
+		require.NoError(t, repo.SaveLink(ctx, link))
+	}
+
+	s := &fakeScraper{
+		results: map[string]scraper.Metadata{
+			changed.URL: {URL: "https://example.com/moved", Title: "New title", StatusCode: 200},
+			// Browsers render error pages without failing
+			broken.URL: {Title: "Service Unavailable", StatusCode: 503},
+		},
+		errs: map[string]error{
+			gone.URL:  fmt.Errorf("failed to fetch: %w", &scraper.StatusError{StatusCode: 404}),
+			flaky.URL: errors.New("connection refused"),
+		},
+	}
+	notifier := &fakeNotifier{}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	c := New(repo, s, notifier, Options{MaxAge: 24 * time.Hour, DeadAfter: 2}, logger)
+
+	checked, err := c.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, checked, "Recently saved links should not be checked")
+
+	got, err := repo.GetLinkByID(ctx, 1, changed.ID)
+	require.NoError(t, err, "A link whose page moved should keep its ID")
+	assert.Equal(t, "New title", got.Title)
+	assert.Equal(t, []string{"keep"}, got.Tags)
+	assert.Equal(t, 200, got.HTTPStatus)
+	assert.Equal(t, "https://example.com/moved", got.RedirectURL)
+	assert.Empty(t, got.ResolvedURL, "A link should not be re-keyed by a check")
+	assert.Equal(t, "https://example.com/changed", got.CanonicalURL)
+	assert.False(t, got.Dead)
+	assert.WithinDuration(t, time.Now(), got.LastChecked, time.Minute)
+
+	got, err = repo.GetLinkByID(ctx, 1, gone.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Dead, "A 404 should mark the link dead right away")
+	assert.Equal(t, 404, got.HTTPStatus)
+	assert.Equal(t, "Gone", got.Title, "Metadata should be kept for dead links")
+
+	got, err = repo.GetLinkByID(ctx, 2, flaky.ID)
+	require.NoError(t, err)
+	assert.False(t, got.Dead, "A single failure should not mark the link dead")
+	assert.Equal(t, 1, got.CheckFailures)
+
+	got, err = repo.GetLinkByID(ctx, 2, broken.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.CheckFailures, "An error status should count as a failure")
+	assert.Equal(t, 503, got.HTTPStatus)
+	assert.Equal(t, "Broken", got.Title)
+
+	require.Len(t, notifier.dead, 1)
+	assert.Equal(t, gone.ID, notifier.dead[0].ID)
+
+	// --- A second failure kills the flaky link; the dead one is not notified again ---
+	c.opts.MaxAge = -time.Hour
+	delete(s.results, broken.URL)
+	_, err = c.RunOnce(ctx)
+	require.NoError(t, err)
+	got, err = repo.GetLinkByID(ctx, 2, flaky.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Dead)
+	require.Len(t, notifier.dead, 2)
+	assert.Equal(t, flaky.ID, notifier.dead[1].ID)
+
+	// --- A page that comes back is no longer dead ---
+	delete(s.errs, gone.URL)
+	s.results[gone.URL] = scraper.Metadata{Title: "Back again", StatusCode: 200}
+	_, err = c.RunOnce(ctx)
+	require.NoError(t, err)
+	got, err = repo.GetLinkByID(ctx, 1, gone.ID)
+	require.NoError(t, err)
+	assert.False(t, got.Dead)
+	assert.Zero(t, got.CheckFailures)
+	assert.Equal(t, "Back again", got.Title)
+	assert.Equal(t, 200, got.HTTPStatus)
+	assert.Len(t, notifier.dead, 2)
+}
@@ -0,0 +1,127 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/canonical"
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// CachingScraper remembers the results of another Scraper by canonical URL,
+// so users saving the same page share a single scrape until the entry expires.
+type CachingScraper struct {
+	next  Scraper
+	store storage.Cache
+	ttl   time.Duration
+	log   logrus.FieldLogger
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// CacheStats counts how often a CachingScraper found a page in its cache.
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// cachedMetadata is the cache entry of a page. Metadata leaves the article
+// out of its JSON, so it is stored next to it.
+type cachedMetadata struct {
+	Metadata
+	Article *domain.Article `json:"article,omitempty"`
+}
+
+// refreshKey marks contexts that bypass the cache, see WithRefresh.
+type refreshKey struct{}
+
+// WithRefresh returns a context that makes a CachingScraper scrape the page
+// again instead of using its cached result. The fresh result is cached.
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey{}, true)
+}
+
+// NewCachingScraper caches the results of next in store for ttl.
+func NewCachingScraper(next Scraper, store storage.Cache, ttl time.Duration, logger logrus.FieldLogger) *CachingScraper {
+	return &CachingScraper{
+		next:  next,
+		store: store,
+		ttl:   ttl,
+		log:   logger.WithField("component", "scrape_cache"),
+	}
+}
+
+// ScrapeMetadata returns the cached metadata of url, scraping it on a miss.
+// Failures are not cached.
+func (s *CachingScraper) ScrapeMetadata(ctx context.Context, url string) (Metadata, error) {
+	key := cacheKey(url)
+	log := s.log.WithField("url", url)
+
+	if refresh, _ := ctx.Value(refreshKey{}).(bool); !refresh {
+		if meta, ok := s.lookup(ctx, key, log); ok {
+			s.hits.Add(1)
+			log.Debug("Scrape cache hit")
+			return meta, nil
+		}
+	}
+	s.misses.Add(1)
+
+	meta, err := s.next.ScrapeMetadata(ctx, url)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	entry, err := json.Marshal(cachedMetadata{Metadata: meta, Article: meta.Article})
+	if err != nil {
+		log.WithError(err).Warn("Failed to encode scrape result for the cache")
+		return meta, nil
+	}
+	if err := s.store.SetCache(ctx, key, entry, s.ttl); err != nil {
+		log.WithError(err).Warn("Failed to cache scrape result")
+	}
+	return meta, nil
+}
+
+// lookup reads a cache entry, treating unreadable entries as misses.
+func (s *CachingScraper) lookup(ctx context.Context, key string, log logrus.FieldLogger) (Metadata, bool) {
+	data, err := s.store.GetCache(ctx, key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.WithError(err).Warn("Failed to read scrape cache")
+		}
+		return Metadata{}, false
+	}
+	var entry cachedMetadata
+	if err := json.Unmarshal(data, &entry); err != nil {
+		log.WithError(err).Warn("Ignoring malformed scrape cache entry")
+		return Metadata{}, false
+	}
+	meta := entry.Metadata
+	meta.Article = entry.Article
+	return meta, true
+}
+
+// Stats returns the hit and miss counts since the scraper was created.
+func (s *CachingScraper) Stats() CacheStats {
+	return CacheStats{Hits: s.hits.Load(), Misses: s.misses.Load()}
+}
+
+// Close closes the underlying scraper.
+func (s *CachingScraper) Close() error {
+	return s.next.Close()
+}
+
+// cacheKey returns the cache key of a page, based on its canonical URL.
+func cacheKey(url string) string {
+	if c, err := canonical.URL(url); err == nil {
+		url = c
+	}
+	return "meta:" + url
+}
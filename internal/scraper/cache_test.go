@@ -0,0 +1,90 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// memoryCache is an in-memory storage.Cache that records the TTLs it was given.
+type memoryCache struct {
+	entries map[string][]byte
+	ttls    map[string]time.Duration
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (c *memoryCache) GetCache(ctx context.Context, key string) ([]byte, error) {
+	value, ok := c.entries[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return value, nil
+}
+
+func (c *memoryCache) SetCache(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.entries[key] = value
+	c.ttls[key] = ttl
+	return nil
+}
+
+// articleScraper returns a page with an article and counts its calls.
+type articleScraper struct {
+	fakeScraper
+}
+
+func (f *articleScraper) ScrapeMetadata(ctx context.Context, url string) (Metadata, error) {
+	meta, err := f.fakeScraper.ScrapeMetadata(ctx, url)
+	if err == nil {
+		meta.Article = &domain.Article{Text: "Body", WordCount: 1}
+	}
+	return meta, err
+}
+
+// TestCachingScraper tests that results are shared by canonical URL and can be refreshed.
+func TestCachingScraper(t *testing.T) {
+	ctx := context.Background()
+	next := &articleScraper{fakeScraper{title: "Cached"}}
+	store := newMemoryCache()
+	s := NewCachingScraper(next, store, time.Hour, newTestLogger())
+
+	meta, err := s.ScrapeMetadata(ctx, "https://Example.com/page?utm_source=x")
+	require.NoError(t, err)
+	assert.Equal(t, "Cached", meta.Title)
+	assert.Equal(t, time.Hour, store.ttls["meta:https://example.com/page"])
+
+	// --- Another spelling of the same page is served from the cache ---
+	meta, err = s.ScrapeMetadata(ctx, "https://example.com/page")
+	require.NoError(t, err)
+	assert.Equal(t, "Cached", meta.Title)
+	require.NotNil(t, meta.Article, "The article should be cached with the metadata")
+	assert.Equal(t, "Body", meta.Article.Text)
+	assert.Equal(t, 1, next.calls)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1}, s.Stats())
+
+	// --- A refresh scrapes again and updates the cache ---
+	next.title = "Fresh"
+	meta, err = s.ScrapeMetadata(WithRefresh(ctx), "https://example.com/page")
+	require.NoError(t, err)
+	assert.Equal(t, "Fresh", meta.Title)
+	assert.Equal(t, 2, next.calls)
+	meta, err = s.ScrapeMetadata(ctx, "https://example.com/page")
+	require.NoError(t, err)
+	assert.Equal(t, "Fresh", meta.Title)
+
+	// --- Failures are not cached ---
+	next.err = errors.New("boom")
+	_, err = s.ScrapeMetadata(ctx, "https://example.com/other")
+	assert.Error(t, err)
+	assert.NotContains(t, store.entries, "meta:https://example.com/other")
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 3}, s.Stats())
+}
@@ -0,0 +1,173 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+)
+
+// Keys of Metadata.Extra filled in by the built-in extractors.
+const (
+	// ExtraDuration is the length of a video as a Go duration string, e.g. "4m13s".
+	ExtraDuration = "duration"
+	// ExtraViews is the view count of a video.
+	ExtraViews = "views"
+	// ExtraStars is the star count of a repository.
+	ExtraStars = "stars"
+	// ExtraForks is the fork count of a repository.
+	ExtraForks = "forks"
+	// ExtraProgrammingLanguage is the main programming language of a repository.
+	ExtraProgrammingLanguage = "programming_language"
+	// ExtraScore is the score (upvotes minus downvotes) of a post.
+	ExtraScore = "score"
+	// ExtraComments is the comment count of a post.
+	ExtraComments = "comments"
+	// ExtraCommunity is the community a post belongs to, e.g. "r/golang".
+	ExtraCommunity = "community"
+)
+
+// errNoDocument is returned by extractors that need the page HTML when only the URL is known.
+var errNoDocument = errors.New("page HTML is not available")
+
+// Extractor refines the generic metadata of a page using knowledge of a
+// specific site, such as its oEmbed endpoint or page structure.
+type Extractor interface {
+	// Name identifies the extractor in logs.
+	Name() string
+
+	// Extract updates meta in place. doc is the parsed page, or nil when the
+	// page itself could not be fetched and only meta.URL is known.
+	// Extractors are best-effort: fields set before an error are kept.
+	Extract(ctx context.Context, doc *html.Node, meta *Metadata) error
+}
+
+// ExtractorRegistry maps host patterns to extractors. A pattern matches the
+// host itself and all of its subdomains, so "youtube.com" also matches
+// "www.youtube.com" and "m.youtube.com". The registry must not be modified
+// once scrapers are using it.
+type ExtractorRegistry struct {
+	entries []extractorEntry
+}
+
+// extractorEntry is a registered host pattern.
+type extractorEntry struct {
+	pattern   string
+	extractor Extractor
+}
+
+// NewExtractorRegistry creates an empty registry.
+func NewExtractorRegistry() *ExtractorRegistry {
+	return &ExtractorRegistry{}
+}
+
+// Register adds an extractor for the given host patterns. When several
+// patterns match a host, the longest one wins.
+func (r *ExtractorRegistry) Register(e Extractor, hostPatterns ...string) {
+	for _, pattern := range hostPatterns {
+		pattern = strings.TrimPrefix(strings.ToLower(pattern), "www.")
+		r.entries = append(r.entries, extractorEntry{pattern: pattern, extractor: e})
+	}
+}
+
+// Lookup returns the extractor for a host, or nil if none is registered.
+func (r *ExtractorRegistry) Lookup(host string) Extractor {
+	if r == nil {
+		return nil
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	var best *extractorEntry
+	for i, entry := range r.entries {
+		if host != entry.pattern && !strings.HasSuffix(host, "."+entry.pattern) {
+			continue
+		}
+		if best == nil || len(entry.pattern) > len(best.pattern) {
+			best = &r.entries[i]
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.extractor
+}
+
+// apply runs the extractor registered for the host of meta.URL, if any.
+// It reports whether an extractor ran without error.
+func (r *ExtractorRegistry) apply(ctx context.Context, doc *html.Node, meta *Metadata, log logrus.FieldLogger) bool {
+	u, err := url.Parse(meta.URL)
+	if err != nil {
+		return false
+	}
+	e := r.Lookup(u.Hostname())
+	if e == nil {
+		return false
+	}
+
+	log = log.WithField("extractor", e.Name())
+	if err := e.Extract(ctx, doc, meta); err != nil {
+		log.WithError(err).Warn("Site-specific extraction failed, keeping generic metadata")
+		return false
+	}
+	log.Debug("Applied site-specific extractor")
+	return true
+}
+
+// DefaultExtractors returns a registry with the built-in extractors for
+// YouTube, GitHub, Twitter/X and Reddit. client is used for oEmbed requests;
+// if nil, a client with a 10 second timeout is used. The oEmbed requests
+// count against politeness, which may be nil.
+func DefaultExtractors(client *http.Client, politeness *Politeness) *ExtractorRegistry {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	r := NewExtractorRegistry()
+	r.Register(&youTubeExtractor{client: client, politeness: politeness, endpoint: youTubeOEmbedEndpoint}, "youtube.com", "youtu.be")
+	r.Register(githubExtractor{}, "github.com")
+	r.Register(&twitterExtractor{client: client, politeness: politeness, endpoint: twitterOEmbedEndpoint}, "twitter.com", "x.com")
+	r.Register(&redditExtractor{client: client, politeness: politeness, endpoint: redditOEmbedEndpoint}, "reddit.com")
+	return r
+}
+
+// setExtra sets meta.Extra[key], allocating the map on first use. Empty values are ignored.
+func setExtra(meta *Metadata, key, value string) {
+	if value == "" {
+		return
+	}
+	if meta.Extra == nil {
+		meta.Extra = make(map[string]string)
+	}
+	meta.Extra[key] = value
+}
+
+// findElement returns the first element in doc for which match returns true.
+func findElement(doc *html.Node, match func(*html.Node) bool) *html.Node {
+	if doc == nil {
+		return nil
+	}
+	if doc.Type == html.ElementNode && match(doc) {
+		return doc
+	}
+	for c := doc.FirstChild; c != nil; c = c.NextSibling {
+		if n := findElement(c, match); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// itemprop returns the content of the first <meta> or <link> with the given
+// microdata itemprop, or "" if there is none.
+func itemprop(doc *html.Node, name string) string {
+	n := findElement(doc, func(n *html.Node) bool {
+		return (n.Data == "meta" || n.Data == "link") && attr(n, "itemprop") == name
+	})
+	if n == nil {
+		return ""
+	}
+	return strings.TrimSpace(firstNonEmpty(attr(n, "content"), attr(n, "href")))
+}
@@ -0,0 +1,165 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+// newOEmbedServer serves a recorded oEmbed response from testdata and
+// records the page URL of the last request.
+func newOEmbedServer(t *testing.T, fixture string) (*httptest.Server, *string) {
+	t.Helper()
+	var requested string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Query().Get("url")
+		if fixture == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeFile(w, r, "testdata/"+fixture)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requested
+}
+
+// extractFixture runs the generic extraction and then e on a recorded page.
+func extractFixture(t *testing.T, e Extractor, fixture, pageURL string) Metadata {
+	t.Helper()
+	var doc *html.Node
+	meta := Metadata{URL: pageURL}
+	if fixture != "" {
+		doc = parseFixture(t, fixture)
+		meta = extractMetadata(doc, pageURL)
+	}
+	require.NoError(t, e.Extract(context.Background(), doc, &meta))
+	return meta
+}
+
+// TestExtractorRegistry_Lookup tests host pattern matching.
+func TestExtractorRegistry_Lookup(t *testing.T) {
+	r := NewExtractorRegistry()
+	generic, specific := githubExtractor{}, &redditExtractor{}
+	r.Register(generic, "example.com")
+	r.Register(specific, "old.example.com")
+
+	assert.Equal(t, generic, r.Lookup("example.com"))
+	assert.Equal(t, generic, r.Lookup("WWW.Example.com."))
+	assert.Equal(t, specific, r.Lookup("old.example.com"), "The longest pattern should win")
+	assert.Nil(t, r.Lookup("notexample.com"))
+	assert.Nil(t, (*ExtractorRegistry)(nil).Lookup("example.com"))
+
+	defaults := DefaultExtractors(nil, nil)
+	for host, name := range map[string]string{
+		"www.youtube.com":    "youtube",
+		"m.youtube.com":      "youtube",
+		"github.com":         "github",
+		"x.com":              "twitter",
+		"mobile.twitter.com": "twitter",
+		"old.reddit.com":     "reddit",
+	} {
+		e := defaults.Lookup(host)
+		require.NotNil(t, e, host)
+		assert.Equal(t, name, e.Name(), host)
+	}
+}
+
+// TestYouTubeExtractor tests video details from the watch page and oEmbed.
+func TestYouTubeExtractor(t *testing.T) {
+	srv, requested := newOEmbedServer(t, "youtube_oembed.json")
+	e := &youTubeExtractor{client: srv.Client(), endpoint: srv.URL}
+	pageURL := "https://www.youtube.com/watch?v=oV9rvDllKEg"
+
+	meta := extractFixture(t, e, "youtube.html", pageURL)
+	assert.Equal(t, pageURL, *requested)
+	assert.Equal(t, "Concurrency is not Parallelism", meta.Title, "The oEmbed title has no site suffix")
+	assert.Equal(t, "gocoding", meta.Author)
+	assert.Equal(t, "YouTube", meta.SiteName)
+	assert.Equal(t, "31m23s", meta.Extra[ExtraDuration])
+	assert.Equal(t, "912345", meta.Extra[ExtraViews])
+	assert.Equal(t, 2013, meta.PublishedAt.Year())
+
+	// Without oEmbed the page structure still provides the details
+	srv, _ = newOEmbedServer(t, "")
+	e = &youTubeExtractor{client: srv.Client(), endpoint: srv.URL}
+	meta = extractFixture(t, e, "youtube.html", pageURL)
+	assert.Equal(t, "Concurrency is not Parallelism", meta.Title)
+	assert.Equal(t, "gocoding", meta.Author)
+	assert.Equal(t, "31m23s", meta.Extra[ExtraDuration])
+}
+
+// TestGitHubExtractor tests repository details from a repository page.
+func TestGitHubExtractor(t *testing.T) {
+	meta := extractFixture(t, githubExtractor{}, "github.html", "https://github.com/golang/go")
+
+	assert.Equal(t, "golang/go", meta.Title)
+	assert.Equal(t, "golang", meta.Author)
+	assert.Equal(t, "GitHub", meta.SiteName)
+	assert.Equal(t, "123456", meta.Extra[ExtraStars])
+	assert.Equal(t, "17654", meta.Extra[ExtraForks])
+	assert.Equal(t, "Go", meta.Extra[ExtraProgrammingLanguage])
+	assert.Equal(t, []string{"go", "programming-language", "language"}, meta.Keywords)
+
+	err := githubExtractor{}.Extract(context.Background(), nil, &Metadata{URL: "https://github.com/golang/go"})
+	assert.ErrorIs(t, err, errNoDocument)
+}
+
+// TestTwitterExtractor tests post details from oEmbed, without the page itself.
+func TestTwitterExtractor(t *testing.T) {
+	srv, _ := newOEmbedServer(t, "twitter_oembed.json")
+	e := &twitterExtractor{client: srv.Client(), endpoint: srv.URL}
+
+	meta := extractFixture(t, e, "", "https://x.com/golang/status/1234567890123456789")
+	assert.Equal(t, "Go on X", meta.Title)
+	assert.Equal(t, "Go", meta.Author)
+	assert.Equal(t, "Go 1.22 is released! Range over integers, loop variable fixes and more. https://t.co/abc", meta.Description)
+	assert.Equal(t, time.Date(2024, 2, 6, 0, 0, 0, 0, time.UTC), meta.PublishedAt)
+
+	srv, _ = newOEmbedServer(t, "")
+	e = &twitterExtractor{client: srv.Client(), endpoint: srv.URL}
+	assert.Error(t, e.Extract(context.Background(), nil, &Metadata{URL: "https://x.com/golang/status/1"}))
+}
+
+// TestRedditExtractor tests post details from the page and the oEmbed fallback.
+func TestRedditExtractor(t *testing.T) {
+	srv, _ := newOEmbedServer(t, "reddit_oembed.json")
+	e := &redditExtractor{client: srv.Client(), endpoint: srv.URL}
+	pageURL := "https://www.reddit.com/r/golang/comments/1abcde/what_are_you_building/"
+
+	meta := extractFixture(t, e, "reddit.html", pageURL)
+	assert.Equal(t, "What are you building with Go this week?", meta.Title)
+	assert.Equal(t, "u/gopher42", meta.Author)
+	assert.Equal(t, "187", meta.Extra[ExtraScore])
+	assert.Equal(t, "64", meta.Extra[ExtraComments])
+	assert.Equal(t, "r/golang", meta.Extra[ExtraCommunity])
+	assert.Equal(t, time.Date(2024, 3, 15, 9, 30, 0, 123000000, time.UTC), meta.PublishedAt.UTC())
+
+	meta = extractFixture(t, e, "", pageURL)
+	assert.Equal(t, "What are you building with Go this week?", meta.Title)
+	assert.Equal(t, "u/gopher42", meta.Author)
+}
+
+// TestParseISODuration tests schema.org duration parsing.
+func TestParseISODuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"PT4M13S": 4*time.Minute + 13*time.Second,
+		"PT1H2M":  time.Hour + 2*time.Minute,
+		"P1DT1S":  24*time.Hour + time.Second,
+		"pt30.5s": 30*time.Second + 500*time.Millisecond,
+		"PT":      0,
+		"4:13":    0,
+		"":        0,
+	}
+	for in, want := range tests {
+		got, ok := parseISODuration(in)
+		assert.Equal(t, want, got, in)
+		assert.Equal(t, want > 0, ok, in)
+	}
+}
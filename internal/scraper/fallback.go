@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FallbackScraper tries the fast HTTPScraper first and only falls back to a
+// headless browser scraper when the static HTML yields no metadata or the page
+// is rendered by JavaScript.
+type FallbackScraper struct {
+	static   *HTTPScraper
+	headless Scraper
+	log      logrus.FieldLogger
+}
+
+// NewFallbackScraper creates a scraper that uses static first and headless as a fallback.
+func NewFallbackScraper(static *HTTPScraper, headless Scraper, logger logrus.FieldLogger) *FallbackScraper {
+	return &FallbackScraper{
+		static:   static,
+		headless: headless,
+		log:      logger.WithField("component", "fallback_scraper"),
+	}
+}
+
+// ScrapeMetadata fetches page metadata, using a headless browser only when needed.
+func (s *FallbackScraper) ScrapeMetadata(ctx context.Context, url string) (Metadata, error) {
+	log := s.log.WithField("url", url)
+
+	page, err := s.static.fetch(ctx, url)
+	switch {
+	case err != nil && IsPermanentFailure(err):
+		return Metadata{}, err
+	case err != nil:
+		log.WithError(err).Info("Static scrape failed, falling back to headless browser")
+	case !page.isHTML():
+		// A browser cannot extract anything more from a PDF or an image
+		return page.meta, nil
+	case page.extracted:
+		// A site-specific extractor already knows the page better than a browser would
+		return page.meta, nil
+	case page.jsRendered || page.empty():
+		log.WithField("js_rendered", page.jsRendered).Info("Static result insufficient, falling back to headless browser")
+	default:
+		return page.meta, nil
+	}
+
+	meta, headlessErr := s.headless.ScrapeMetadata(ctx, url)
+	if headlessErr != nil {
+		if err == nil {
+			// The static result was thin but valid; prefer it over an error
+			log.WithError(headlessErr).Warn("Headless scrape failed, using static result")
+			return page.meta, nil
+		}
+		return Metadata{}, errors.Join(err, headlessErr)
+	}
+	return meta, nil
+}
+
+// Close closes both underlying scrapers.
+func (s *FallbackScraper) Close() error {
+	return errors.Join(s.static.Close(), s.headless.Close())
+}
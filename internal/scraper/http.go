@@ -0,0 +1,350 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// maxBodyBytes limits how much of a response body is read and parsed.
+const maxBodyBytes = 2 << 20
+
+// maxRedirects is how many redirects a page request follows, like the default of net/http.
+const maxRedirects = 10
+
+// defaultUserAgent identifies the scraper to the sites it fetches.
+const defaultUserAgent = "Mozilla/5.0 (compatible; JetEngine/1.0; +https://github.com/zenzer0s/JetEngine)"
+
+// StatusError is returned when a page responds with an HTTP error status.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// HTTPScraper implements the Scraper interface with plain HTTP requests.
+// It reads <title> and meta tags from the static HTML and never runs scripts,
+// so it is fast but cannot see content rendered by JavaScript.
+type HTTPScraper struct {
+	client          *http.Client
+	extractors      *ExtractorRegistry
+	politeness      *Politeness
+	extractArticles bool
+	log             logrus.FieldLogger
+}
+
+// HTTPOptions configures an HTTPScraper.
+type HTTPOptions struct {
+	// Client performs the requests. If nil, a client with a 15 second timeout
+	// is used, which refuses private addresses unless AllowPrivateNetworks is set.
+	Client *http.Client
+	// AllowPrivateNetworks lets the default client fetch pages on loopback,
+	// private and link-local addresses.
+	AllowPrivateNetworks bool
+	// Extractors refine metadata for known sites. It may be nil.
+	Extractors *ExtractorRegistry
+	// ExtractArticles enables extracting the readable article body into Metadata.Article.
+	ExtractArticles bool
+	// Politeness limits the requests per host. It may be nil.
+	Politeness *Politeness
+}
+
+// NewHTTPScraper creates a new HTTP scraper.
+func NewHTTPScraper(opts HTTPOptions, logger logrus.FieldLogger) *HTTPScraper {
+	if opts.Client == nil {
+		opts.Client = newGuardedClient(15*time.Second, opts.AllowPrivateNetworks)
+	}
+	s := &HTTPScraper{
+		extractors:      opts.Extractors,
+		politeness:      opts.Politeness,
+		extractArticles: opts.ExtractArticles,
+		log:             logger.WithField("component", "http_scraper"),
+	}
+	// Redirects are followed on a copy, so the caller's client is left alone
+	client := *opts.Client
+	next := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if next != nil {
+			if err := next(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return s.holdRedirect(req, via)
+	}
+	s.client = &client
+	return s
+}
+
+// heldHostsKey is the context key of the hosts a page request holds.
+type heldHostsKey struct{}
+
+// heldHosts collects the politeness releases of the hosts a page request
+// was redirected to, which are held until the page was read.
+type heldHosts struct {
+	mu       sync.Mutex
+	releases []func()
+}
+
+// release frees all held hosts.
+func (h *heldHosts) release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, release := range h.releases {
+		release()
+	}
+	h.releases = nil
+}
+
+// holdRedirect waits for the politeness limits of a redirect to another host
+// and holds that host until the page was read. Hosts the request went through
+// before are held already.
+func (s *HTTPScraper) holdRedirect(req *http.Request, via []*http.Request) error {
+	held, _ := req.Context().Value(heldHostsKey{}).(*heldHosts)
+	if held == nil {
+		return nil
+	}
+	for _, prev := range via {
+		if strings.EqualFold(prev.URL.Hostname(), req.URL.Hostname()) {
+			return nil
+		}
+	}
+	release, err := s.politeness.Acquire(req.Context(), req.URL.String())
+	if err != nil {
+		return err
+	}
+	held.mu.Lock()
+	held.releases = append(held.releases, release)
+	held.mu.Unlock()
+	return nil
+}
+
+// Close is a no-op; the HTTP scraper holds no resources.
+func (s *HTTPScraper) Close() error {
+	return nil
+}
+
+// ScrapeMetadata fetches metadata from the static HTML of a page.
+func (s *HTTPScraper) ScrapeMetadata(ctx context.Context, url string) (Metadata, error) {
+	page, err := s.fetch(ctx, url)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return page.meta, nil
+}
+
+// staticPage holds what the HTTP scraper learned about a page.
+type staticPage struct {
+	meta Metadata
+	// jsRendered is set when the page looks like an empty shell filled in by scripts.
+	jsRendered bool
+	// extracted is set when a site-specific extractor succeeded, which makes
+	// the result good enough even for pages rendered by JavaScript.
+	extracted bool
+}
+
+// isHTML reports whether the page is an HTML document, as opposed to a PDF, an image, etc.
+func (p staticPage) isHTML() bool {
+	return p.meta.ContentType == "text/html" || p.meta.ContentType == "application/xhtml+xml"
+}
+
+// empty reports whether no useful metadata was found.
+func (p staticPage) empty() bool {
+	return p.meta.Title == "" && p.meta.Description == ""
+}
+
+// fetch downloads and parses a page.
+func (s *HTTPScraper) fetch(ctx context.Context, url string) (staticPage, error) {
+	log := s.log.WithField("url", url)
+	log.Info("Attempting to scrape metadata")
+
+	held := &heldHosts{}
+	ctx = context.WithValue(ctx, heldHostsKey{}, held)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return staticPage{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	pageRelease, err := s.politeness.Acquire(ctx, url)
+	if err != nil {
+		return staticPage{}, err
+	}
+	// The hosts are released before the extractors run, since their oEmbed
+	// requests may go to the same host
+	release := sync.OnceFunc(func() {
+		held.release()
+		pageRelease()
+	})
+	defer release()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		log.WithError(err).Warn("HTTP request failed")
+		return staticPage{}, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		log.WithField("status", resp.StatusCode).Warn("Page returned an error status")
+		err := fmt.Errorf("failed to fetch %s: %w", url, &StatusError{StatusCode: resp.StatusCode})
+		if IsPermanentFailure(err) {
+			return staticPage{}, err
+		}
+		// Some sites refuse plain HTTP clients but still answer their oEmbed endpoint
+		release()
+		meta := Metadata{URL: resp.Request.URL.String(), StatusCode: resp.StatusCode}
+		if s.extractors.apply(ctx, nil, &meta, log) {
+			return staticPage{meta: meta, extracted: true}, nil
+		}
+		return staticPage{}, err
+	}
+
+	finalURL := resp.Request.URL.String()
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		mediaType = "text/html"
+	}
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		log.WithField("content_type", mediaType).Info("Page is not HTML, skipping metadata extraction")
+		return staticPage{meta: Metadata{
+			URL:         finalURL,
+			StatusCode:  resp.StatusCode,
+			Title:       fileName(resp.Request.URL.Path),
+			ContentType: mediaType,
+			Language:    resp.Header.Get("Content-Language"),
+		}}, nil
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxBodyBytes), contentType)
+	if err != nil {
+		return staticPage{}, fmt.Errorf("failed to decode %s: %w", url, err)
+	}
+	doc, err := html.Parse(body)
+	if err != nil {
+		log.WithError(err).Warn("Failed to parse HTML")
+		return staticPage{}, fmt.Errorf("failed to parse %s: %w", url, err)
+	}
+
+	page := staticPage{
+		meta:       extractMetadata(doc, finalURL),
+		jsRendered: looksJSRendered(doc),
+	}
+	page.meta.ContentType = mediaType
+	page.meta.StatusCode = resp.StatusCode
+	if page.meta.Language == "" {
+		page.meta.Language = resp.Header.Get("Content-Language")
+	}
+	release()
+	page.extracted = s.extractors.apply(ctx, doc, &page.meta, log)
+	if s.extractArticles && !page.jsRendered {
+		page.meta.Article = extractArticle(doc, finalURL)
+	}
+	log.WithFields(logrus.Fields{
+		"title":       page.meta.Title,
+		"js_rendered": page.jsRendered,
+	}).Info("Metadata scraping completed successfully")
+	return page, nil
+}
+
+// fileName returns the last path element as a fallback title for documents
+// without metadata, or "" for the site root.
+func fileName(p string) string {
+	name := path.Base(p)
+	if name == "/" || name == "." {
+		return ""
+	}
+	return name
+}
+
+// looksJSRendered reports whether a document looks like an application shell
+// that only gets its content from scripts: it has scripts but next to no
+// visible text, or a <noscript> asking to enable JavaScript.
+func looksJSRendered(doc *html.Node) bool {
+	var textLen, scripts int
+	var noscriptJS bool
+
+	var walk func(n *html.Node, inBody bool)
+	walk = func(n *html.Node, inBody bool) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script":
+				scripts++
+				return
+			case "style", "template", "title", "head":
+				return
+			case "noscript":
+				if strings.Contains(strings.ToLower(nodeText(n)), "javascript") {
+					noscriptJS = true
+				}
+				return
+			case "body":
+				inBody = true
+			}
+		}
+		if n.Type == html.TextNode && inBody {
+			textLen += len(strings.TrimSpace(n.Data))
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, inBody)
+		}
+	}
+	walk(doc, false)
+
+	return scripts > 0 && (noscriptJS || textLen < 200)
+}
+
+// attr returns the value of an attribute of n, or "" if it is not set.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// nodeText returns the concatenated text content of n.
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// IsPermanentFailure reports whether err means the page itself is gone, or may
+// not be fetched at all, so a headless browser or a retry would not fare any better.
+func IsPermanentFailure(err error) bool {
+	if errors.Is(err, ErrDisallowed) || errors.Is(err, ErrBlockedAddress) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone
+	}
+	return errors.Is(err, context.Canceled)
+}
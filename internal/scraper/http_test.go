@@ -0,0 +1,199 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const staticHTML = `<!doctype html>
+<html><head>
+<title> Static Page </title>
+<meta name="description" content="A page with static metadata.">
+</head><body><p>Hello</p></body></html>`
+
+const shellHTML = `<!doctype html>
+<html><head><title></title></head>
+<body><noscript>You need to enable JavaScript to run this app.</noscript>
+<div id="root"></div><script src="/app.js"></script></body></html>`
+
+// fakeScraper is a Scraper stub that records calls.
+type fakeScraper struct {
+	title string
+	err   error
+	calls int
+}
+
+func (f *fakeScraper) ScrapeMetadata(ctx context.Context, url string) (Metadata, error) {
+	f.calls++
+	if f.err != nil {
+		return Metadata{}, f.err
+	}
+	return Metadata{URL: url, Title: f.title}, nil
+}
+
+func (f *fakeScraper) Close() error { return nil }
+
+// newTestServer serves fixed responses by path.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/static", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, staticHTML)
+	})
+	mux.HandleFunc("/og", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head><meta property="og:title" content="OG Title"><meta property="og:description" content="OG Desc"></head><body>`+
+			`<p>Enough server-rendered text to not look like an application shell at all, with several sentences of real content.</p>`+
+			`<p>Another paragraph that pushes the amount of visible text over the heuristic threshold used by the scraper.</p>`+
+			`<script>console.log("analytics")</script></body></html>`)
+	})
+	mux.HandleFunc("/shell", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, shellHTML)
+	})
+	mux.HandleFunc("/pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		io.WriteString(w, "%PDF-1.4")
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/static", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/blocked", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	srv := httptest.NewServer(mux) // unknown paths return 404
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestLogger() logrus.FieldLogger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// TestHTTPScraper_ScrapeMetadata tests static metadata extraction.
+func TestHTTPScraper_ScrapeMetadata(t *testing.T) {
+	srv := newTestServer(t)
+	s := NewHTTPScraper(HTTPOptions{Client: srv.Client()}, newTestLogger())
+	ctx := context.Background()
+
+	meta, err := s.ScrapeMetadata(ctx, srv.URL+"/static")
+	require.NoError(t, err)
+	assert.Equal(t, "Static Page", meta.Title)
+	assert.Equal(t, "A page with static metadata.", meta.Description)
+	assert.Equal(t, "text/html", meta.ContentType)
+
+	meta, err = s.ScrapeMetadata(ctx, srv.URL+"/og")
+	require.NoError(t, err)
+	assert.Equal(t, "OG Title", meta.Title, "og:title should be used when <title> is missing")
+	assert.Equal(t, "OG Desc", meta.Description)
+
+	meta, err = s.ScrapeMetadata(ctx, srv.URL+"/redirect")
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/static", meta.URL, "The final URL after redirects should be recorded")
+
+	meta, err = s.ScrapeMetadata(ctx, srv.URL+"/pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", meta.ContentType)
+
+	_, err = s.ScrapeMetadata(ctx, srv.URL+"/missing")
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+}
+
+// TestHTTPScraper_PrivateNetworks tests that the default client refuses
+// internal addresses unless they are allowed.
+func TestHTTPScraper_PrivateNetworks(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	_, err := NewHTTPScraper(HTTPOptions{}, newTestLogger()).ScrapeMetadata(ctx, srv.URL+"/static")
+	assert.ErrorIs(t, err, ErrBlockedAddress)
+	assert.True(t, IsPermanentFailure(err), "A blocked address should not fall back to the browser")
+
+	meta, err := NewHTTPScraper(HTTPOptions{AllowPrivateNetworks: true}, newTestLogger()).ScrapeMetadata(ctx, srv.URL+"/static")
+	require.NoError(t, err)
+	assert.Equal(t, "Static Page", meta.Title)
+
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "169.254.169.254", "::1", "fe80::1", "0.0.0.0", "::ffff:10.0.0.1"} {
+		assert.True(t, blockedIP(netip.MustParseAddr(ip)), ip)
+	}
+	assert.False(t, blockedIP(netip.MustParseAddr("93.184.216.34")))
+	assert.ErrorIs(t, checkPublicURL(ctx, "http://169.254.169.254/latest/meta-data/"), ErrBlockedAddress)
+}
+
+// TestHTTPScraper_RedirectPoliteness tests that a redirect to another host
+// waits for the limits of that host.
+func TestHTTPScraper_RedirectPoliteness(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			io.WriteString(w, "User-agent: *\nDisallow: /\n")
+			return
+		}
+		io.WriteString(w, staticHTML)
+	}))
+	t.Cleanup(target.Close)
+	// The same server under another host name
+	targetURL := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, targetURL+"/page", http.StatusFound)
+	}))
+	t.Cleanup(origin.Close)
+
+	p := NewPoliteness(PolitenessOptions{RespectRobots: true, Client: origin.Client()}, newTestLogger())
+	s := NewHTTPScraper(HTTPOptions{Client: origin.Client(), Politeness: p}, newTestLogger())
+	_, err := s.ScrapeMetadata(context.Background(), origin.URL+"/")
+	assert.ErrorIs(t, err, ErrDisallowed, "robots.txt of the redirect target should apply")
+}
+
+// TestFallbackScraper tests when the headless scraper is used.
+func TestFallbackScraper(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		path          string
+		headlessErr   error
+		wantTitle     string
+		wantErr       bool
+		wantFallbacks int
+	}{
+		{path: "/static", wantTitle: "Static Page"},
+		{path: "/og", wantTitle: "OG Title"},
+		{path: "/shell", wantTitle: "Rendered", wantFallbacks: 1},
+		{path: "/shell", headlessErr: errors.New("no browser"), wantTitle: "", wantFallbacks: 1},
+		{path: "/blocked", wantTitle: "Rendered", wantFallbacks: 1},
+		{path: "/blocked", headlessErr: errors.New("no browser"), wantErr: true, wantFallbacks: 1},
+		{path: "/pdf", wantTitle: "pdf"},
+		{path: "/missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			headless := &fakeScraper{title: "Rendered", err: tt.headlessErr}
+			s := NewFallbackScraper(NewHTTPScraper(HTTPOptions{Client: srv.Client()}, newTestLogger()), headless, newTestLogger())
+
+			meta, err := s.ScrapeMetadata(ctx, srv.URL+tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantTitle, meta.Title)
+			}
+			assert.Equal(t, tt.wantFallbacks, headless.calls)
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package scraper
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/readability"
+)
+
+// Metadata is everything a scraper learned about a page.
+type Metadata struct {
+	// URL is the final URL of the page after redirects.
+	URL string `json:"url"`
+	// CanonicalURL is the URL the page declares as canonical (<link rel="canonical"> or og:url).
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	// StatusCode is the HTTP status the page was served with, or 0 if unknown.
+	StatusCode int `json:"status_code,omitempty"`
+
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	SiteName    string    `json:"site_name,omitempty"`
+	Author      string    `json:"author,omitempty"`
+	PublishedAt time.Time `json:"published_at,omitzero"`
+	Language    string    `json:"language,omitempty"`
+	// ContentType is the media type of the document, e.g. "text/html" or "application/pdf".
+	ContentType string `json:"content_type,omitempty"`
+	// Type is the Open Graph object type, e.g. "article" or "video.other".
+	Type       string   `json:"type,omitempty"`
+	ImageURL   string   `json:"image_url,omitempty"`
+	FaviconURL string   `json:"favicon_url,omitempty"`
+	Keywords   []string `json:"keywords,omitempty"`
+
+	// OpenGraph holds all og:* properties (first value wins), keyed without the "og:" prefix.
+	OpenGraph map[string]string `json:"open_graph,omitempty"`
+	// TwitterCard holds all twitter:* properties, keyed without the "twitter:" prefix.
+	TwitterCard map[string]string `json:"twitter_card,omitempty"`
+	// JSONLD holds the JSON-LD objects embedded in the page, with @graph flattened.
+	JSONLD []map[string]any `json:"json_ld,omitempty"`
+
+	// Extra holds site-specific details found by an Extractor, keyed by the Extra* constants.
+	Extra map[string]string `json:"extra,omitempty"`
+
+	// Article is the readable main content of the page, if article extraction
+	// is enabled and the page has one. LinkID and UserID are not set.
+	Article *domain.Article `json:"-"`
+}
+
+// ApplyTo copies the scraped metadata onto a link. Fields already set by the
+// user, such as tags and read state, are left untouched.
+func (m Metadata) ApplyTo(link *domain.Link) {
+	link.Title = m.Title
+	link.Description = m.Description
+	if m.URL != "" && m.URL != link.URL {
+		link.ResolvedURL = m.URL
+	}
+	link.PreviewImageURL = m.ImageURL
+	link.SiteName = m.SiteName
+	link.Author = m.Author
+	link.PublishedAt = m.PublishedAt
+	link.Language = m.Language
+	link.ContentType = m.ContentType
+	link.FaviconURL = m.FaviconURL
+	link.Keywords = m.Keywords
+	link.Extra = m.Extra
+	if m.StatusCode != 0 {
+		link.HTTPStatus = m.StatusCode
+	}
+}
+
+// extractArticle extracts the readable article body of a page, or returns nil
+// if the page has none.
+func extractArticle(doc *html.Node, pageURL string) *domain.Article {
+	article, ok := readability.Extract(doc, pageURL)
+	if !ok {
+		return nil
+	}
+	return &domain.Article{
+		HTML:        article.HTML,
+		Text:        article.Text,
+		WordCount:   article.WordCount,
+		ExtractedAt: time.Now(),
+	}
+}
+
+// dateLayouts are the date formats accepted for publication dates.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// extractMetadata collects metadata from a parsed HTML document.
+// pageURL is the final URL of the page and is used to resolve relative links.
+func extractMetadata(doc *html.Node, pageURL string) Metadata {
+	meta := Metadata{
+		URL:         pageURL,
+		ContentType: "text/html",
+		OpenGraph:   make(map[string]string),
+		TwitterCard: make(map[string]string),
+	}
+	named := make(map[string]string) // <meta name=...>, lower-cased
+	var title, canonical, icon, imageSrc string
+	var articleTags []string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "html":
+				meta.Language = strings.TrimSpace(attr(n, "lang"))
+			case "title":
+				if title == "" {
+					title = strings.TrimSpace(nodeText(n))
+				}
+				return
+			case "meta":
+				content := strings.TrimSpace(attr(n, "content"))
+				if content == "" {
+					break
+				}
+				property := strings.ToLower(attr(n, "property"))
+				name := strings.ToLower(attr(n, "name"))
+				switch {
+				case strings.HasPrefix(property, "og:"):
+					setFirst(meta.OpenGraph, strings.TrimPrefix(property, "og:"), content)
+				case property == "article:tag":
+					articleTags = append(articleTags, content)
+				case property != "":
+					setFirst(named, property, content)
+				}
+				switch {
+				case strings.HasPrefix(name, "twitter:"):
+					setFirst(meta.TwitterCard, strings.TrimPrefix(name, "twitter:"), content)
+				case name != "":
+					setFirst(named, name, content)
+				}
+				if strings.EqualFold(attr(n, "http-equiv"), "content-language") {
+					setFirst(named, "content-language", content)
+				}
+			case "link":
+				rels := strings.Fields(strings.ToLower(attr(n, "rel")))
+				href := strings.TrimSpace(attr(n, "href"))
+				for _, rel := range rels {
+					switch rel {
+					case "canonical":
+						canonical = href
+					case "icon":
+						if icon == "" {
+							icon = href
+						}
+					case "apple-touch-icon":
+						if icon == "" {
+							icon = href
+						}
+					case "image_src":
+						imageSrc = href
+					}
+				}
+			case "script":
+				if strings.EqualFold(strings.TrimSpace(attr(n, "type")), "application/ld+json") {
+					meta.JSONLD = append(meta.JSONLD, parseJSONLD(nodeText(n))...)
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	ld := primaryJSONLD(meta.JSONLD)
+	og, tw := meta.OpenGraph, meta.TwitterCard
+
+	meta.Title = firstNonEmpty(title, og["title"], tw["title"], ldString(ld["headline"]), ldString(ld["name"]))
+	meta.Description = firstNonEmpty(named["description"], og["description"], tw["description"], ldString(ld["description"]))
+	meta.SiteName = firstNonEmpty(og["site_name"], named["application-name"], ldName(ld["publisher"]))
+	meta.Author = firstNonEmpty(named["author"], ldName(ld["author"]), named["article:author"], tw["creator"])
+	meta.Type = og["type"]
+	meta.CanonicalURL = resolveURL(pageURL, firstNonEmpty(canonical, og["url"]))
+	meta.ImageURL = resolveURL(pageURL, firstNonEmpty(og["image"], og["image:url"], og["image:secure_url"], tw["image"], tw["image:src"], ldImage(ld["image"]), imageSrc))
+	meta.FaviconURL = resolveURL(pageURL, firstNonEmpty(icon, "/favicon.ico"))
+	meta.Language = firstNonEmpty(meta.Language, named["content-language"], og["locale"])
+	meta.PublishedAt = parseDate(firstNonEmpty(named["article:published_time"], ldString(ld["datePublished"]), named["date"], named["pubdate"], named["dc.date"]))
+	meta.Keywords = keywords(named["keywords"], articleTags)
+
+	if len(og) == 0 {
+		meta.OpenGraph = nil
+	}
+	if len(tw) == 0 {
+		meta.TwitterCard = nil
+	}
+	return meta
+}
+
+// parseJSONLD decodes a JSON-LD script body into objects, flattening arrays and @graph.
+// Invalid JSON is ignored; many sites ship broken structured data.
+func parseJSONLD(text string) []map[string]any {
+	var v any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &v); err != nil {
+		return nil
+	}
+	var objects []map[string]any
+	var collect func(v any)
+	collect = func(v any) {
+		switch t := v.(type) {
+		case []any:
+			for _, item := range t {
+				collect(item)
+			}
+		case map[string]any:
+			if graph, ok := t["@graph"]; ok {
+				collect(graph)
+				return
+			}
+			objects = append(objects, t)
+		}
+	}
+	collect(v)
+	return objects
+}
+
+// primaryJSONLD picks the JSON-LD object that describes the page's main content.
+func primaryJSONLD(objects []map[string]any) map[string]any {
+	preferred := []string{"Article", "NewsArticle", "BlogPosting", "TechArticle", "Report", "VideoObject", "Product", "Recipe", "WebPage"}
+	for _, typ := range preferred {
+		for _, obj := range objects {
+			if ldHasType(obj, typ) {
+				return obj
+			}
+		}
+	}
+	if len(objects) > 0 {
+		return objects[0]
+	}
+	return map[string]any{}
+}
+
+// ldHasType reports whether a JSON-LD object has the given @type.
+func ldHasType(obj map[string]any, typ string) bool {
+	switch t := obj["@type"].(type) {
+	case string:
+		return t == typ
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ldString returns a JSON-LD value as a string, if it is one.
+func ldString(v any) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
+
+// ldName returns the name of a JSON-LD person or organization, which may be
+// given as a string, an object with a "name", or a list of either.
+func ldName(v any) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case map[string]any:
+		return ldString(t["name"])
+	case []any:
+		var names []string
+		for _, item := range t {
+			if name := ldName(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// ldImage returns the URL of a JSON-LD image, which may be a string, an
+// ImageObject or a list of either.
+func ldImage(v any) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case map[string]any:
+		return ldString(t["url"])
+	case []any:
+		if len(t) > 0 {
+			return ldImage(t[0])
+		}
+	}
+	return ""
+}
+
+// resolveURL resolves ref against base. It returns "" for an empty ref and
+// ref unchanged if either URL cannot be parsed.
+func resolveURL(base, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+// parseDate parses a publication date in one of the common formats.
+func parseDate(s string) time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// keywords merges the comma-separated keywords meta tag with article:tag values.
+func keywords(metaKeywords string, tags []string) []string {
+	seen := make(map[string]struct{})
+	var out []string
+	for _, k := range append(strings.Split(metaKeywords, ","), tags...) {
+		k = strings.TrimSpace(k)
+		key := strings.ToLower(k)
+		if k == "" {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, k)
+	}
+	return out
+}
+
+// setFirst sets m[key] unless a value is already present.
+func setFirst(m map[string]string, key, value string) {
+	if _, ok := m[key]; !ok {
+		m[key] = value
+	}
+}
+
+// firstNonEmpty returns the first non-empty string.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
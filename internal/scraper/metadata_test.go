@@ -0,0 +1,75 @@
+package scraper
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+
+	"jetengine/internal/domain"
+)
+
+// parseFixture parses an HTML file from testdata.
+func parseFixture(t *testing.T, name string) *html.Node {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	doc, err := html.Parse(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	return doc
+}
+
+// TestExtractMetadata tests extraction of Open Graph, Twitter Card and JSON-LD metadata.
+func TestExtractMetadata(t *testing.T) {
+	meta := extractMetadata(parseFixture(t, "article.html"), "https://blog.example.com/go-schedulers?ref=feed")
+
+	assert.Equal(t, "Understanding Go Schedulers | Example Blog", meta.Title)
+	assert.Equal(t, "A deep dive into the Go runtime scheduler.", meta.Description)
+	assert.Equal(t, "Example Blog", meta.SiteName)
+	assert.Equal(t, "Jane Doe", meta.Author)
+	assert.Equal(t, "article", meta.Type)
+	assert.Equal(t, "en-GB", meta.Language)
+	assert.Equal(t, "text/html", meta.ContentType)
+	assert.Equal(t, "https://blog.example.com/go-schedulers", meta.CanonicalURL)
+	assert.Equal(t, "https://blog.example.com/images/cover.png", meta.ImageURL, "Relative image URLs should be resolved")
+	assert.Equal(t, "https://blog.example.com/static/favicon.png", meta.FaviconURL)
+	assert.Equal(t, time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC), meta.PublishedAt)
+	assert.Equal(t, []string{"go", "runtime", "Scheduling", "concurrency"}, meta.Keywords)
+	assert.Equal(t, "summary_large_image", meta.TwitterCard["card"])
+	assert.Equal(t, "Understanding Go Schedulers", meta.OpenGraph["title"])
+	require.Len(t, meta.JSONLD, 2, "@graph should be flattened")
+}
+
+// TestExtractMetadata_Fallbacks tests fallbacks for pages with sparse metadata.
+func TestExtractMetadata_Fallbacks(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><head>
+<script type="application/ld+json">{"@type":"NewsArticle","headline":"LD Headline","description":"LD Desc",
+"author":"John Roe","image":{"@type":"ImageObject","url":"https://cdn.example.com/a.jpg"}}</script>
+<script type="application/ld+json">{ not json </script>
+</head><body></body></html>`))
+	require.NoError(t, err)
+
+	meta := extractMetadata(doc, "https://news.example.com/a")
+	assert.Equal(t, "LD Headline", meta.Title)
+	assert.Equal(t, "LD Desc", meta.Description)
+	assert.Equal(t, "John Roe", meta.Author)
+	assert.Equal(t, "https://cdn.example.com/a.jpg", meta.ImageURL)
+	assert.Equal(t, "https://news.example.com/favicon.ico", meta.FaviconURL, "The default favicon location should be used")
+	assert.Nil(t, meta.OpenGraph)
+}
+
+// TestMetadata_ApplyTo tests that scraped metadata is copied onto a link.
+func TestMetadata_ApplyTo(t *testing.T) {
+	link := domain.Link{URL: "https://short.example/x", Tags: []string{"keep"}, Read: true}
+	Metadata{URL: "https://example.com/x", Title: "T", ImageURL: "https://example.com/i.png"}.ApplyTo(&link)
+
+	assert.Equal(t, "T", link.Title)
+	assert.Equal(t, "https://example.com/x", link.ResolvedURL)
+	assert.Equal(t, "https://example.com/i.png", link.PreviewImageURL)
+	assert.Equal(t, []string{"keep"}, link.Tags, "User data should be kept")
+	assert.True(t, link.Read)
+}
@@ -0,0 +1,85 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned for pages on loopback, private, link-local or
+// unspecified addresses, which the scrapers refuse to fetch unless private
+// networks are allowed. Otherwise anyone saving a link could make the server
+// read internal services or cloud metadata endpoints.
+var ErrBlockedAddress = errors.New("address is not publicly routable")
+
+// blockedIP reports whether ip belongs to a network the scrapers must not reach.
+func blockedIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// guardedDialer returns a dialer that refuses connections to blocked
+// addresses. The check runs on the resolved address of every connection, so
+// it also covers redirects and host names that resolve to internal addresses.
+func guardedDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+			}
+			if blockedIP(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+			}
+			return nil
+		},
+	}
+}
+
+// newGuardedClient creates an HTTP client with the given timeout that
+// refuses blocked addresses unless allowPrivate is set.
+func newGuardedClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if !allowPrivate {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = guardedDialer().DialContext
+		client.Transport = transport
+	}
+	return client
+}
+
+// checkPublicURL resolves the host of rawURL and fails with ErrBlockedAddress
+// if any of its addresses is blocked. It guards clients that do not dial
+// through guardedDialer, such as the headless browser.
+func checkPublicURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		// Malformed URLs fail in the scraper itself
+		return nil
+	}
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil {
+		if blockedIP(ip) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, u.Hostname())
+		}
+		return nil
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", u.Hostname(), err)
+	}
+	for _, ip := range ips {
+		if blockedIP(ip) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, u.Hostname(), ip)
+		}
+	}
+	return nil
+}
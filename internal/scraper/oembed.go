@@ -0,0 +1,66 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// maxOEmbedBytes limits how much of an oEmbed response is read.
+const maxOEmbedBytes = 256 << 10
+
+// oEmbed is an oEmbed response (https://oembed.com). Only the fields used by
+// the extractors are decoded.
+type oEmbed struct {
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	AuthorURL    string `json:"author_url"`
+	ProviderName string `json:"provider_name"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	HTML         string `json:"html"`
+}
+
+// fetchOEmbed requests the oEmbed representation of pageURL from endpoint.
+// The request counts against the politeness limits of the endpoint's host.
+func fetchOEmbed(ctx context.Context, client *http.Client, politeness *Politeness, endpoint, pageURL string) (oEmbed, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return oEmbed{}, fmt.Errorf("invalid oEmbed endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("url", pageURL)
+	q.Set("format", "json")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return oEmbed{}, fmt.Errorf("failed to create oEmbed request: %w", err)
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	release, err := politeness.acquireAPI(ctx, u.String())
+	if err != nil {
+		return oEmbed{}, err
+	}
+	defer release()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return oEmbed{}, fmt.Errorf("failed to fetch oEmbed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return oEmbed{}, fmt.Errorf("failed to fetch oEmbed: %w", &StatusError{StatusCode: resp.StatusCode})
+	}
+
+	var data oEmbed
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOEmbedBytes)).Decode(&data); err != nil {
+		return oEmbed{}, fmt.Errorf("failed to decode oEmbed: %w", err)
+	}
+	return data, nil
+}
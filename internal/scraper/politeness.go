@@ -0,0 +1,376 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrDisallowed is returned for pages that robots.txt does not allow the scraper to fetch.
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+const (
+	// robotsTTL is how long a host's robots.txt is cached.
+	robotsTTL = 12 * time.Hour
+	// robotsErrorTTL is how long a robots.txt that could not be fetched is treated as allowing everything.
+	robotsErrorTTL = time.Hour
+	// maxRobotsBytes limits how much of a robots.txt is read.
+	maxRobotsBytes = 512 << 10
+	// maxCrawlDelay caps the Crawl-delay honored from robots.txt, so a site cannot stall the queue.
+	maxCrawlDelay = 30 * time.Second
+	// hostIdleTTL is how long the state of a host without requests is kept.
+	// It must exceed the longest per-host delay, so evicting a host never
+	// lets a request start early.
+	hostIdleTTL = 30 * time.Minute
+)
+
+// PolitenessOptions configures a Politeness. Zero values disable the corresponding limit.
+type PolitenessOptions struct {
+	// HostConcurrency limits how many requests run at once against a single host.
+	HostConcurrency int
+	// HostDelay is the minimum time between the starts of two requests to the same host.
+	HostDelay time.Duration
+	// RequestsPerSecond is the rate of the token bucket shared by all hosts.
+	RequestsPerSecond float64
+	// Burst is how many requests the shared bucket allows at once. Default 1.
+	Burst int
+	// RespectRobots makes pages disallowed by robots.txt fail with ErrDisallowed,
+	// and honors the Crawl-delay of a site if it is longer than HostDelay.
+	RespectRobots bool
+	// Client fetches robots.txt files. If nil, a client with a 10 second
+	// timeout is used, which refuses private addresses unless
+	// AllowPrivateNetworks is set.
+	Client *http.Client
+	// AllowPrivateNetworks lets the default client fetch robots.txt from
+	// loopback, private and link-local addresses.
+	AllowPrivateNetworks bool
+}
+
+// Politeness keeps the scrapers from hammering a site: every page request
+// first waits for a free per-host slot, the per-host delay and a token from
+// a bucket shared by all hosts. It is shared by the HTTP and rod scrapers so
+// their requests count against the same limits. A nil *Politeness imposes no limits.
+type Politeness struct {
+	opts   PolitenessOptions
+	bucket *tokenBucket
+	log    logrus.FieldLogger
+
+	mu        sync.Mutex
+	hosts     map[string]*hostState
+	lastSweep time.Time // last eviction of idle hosts
+}
+
+// hostState tracks the requests to one host.
+type hostState struct {
+	// slots holds a token per running request when concurrency is limited.
+	slots chan struct{}
+
+	// users and lastUsed are guarded by Politeness.mu. A host is only
+	// evicted while no request holds it.
+	users    int
+	lastUsed time.Time
+
+	mu   sync.Mutex
+	next time.Time // earliest start of the next request
+
+	robotsMu      sync.Mutex
+	robots        robotsRules
+	robotsExpires time.Time
+	// robotsFetch is closed once the running robots.txt fetch finished; it is nil while none runs.
+	robotsFetch chan struct{}
+}
+
+// NewPoliteness creates the shared request limits.
+func NewPoliteness(opts PolitenessOptions, logger logrus.FieldLogger) *Politeness {
+	if opts.Client == nil {
+		opts.Client = newGuardedClient(10*time.Second, opts.AllowPrivateNetworks)
+	}
+	p := &Politeness{
+		opts:      opts,
+		log:       logger.WithField("component", "politeness"),
+		hosts:     make(map[string]*hostState),
+		lastSweep: time.Now(),
+	}
+	if opts.RequestsPerSecond > 0 {
+		p.bucket = newTokenBucket(opts.RequestsPerSecond, max(opts.Burst, 1))
+	}
+	return p
+}
+
+// Acquire waits until a request to pageURL may start and returns a function
+// that must be called once the request finished. It fails with ErrDisallowed
+// if robots.txt forbids the page, or with the context's error.
+func (p *Politeness) Acquire(ctx context.Context, pageURL string) (release func(), err error) {
+	if p == nil {
+		return func() {}, nil
+	}
+	return p.acquire(ctx, pageURL, p.opts.RespectRobots)
+}
+
+// acquireAPI is like Acquire for requests to APIs such as oEmbed endpoints,
+// which robots.txt does not govern. Only the request limits apply.
+func (p *Politeness) acquireAPI(ctx context.Context, apiURL string) (release func(), err error) {
+	if p == nil {
+		return func() {}, nil
+	}
+	return p.acquire(ctx, apiURL, false)
+}
+
+// acquire waits for the limits of the host of rawURL, checking robots.txt first if robots is set.
+func (p *Politeness) acquire(ctx context.Context, rawURL string, robots bool) (release func(), err error) {
+	noop := func() {}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		// Malformed URLs fail in the scraper itself
+		return noop, nil
+	}
+	host := p.host(u.Hostname())
+	done := func() { p.done(host) }
+	log := p.log.WithField("host", u.Hostname())
+
+	delay := p.opts.HostDelay
+	if robots {
+		rules, err := host.robotsRules(ctx, p.opts.Client, u, log)
+		if err != nil {
+			done()
+			return noop, err
+		}
+		if !rules.allowed(robotsPath(u)) {
+			log.WithField("url", rawURL).Info("Page disallowed by robots.txt")
+			done()
+			return noop, fmt.Errorf("%w: %s", ErrDisallowed, rawURL)
+		}
+		delay = max(delay, min(rules.crawlDelay, maxCrawlDelay))
+	}
+
+	if host.slots != nil {
+		select {
+		case host.slots <- struct{}{}:
+		case <-ctx.Done():
+			done()
+			return noop, ctx.Err()
+		}
+		release = func() {
+			<-host.slots
+			done()
+		}
+	} else {
+		release = done
+	}
+
+	if err := host.waitTurn(ctx, delay); err != nil {
+		release()
+		return noop, err
+	}
+	if p.bucket != nil {
+		if err := p.bucket.wait(ctx); err != nil {
+			release()
+			return noop, err
+		}
+	}
+	return release, nil
+}
+
+// host returns the state of a host, creating it on first use, and marks it
+// in use until the matching call of done. It also evicts hosts that have been
+// idle for hostIdleTTL, so the map does not grow with every site ever scraped.
+func (p *Politeness) host(name string) *hostState {
+	name = strings.ToLower(name)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(p.lastSweep) >= hostIdleTTL {
+		for n, h := range p.hosts {
+			if h.users == 0 && now.Sub(h.lastUsed) >= hostIdleTTL {
+				delete(p.hosts, n)
+			}
+		}
+		p.lastSweep = now
+	}
+
+	h, ok := p.hosts[name]
+	if !ok {
+		h = &hostState{}
+		if p.opts.HostConcurrency > 0 {
+			h.slots = make(chan struct{}, p.opts.HostConcurrency)
+		}
+		p.hosts[name] = h
+	}
+	h.users++
+	return h
+}
+
+// done marks the end of a use of a host returned by host.
+func (p *Politeness) done(h *hostState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h.users--
+	h.lastUsed = time.Now()
+}
+
+// waitTurn reserves the next start time of the host and sleeps until then.
+func (h *hostState) waitTurn(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	h.mu.Lock()
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(delay)
+	h.mu.Unlock()
+
+	return sleep(ctx, start.Sub(now))
+}
+
+// robotsRules returns the cached robots.txt rules of the host, fetching them
+// when missing or expired. A robots.txt that is missing or cannot be fetched
+// allows everything. Only one request per host fetches robots.txt; the others
+// wait for its result without holding the lock. It only fails with the
+// context's error.
+func (h *hostState) robotsRules(ctx context.Context, client *http.Client, page *url.URL, log logrus.FieldLogger) (robotsRules, error) {
+	for {
+		h.robotsMu.Lock()
+		if time.Now().Before(h.robotsExpires) {
+			rules := h.robots
+			h.robotsMu.Unlock()
+			return rules, nil
+		}
+		fetching := h.robotsFetch
+		if fetching == nil {
+			h.robotsFetch = make(chan struct{})
+		}
+		h.robotsMu.Unlock()
+		if fetching == nil {
+			break
+		}
+
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return robotsRules{}, ctx.Err()
+		}
+	}
+
+	robotsURL := (&url.URL{Scheme: page.Scheme, Host: page.Host, Path: "/robots.txt"}).String()
+	rules, err := fetchRobots(ctx, client, robotsURL)
+	ttl := robotsTTL
+	if err != nil {
+		log.WithError(err).Debug("Could not fetch robots.txt, allowing all pages")
+		rules, ttl = robotsRules{}, robotsErrorTTL
+	}
+
+	h.robotsMu.Lock()
+	defer h.robotsMu.Unlock()
+	close(h.robotsFetch)
+	h.robotsFetch = nil
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// The fetch was abandoned, not answered; a waiting request retries it
+		return robotsRules{}, ctxErr
+	}
+	h.robots, h.robotsExpires = rules, time.Now().Add(ttl)
+	return rules, nil
+}
+
+// fetchRobots downloads and parses a robots.txt file. A client error status
+// such as 404 means there are no rules.
+func fetchRobots(ctx context.Context, client *http.Client, robotsURL string) (robotsRules, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return robotsRules{}, fmt.Errorf("failed to create robots.txt request: %w", err)
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return robotsRules{}, fmt.Errorf("failed to fetch %s: %w", robotsURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return robotsRules{}, &StatusError{StatusCode: resp.StatusCode}
+	case resp.StatusCode >= 400:
+		return robotsRules{}, nil
+	}
+	return parseRobots(io.LimitReader(resp.Body, maxRobotsBytes), robotsAgent), nil
+}
+
+// robotsPath returns the part of a URL that robots.txt rules match against.
+func robotsPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
+
+// tokenBucket is a rate limiter that allows bursts of up to burst requests
+// and refills at rate tokens per second.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full bucket.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token, sleeping until one is available.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep pauses for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,225 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const robotsTxt = `# Example robots.txt
+User-agent: *
+Disallow: /private/
+Allow: /private/*.html$
+Disallow: /*.pdf$
+
+User-agent: OtherBot
+User-agent: JetEngine
+Disallow: /no-jet
+Crawl-delay: 2
+`
+
+// TestParseRobots tests group selection and rule precedence.
+func TestParseRobots(t *testing.T) {
+	generic := parseRobots(strings.NewReader(robotsTxt), "somebot")
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{"/", true},
+		{"/private/", false},
+		{"/private/notes", false},
+		{"/private/page.html", true},
+		{"/private/page.html?print=1", false},
+		{"/docs/manual.pdf", false},
+		{"/docs/manual.pdf?download=1", true},
+		{"/no-jet", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, generic.allowed(tt.path), tt.path)
+	}
+	assert.Zero(t, generic.crawlDelay)
+
+	// A group naming the agent replaces the "*" group
+	specific := parseRobots(strings.NewReader(robotsTxt), robotsAgent)
+	assert.False(t, specific.allowed("/no-jet/page"))
+	assert.True(t, specific.allowed("/private/"))
+	assert.Equal(t, 2*time.Second, specific.crawlDelay)
+
+	assert.True(t, parseRobots(strings.NewReader("User-agent: *\nDisallow:\n"), robotsAgent).allowed("/anything"))
+}
+
+// TestPoliteness_Robots tests that disallowed pages fail permanently and robots.txt is cached.
+func TestPoliteness_Robots(t *testing.T) {
+	var robotsFetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			robotsFetches.Add(1)
+			io.WriteString(w, "User-agent: *\nDisallow: /private\n")
+		}
+	}))
+	defer srv.Close()
+
+	p := NewPoliteness(PolitenessOptions{RespectRobots: true, Client: srv.Client()}, newTestLogger())
+	ctx := context.Background()
+
+	release, err := p.Acquire(ctx, srv.URL+"/public")
+	require.NoError(t, err)
+	release()
+
+	_, err = p.Acquire(ctx, srv.URL+"/private/page")
+	assert.ErrorIs(t, err, ErrDisallowed)
+	assert.True(t, IsPermanentFailure(err))
+	assert.Equal(t, int32(1), robotsFetches.Load(), "robots.txt should be fetched once per host")
+
+	// Concurrent requests to a new host share one robots.txt fetch
+	p = NewPoliteness(PolitenessOptions{RespectRobots: true, Client: srv.Client()}, newTestLogger())
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := p.Acquire(ctx, srv.URL+"/public")
+			if assert.NoError(t, err) {
+				release()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), robotsFetches.Load(), "robots.txt should be fetched once per host")
+
+	// A request cancelled while waiting for robots.txt fails with its context's error
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = NewPoliteness(PolitenessOptions{RespectRobots: true, Client: srv.Client()}, newTestLogger()).Acquire(cancelled, srv.URL+"/public")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Without RespectRobots every page is allowed
+	release, err = NewPoliteness(PolitenessOptions{}, newTestLogger()).Acquire(ctx, srv.URL+"/private/page")
+	require.NoError(t, err)
+	release()
+}
+
+// TestPoliteness_Limits tests the per-host concurrency limit and delay.
+func TestPoliteness_Limits(t *testing.T) {
+	ctx := context.Background()
+	p := NewPoliteness(PolitenessOptions{HostConcurrency: 1}, newTestLogger())
+
+	release, err := p.Acquire(ctx, "https://example.com/a")
+	require.NoError(t, err)
+
+	// Another host is not blocked by the busy one
+	other, err := p.Acquire(ctx, "https://example.org/a")
+	require.NoError(t, err)
+	other()
+
+	// The busy host is, until its request finished
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = p.Acquire(waitCtx, "https://EXAMPLE.com/b")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	release()
+	release, err = p.Acquire(ctx, "https://example.com/b")
+	require.NoError(t, err)
+	release()
+
+	// Consecutive requests to a host are spaced by the delay
+	p = NewPoliteness(PolitenessOptions{HostDelay: 30 * time.Millisecond}, newTestLogger())
+	start := time.Now()
+	for range 3 {
+		release, err := p.Acquire(ctx, "https://example.com/")
+		require.NoError(t, err)
+		release()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+
+	var none *Politeness
+	release, err = none.Acquire(ctx, "https://example.com/")
+	require.NoError(t, err)
+	release()
+}
+
+// TestPoliteness_Eviction tests that idle hosts are dropped and busy ones are kept.
+func TestPoliteness_Eviction(t *testing.T) {
+	ctx := context.Background()
+	p := NewPoliteness(PolitenessOptions{HostConcurrency: 1}, newTestLogger())
+
+	idle, err := p.Acquire(ctx, "https://idle.example/")
+	require.NoError(t, err)
+	idle()
+	busy, err := p.Acquire(ctx, "https://busy.example/")
+	require.NoError(t, err)
+
+	p.mu.Lock()
+	p.lastSweep = time.Now().Add(-hostIdleTTL)
+	for _, h := range p.hosts {
+		h.lastUsed = time.Now().Add(-hostIdleTTL)
+	}
+	p.mu.Unlock()
+
+	other, err := p.Acquire(ctx, "https://other.example/")
+	require.NoError(t, err)
+	other()
+	p.mu.Lock()
+	assert.NotContains(t, p.hosts, "idle.example")
+	assert.Contains(t, p.hosts, "busy.example", "A host in use should not be evicted")
+	p.mu.Unlock()
+
+	// The busy host still enforces its limit
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = p.Acquire(waitCtx, "https://busy.example/")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	busy()
+}
+
+// TestPoliteness_OEmbed tests that oEmbed requests count against the limits
+// of the endpoint's host but ignore its robots.txt.
+func TestPoliteness_OEmbed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			io.WriteString(w, "User-agent: *\nDisallow: /oembed\n")
+			return
+		}
+		http.ServeFile(w, r, "testdata/youtube_oembed.json")
+	}))
+	defer srv.Close()
+	p := NewPoliteness(PolitenessOptions{HostConcurrency: 1, RespectRobots: true, Client: srv.Client()}, newTestLogger())
+	ctx := context.Background()
+
+	release, err := p.Acquire(ctx, srv.URL+"/page")
+	require.NoError(t, err)
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = fetchOEmbed(waitCtx, srv.Client(), p, srv.URL+"/oembed", "https://www.youtube.com/watch?v=x")
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "oEmbed requests should wait for the host")
+	release()
+
+	oe, err := fetchOEmbed(ctx, srv.Client(), p, srv.URL+"/oembed", "https://www.youtube.com/watch?v=x")
+	require.NoError(t, err)
+	assert.NotEmpty(t, oe.Title)
+}
+
+// TestTokenBucket tests that the shared bucket allows a burst and then the configured rate.
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(50, 2)
+	ctx := context.Background()
+	start := time.Now()
+	for range 4 {
+		require.NoError(t, b.wait(ctx))
+	}
+	// Two tokens come from the burst, two more take 20ms each
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, b.wait(cancelled), context.Canceled)
+}
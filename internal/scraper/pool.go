@@ -0,0 +1,304 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// releaseTimeout bounds how long resetting a page before reuse may take.
+	releaseTimeout = 5 * time.Second
+	// healthCheckTimeout bounds how long the browser may take to answer a health check.
+	healthCheckTimeout = 5 * time.Second
+	// closeTimeout bounds each call closing a page or the browser, which may hang with a wedged browser.
+	closeTimeout = 5 * time.Second
+)
+
+// errPoolClosed is returned when a page is requested after the pool was closed.
+var errPoolClosed = errors.New("browser pool is closed")
+
+// browserPool manages one long-lived browser and a bounded set of reusable pages.
+// Each page lives in its own incognito context so scrapes do not share cookies
+// with pages used concurrently. The browser is launched lazily on first use and
+// relaunched when a health check finds that it has crashed.
+type browserPool struct {
+	log logrus.FieldLogger
+
+	// sem bounds the number of pages in use at the same time.
+	sem chan struct{}
+
+	// mu guards the fields below. No call to the browser is made while it is
+	// held, so a hung browser cannot block the pool.
+	mu       sync.Mutex
+	launcher *launcher.Launcher
+	browser  *rod.Browser
+	// generation increases whenever the browser is dropped, so pages of a dead browser are discarded.
+	generation int
+	idle       []*pooledPage
+	closed     bool
+	// launching is closed once the running launch finished; it is nil while none runs.
+	launching chan struct{}
+}
+
+// browserProcess is a browser detached from the pool, with its idle pages, for shutting down.
+type browserProcess struct {
+	launcher *launcher.Launcher
+	browser  *rod.Browser
+	idle     []*pooledPage
+}
+
+// pooledPage is a page together with its incognito context and browser generation.
+type pooledPage struct {
+	page       *rod.Page
+	incognito  *rod.Browser
+	generation int
+}
+
+// newBrowserPool creates a pool that allows at most maxPages concurrent pages.
+func newBrowserPool(maxPages int, logger logrus.FieldLogger) *browserPool {
+	if maxPages < 1 {
+		maxPages = 1
+	}
+	return &browserPool{
+		log: logger.WithField("component", "browser_pool"),
+		sem: make(chan struct{}, maxPages),
+	}
+}
+
+// acquire returns a ready-to-use page, waiting for a free slot if the pool is at capacity.
+// Every successful acquire must be paired with a release.
+func (p *browserPool) acquire(ctx context.Context) (*pooledPage, error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	pp, err := p.takePage(ctx)
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	return pp, nil
+}
+
+// takePage pops an idle page of the current browser or opens a new one.
+func (p *browserPool) takePage(ctx context.Context) (*pooledPage, error) {
+	browser, generation, err := p.healthyBrowser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []*pooledPage
+	defer func() {
+		for _, pp := range stale {
+			pp.close()
+		}
+	}()
+	p.mu.Lock()
+	for len(p.idle) > 0 {
+		pp := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if pp.generation == generation {
+			p.mu.Unlock()
+			return pp, nil
+		}
+		stale = append(stale, pp)
+	}
+	p.mu.Unlock()
+
+	incognito, err := browser.Incognito()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create incognito context: %w", err)
+	}
+	page, err := incognito.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		_ = incognito.Timeout(closeTimeout).Close()
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+	p.log.WithField("generation", generation).Debug("Opened new pooled page")
+	return &pooledPage{page: page, incognito: incognito, generation: generation}, nil
+}
+
+// release returns a page to the pool. Pages that saw an error, or that belong
+// to a browser that has since been relaunched, are closed instead of reused.
+func (p *browserPool) release(pp *pooledPage, healthy bool) {
+	defer func() { <-p.sem }()
+
+	if healthy {
+		// Leave the previous site so it stops running scripts while idle
+		if err := pp.page.Timeout(releaseTimeout).Navigate("about:blank"); err != nil {
+			healthy = false
+		}
+	}
+
+	p.mu.Lock()
+	reuse := healthy && !p.closed && pp.generation == p.generation
+	if reuse {
+		p.idle = append(p.idle, pp)
+	}
+	p.mu.Unlock()
+	if !reuse {
+		pp.close()
+	}
+}
+
+// healthyBrowser returns the browser and its generation after checking that
+// it still responds. The check runs without holding p.mu and is bounded by
+// healthCheckTimeout, so a hung browser does not block other callers. A
+// browser that fails the check is shut down and replaced; callers arriving
+// meanwhile wait for the new one.
+func (p *browserPool) healthyBrowser(ctx context.Context) (*rod.Browser, int, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, 0, errPoolClosed
+		}
+		if launching := p.launching; launching != nil {
+			p.mu.Unlock()
+			select {
+			case <-launching:
+				continue
+			case <-ctx.Done():
+				return nil, 0, ctx.Err()
+			}
+		}
+		browser, generation := p.browser, p.generation
+		p.mu.Unlock()
+
+		if browser != nil {
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			_, err := (proto.BrowserGetVersion{}).Call(browser.Context(checkCtx))
+			cancel()
+			if err == nil {
+				return browser, generation, nil
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				// The caller gave up; that says nothing about the browser
+				return nil, 0, ctxErr
+			}
+		}
+
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, 0, errPoolClosed
+		}
+		if p.generation != generation || p.launching != nil {
+			// Another caller replaced the browser meanwhile
+			p.mu.Unlock()
+			continue
+		}
+		if browser != nil {
+			p.log.Warn("Browser health check failed, relaunching")
+		}
+		old := p.detachLocked()
+		launching := make(chan struct{})
+		p.launching = launching
+		p.mu.Unlock()
+
+		old.shutdown(p.log)
+		l, browser, err := p.launch()
+
+		p.mu.Lock()
+		p.launching = nil
+		close(launching)
+		if err != nil {
+			p.mu.Unlock()
+			return nil, 0, err
+		}
+		if p.closed {
+			p.mu.Unlock()
+			(&browserProcess{launcher: l, browser: browser}).shutdown(p.log)
+			return nil, 0, errPoolClosed
+		}
+		p.launcher, p.browser = l, browser
+		generation = p.generation
+		p.mu.Unlock()
+
+		p.log.WithField("generation", generation).Info("Persistent rod browser instance launched")
+		return browser, generation, nil
+	}
+}
+
+// launch starts a new browser process and connects to it.
+func (p *browserPool) launch() (*launcher.Launcher, *rod.Browser, error) {
+	path, exists := launcher.LookPath()
+	if !exists {
+		p.log.Error("Cannot find browser executable for rod")
+		return nil, nil, errors.New("rod browser dependency not found")
+	}
+	l := launcher.New().Bin(path)
+	u, err := l.Launch()
+	if err != nil {
+		p.log.WithError(err).Error("Failed to launch rod browser")
+		return nil, nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+	browser := rod.New().ControlURL(u)
+	if err := browser.Connect(); err != nil {
+		l.Kill()
+		p.log.WithError(err).Error("Failed to connect to rod browser")
+		return nil, nil, fmt.Errorf("failed to connect to browser: %w", err)
+	}
+	return l, browser, nil
+}
+
+// detachLocked removes the browser and its idle pages from the pool and
+// starts a new generation, so pages still in use are closed when released.
+// The caller shuts the result down after releasing p.mu, which must be held.
+func (p *browserPool) detachLocked() *browserProcess {
+	old := &browserProcess{launcher: p.launcher, browser: p.browser, idle: p.idle}
+	p.launcher, p.browser, p.idle = nil, nil, nil
+	p.generation++
+	return old
+}
+
+// shutdown kills the browser process and then closes its pages and
+// connection. Killing it first makes the closing calls fail fast when the
+// browser hung, and each of them is bounded by closeTimeout.
+func (bp *browserProcess) shutdown(log logrus.FieldLogger) {
+	if bp.launcher != nil {
+		bp.launcher.Kill()
+	}
+	for _, pp := range bp.idle {
+		pp.close()
+	}
+	if bp.browser != nil {
+		if err := bp.browser.Timeout(closeTimeout).Close(); err != nil {
+			log.WithError(err).Debug("Error closing rod browser instance")
+		}
+	}
+	if bp.launcher != nil {
+		bp.launcher.Cleanup()
+	}
+}
+
+// Close shuts down the browser. Pages still in use are closed when released.
+func (p *browserPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	old := p.detachLocked()
+	p.mu.Unlock()
+
+	old.shutdown(p.log)
+	p.log.Info("Browser pool closed")
+	return nil
+}
+
+// close disposes the page's incognito context, which also closes the page.
+func (pp *pooledPage) close() {
+	_ = pp.incognito.Timeout(closeTimeout).Close()
+}
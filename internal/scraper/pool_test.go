@@ -0,0 +1,22 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBrowserPool_Close tests that a closed pool refuses pages without
+// launching a browser.
+func TestBrowserPool_Close(t *testing.T) {
+	p := newBrowserPool(2, newTestLogger())
+	require.NoError(t, p.Close())
+	require.NoError(t, p.Close(), "Closing twice should be harmless")
+
+	_, err := p.acquire(context.Background())
+	assert.ErrorIs(t, err, errPoolClosed)
+	assert.Empty(t, p.sem, "A failed acquire should free its slot")
+	assert.Equal(t, 1, p.generation, "Closing should drop the browser generation")
+}
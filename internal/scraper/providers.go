@@ -0,0 +1,264 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// oEmbed endpoints of the built-in extractors.
+const (
+	youTubeOEmbedEndpoint = "https://www.youtube.com/oembed"
+	twitterOEmbedEndpoint = "https://publish.twitter.com/oembed"
+	redditOEmbedEndpoint  = "https://www.reddit.com/oembed"
+)
+
+// --- YouTube ---
+
+// youTubeExtractor uses the oEmbed endpoint for the clean title and channel
+// name, and the microdata of the watch page for duration, views and upload date.
+type youTubeExtractor struct {
+	client     *http.Client
+	politeness *Politeness
+	endpoint   string
+}
+
+func (e *youTubeExtractor) Name() string { return "youtube" }
+
+func (e *youTubeExtractor) Extract(ctx context.Context, doc *html.Node, meta *Metadata) error {
+	// oe stays empty when the request fails; the page itself is used instead
+	oe, err := fetchOEmbed(ctx, e.client, e.politeness, e.endpoint, meta.URL)
+	// The <title> carries a " - YouTube" suffix; the oEmbed and og:title do not
+	meta.Title = firstNonEmpty(oe.Title, meta.OpenGraph["title"], meta.Title)
+	meta.Author = firstNonEmpty(oe.AuthorName, meta.Author)
+	meta.SiteName = firstNonEmpty(oe.ProviderName, meta.SiteName)
+	meta.ImageURL = firstNonEmpty(meta.ImageURL, oe.ThumbnailURL)
+	if doc == nil {
+		return err
+	}
+
+	if d, ok := parseISODuration(itemprop(doc, "duration")); ok {
+		setExtra(meta, ExtraDuration, d.String())
+	}
+	setExtra(meta, ExtraViews, digits(firstNonEmpty(itemprop(doc, "interactionCount"), itemprop(doc, "userInteractionCount"))))
+	if meta.PublishedAt.IsZero() {
+		meta.PublishedAt = parseDate(firstNonEmpty(itemprop(doc, "datePublished"), itemprop(doc, "uploadDate")))
+	}
+	if meta.Author == "" {
+		if author := findElement(doc, func(n *html.Node) bool { return attr(n, "itemprop") == "author" }); author != nil {
+			meta.Author = itemprop(author, "name")
+		}
+	}
+	return nil
+}
+
+// isoDurationRE matches ISO 8601 durations as used by schema.org, e.g. "PT1H4M13S".
+var isoDurationRE = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseISODuration parses an ISO 8601 duration with day, hour, minute and second parts.
+func parseISODuration(s string) (time.Duration, bool) {
+	m := isoDurationRE.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(s)))
+	if m == nil {
+		return 0, false
+	}
+	var d time.Duration
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	for i, part := range m[1:] {
+		if part == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, false
+		}
+		d += time.Duration(v * float64(units[i]))
+	}
+	return d, d > 0
+}
+
+// --- GitHub ---
+
+// githubExtractor reads repository details from the structure of a GitHub repository page.
+type githubExtractor struct{}
+
+func (githubExtractor) Name() string { return "github" }
+
+func (githubExtractor) Extract(ctx context.Context, doc *html.Node, meta *Metadata) error {
+	if doc == nil {
+		return errNoDocument
+	}
+	u, err := url.Parse(meta.URL)
+	if err != nil {
+		return err
+	}
+	segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+	if len(segments) < 2 {
+		// Profiles, the home page, etc. are covered by the generic metadata
+		return nil
+	}
+	owner, repo := segments[0], segments[1]
+
+	meta.SiteName = "GitHub"
+	meta.Author = owner
+	if len(segments) == 2 {
+		// The <title> is "GitHub - owner/repo: description"
+		meta.Title = owner + "/" + repo
+	}
+
+	setExtra(meta, ExtraStars, digits(counter(doc, "repo-stars-counter-star")))
+	setExtra(meta, ExtraForks, digits(counter(doc, "repo-network-counter")))
+
+	lang := findElement(doc, func(n *html.Node) bool { return attr(n, "itemprop") == "programmingLanguage" })
+	if lang == nil {
+		// The first entry of the "Languages" sidebar is the main language
+		lang = findElement(doc, func(n *html.Node) bool {
+			return n.Data == "span" && attr(n, "class") == "color-fg-default text-bold mr-1"
+		})
+	}
+	if lang != nil {
+		setExtra(meta, ExtraProgrammingLanguage, strings.TrimSpace(nodeText(lang)))
+	}
+
+	var topics []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" && hasClass(n, "topic-tag") {
+			topics = append(topics, strings.TrimSpace(nodeText(n)))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	meta.Keywords = keywords(strings.Join(meta.Keywords, ","), topics)
+	return nil
+}
+
+// counter returns the exact value of a GitHub counter, which is kept in the
+// title attribute while the text shows a rounded value like "12.3k".
+func counter(doc *html.Node, id string) string {
+	n := findElement(doc, func(n *html.Node) bool { return attr(n, "id") == id })
+	if n == nil {
+		return ""
+	}
+	return firstNonEmpty(attr(n, "title"), strings.TrimSpace(nodeText(n)))
+}
+
+// --- Twitter/X ---
+
+// twitterExtractor uses the oEmbed endpoint, since post pages are rendered by
+// JavaScript and often refuse plain HTTP clients.
+type twitterExtractor struct {
+	client     *http.Client
+	politeness *Politeness
+	endpoint   string
+}
+
+func (e *twitterExtractor) Name() string { return "twitter" }
+
+func (e *twitterExtractor) Extract(ctx context.Context, doc *html.Node, meta *Metadata) error {
+	oe, err := fetchOEmbed(ctx, e.client, e.politeness, e.endpoint, meta.URL)
+	if err != nil {
+		return err
+	}
+
+	meta.SiteName = "X"
+	meta.Author = firstNonEmpty(oe.AuthorName, meta.Author)
+	if oe.AuthorName != "" {
+		meta.Title = oe.AuthorName + " on X"
+	}
+
+	// The embed HTML is a <blockquote> holding the post text in a <p>,
+	// followed by "— Name (@handle) <a>Month D, YYYY</a>"
+	embed, err := html.Parse(strings.NewReader(oe.HTML))
+	if err != nil {
+		return nil
+	}
+	if p := findElement(embed, func(n *html.Node) bool { return n.Data == "p" }); p != nil {
+		meta.Description = firstNonEmpty(strings.TrimSpace(nodeText(p)), meta.Description)
+	}
+	if quote := findElement(embed, func(n *html.Node) bool { return n.Data == "blockquote" }); quote != nil {
+		var date *html.Node
+		for c := quote.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "a" {
+				date = c
+			}
+		}
+		if date != nil {
+			if t, err := time.Parse("January 2, 2006", strings.TrimSpace(nodeText(date))); err == nil {
+				meta.PublishedAt = t
+			}
+		}
+	}
+	return nil
+}
+
+// --- Reddit ---
+
+// redditExtractor reads post details from the <shreddit-post> element of a
+// Reddit post page, and falls back to oEmbed when the page is unavailable.
+type redditExtractor struct {
+	client     *http.Client
+	politeness *Politeness
+	endpoint   string
+}
+
+func (e *redditExtractor) Name() string { return "reddit" }
+
+func (e *redditExtractor) Extract(ctx context.Context, doc *html.Node, meta *Metadata) error {
+	meta.SiteName = "Reddit"
+
+	post := findElement(doc, func(n *html.Node) bool { return n.Data == "shreddit-post" })
+	if post == nil {
+		oe, err := fetchOEmbed(ctx, e.client, e.politeness, e.endpoint, meta.URL)
+		if err != nil {
+			return err
+		}
+		meta.Title = firstNonEmpty(oe.Title, meta.Title)
+		if oe.AuthorName != "" {
+			meta.Author = "u/" + oe.AuthorName
+		}
+		return nil
+	}
+
+	meta.Title = firstNonEmpty(strings.TrimSpace(attr(post, "post-title")), meta.Title)
+	if author := attr(post, "author"); author != "" {
+		meta.Author = "u/" + author
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05.999999-0700", attr(post, "created-timestamp")); err == nil {
+		meta.PublishedAt = t
+	}
+	setExtra(meta, ExtraScore, attr(post, "score"))
+	setExtra(meta, ExtraComments, attr(post, "comment-count"))
+	setExtra(meta, ExtraCommunity, attr(post, "subreddit-prefixed-name"))
+	return nil
+}
+
+// --- Helpers ---
+
+// digits returns only the ASCII digits of s, e.g. "12345" for "12,345".
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// hasClass reports whether n has the given CSS class.
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
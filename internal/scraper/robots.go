@@ -0,0 +1,126 @@
+package scraper
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robotsAgent is the product token matched against User-agent lines in robots.txt.
+const robotsAgent = "jetengine"
+
+// robotsRules are the robots.txt rules that apply to the scraper on one host.
+type robotsRules struct {
+	rules []robotsRule
+	// crawlDelay is the delay between requests the site asks for, or 0.
+	crawlDelay time.Duration
+}
+
+// robotsRule is a single Allow or Disallow line.
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// parseRobots reads a robots.txt file and returns the rules of the group for
+// agent, or of the "*" group if no group names the agent.
+func parseRobots(r io.Reader, agent string) robotsRules {
+	var specific, generic robotsRules
+	var hasSpecific bool
+
+	// A group starts with one or more User-agent lines followed by rules
+	var forSpecific, forGeneric, inAgents bool
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			if !inAgents {
+				forSpecific, forGeneric = false, false
+				inAgents = true
+			}
+			name := strings.ToLower(value)
+			switch {
+			case name == "*":
+				forGeneric = true
+			case name != "" && strings.Contains(agent, name):
+				forSpecific = true
+				hasSpecific = true
+			}
+			continue
+		}
+		inAgents = false
+
+		for _, group := range []struct {
+			applies bool
+			rules   *robotsRules
+		}{{forSpecific, &specific}, {forGeneric, &generic}} {
+			if !group.applies {
+				continue
+			}
+			switch key {
+			case "allow", "disallow":
+				// An empty Disallow allows everything, which is the default anyway
+				if value != "" {
+					group.rules.rules = append(group.rules.rules, robotsRule{pattern: value, allow: key == "allow"})
+				}
+			case "crawl-delay":
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					group.rules.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	if hasSpecific {
+		return specific
+	}
+	return generic
+}
+
+// allowed reports whether path (including any query) may be fetched.
+// The longest matching pattern decides; on a tie Allow wins.
+func (r robotsRules) allowed(path string) bool {
+	allow, longest := true, -1
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > longest || (n == longest && rule.allow) {
+			allow, longest = rule.allow, n
+		}
+	}
+	return allow
+}
+
+// robotsMatch matches a path against a robots.txt pattern, where "*" matches
+// any sequence of characters and a trailing "$" anchors the end of the path.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return !anchored || rest == ""
+}
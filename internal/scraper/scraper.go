@@ -4,10 +4,12 @@ import "context"
 
 // Scraper defines the interface for fetching metadata from a URL.
 type Scraper interface {
-	// ScrapeMetadata fetches the title and description for a given URL.
-	// It returns the title, description, and an error if scraping fails.
-	ScrapeMetadata(ctx context.Context, url string) (title string, description string, err error)
+	// ScrapeMetadata fetches the metadata (title, description, Open Graph,
+	// Twitter Card, JSON-LD, ...) for a given URL.
+	// It returns an error if scraping fails.
+	ScrapeMetadata(ctx context.Context, url string) (Metadata, error)
 
-	// TODO: Consider adding a Close() method if the scraper needs resource cleanup (like a persistent browser instance).
+	// Close releases resources held by the scraper, such as a persistent browser instance.
+	Close() error
 }
 
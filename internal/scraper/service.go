@@ -8,107 +8,162 @@ import (
 	"time"
 
 	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/launcher"
-	"github.com/go-rod/rod/lib/proto"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
 )
 
 // RodScraper implements the Scraper interface using the rod library.
+// It keeps one persistent browser and reuses a bounded set of pages.
 type RodScraper struct {
-	log logrus.FieldLogger
-	// browser *rod.Browser // Optional: Keep a persistent browser instance
+	log             logrus.FieldLogger
+	pool            *browserPool
+	pageTimeout     time.Duration
+	extractors      *ExtractorRegistry
+	politeness      *Politeness
+	extractArticles bool
+	allowPrivate    bool
 }
 
-// NewRodScraper creates a new scraper service instance.
-func NewRodScraper(logger logrus.FieldLogger) *RodScraper {
-	// Optional: Initialize a persistent browser here if desired
-	// path, _ := launcher.LookPath()
-	// u := launcher.New().Bin(path).MustLaunch()
-	// browser := rod.New().ControlURL(u).MustConnect()
-	// logger.Info("Persistent rod browser instance created")
+// RodOptions configures a RodScraper.
+type RodOptions struct {
+	// MaxPages limits how many pages may be scraped concurrently.
+	MaxPages int
+	// PageTimeout bounds loading and scraping a single page.
+	PageTimeout time.Duration
+	// Extractors refine metadata for known sites. It may be nil.
+	Extractors *ExtractorRegistry
+	// ExtractArticles enables extracting the readable article body into Metadata.Article.
+	ExtractArticles bool
+	// Politeness limits the page loads per host. It may be nil.
+	Politeness *Politeness
+	// AllowPrivateNetworks lets the browser load pages on loopback, private
+	// and link-local addresses.
+	AllowPrivateNetworks bool
+}
 
+// NewRodScraper creates a new scraper service instance.
+// The browser is launched lazily on the first scrape.
+func NewRodScraper(opts RodOptions, logger logrus.FieldLogger) *RodScraper {
+	if opts.PageTimeout <= 0 {
+		opts.PageTimeout = 30 * time.Second
+	}
+	log := logger.WithField("component", "scraper")
 	return &RodScraper{
-		log: logger.WithField("component", "scraper"),
-		// browser: browser, // Assign if using persistent browser
+		log:             log,
+		pool:            newBrowserPool(opts.MaxPages, log),
+		pageTimeout:     opts.PageTimeout,
+		extractors:      opts.Extractors,
+		politeness:      opts.Politeness,
+		extractArticles: opts.ExtractArticles,
+		allowPrivate:    opts.AllowPrivateNetworks,
 	}
 }
 
-// Optional: Add a Close method if using a persistent browser
-// func (s *RodScraper) Close() error {
-// 	if s.browser != nil {
-// 		s.log.Info("Closing persistent rod browser instance")
-// 		return s.browser.Close()
-// 	}
-// 	return nil
-// }
-
-// ScrapeMetadata fetches the title and description using rod.
-func (s *RodScraper) ScrapeMetadata(ctx context.Context, url string) (title string, description string, err error) {
+// Close shuts down the persistent browser instance.
+func (s *RodScraper) Close() error {
+	s.log.Info("Closing persistent rod browser instance")
+	return s.pool.Close()
+}
+
+// ScrapeMetadata renders a page with rod and extracts metadata from the resulting DOM,
+// so content inserted by JavaScript is included.
+func (s *RodScraper) ScrapeMetadata(ctx context.Context, url string) (Metadata, error) {
 	log := s.log.WithField("url", url)
 	log.Info("Attempting to scrape metadata")
 
-	// --- Browser Setup ---
-	// Option 1: Launch a new browser for each scrape (simpler, more resource-intensive)
-	path, exists := launcher.LookPath()
-	if !exists {
-		log.Error("Cannot find browser executable for rod")
-		return "", "", errors.New("rod browser dependency not found")
-	}
-	// Use launcher.New().Headless(false).MustLaunch() to see the browser window for debugging
-	u := launcher.New().Bin(path).MustLaunch()
-	browser := rod.New().ControlURL(u)
-	err = browser.Connect()
+	var meta Metadata
+	var doc *html.Node
+	err := s.withPage(ctx, url, log, func(page *rod.Page) error {
+		// The final URL differs from the requested one after redirects
+		finalURL := url
+		if info, err := page.Info(); err == nil && info.URL != "" {
+			finalURL = info.URL
+		}
+
+		content, err := page.HTML()
+		if err != nil {
+			log.WithError(err).Error("Failed to get rendered HTML")
+			return fmt.Errorf("failed to get page HTML: %w", err)
+		}
+		doc, err = html.Parse(strings.NewReader(content))
+		if err != nil {
+			log.WithError(err).Error("Failed to parse rendered HTML")
+			return fmt.Errorf("failed to parse page HTML: %w", err)
+		}
+		meta = extractMetadata(doc, finalURL)
+
+		if contentType, err := page.Eval(`() => document.contentType`); err == nil {
+			meta.ContentType = contentType.Value.Str()
+		}
+		// Browsers render error pages like any other, so ask for the status
+		if status, err := page.Eval(`() => performance.getEntriesByType("navigation")[0]?.responseStatus ?? 0`); err == nil {
+			meta.StatusCode = status.Value.Int()
+		}
+		return nil
+	})
 	if err != nil {
-		log.WithError(err).Error("Failed to connect to rod browser")
-		return "", "", fmt.Errorf("failed to connect to browser: %w", err)
+		return Metadata{}, err
 	}
-	// Ensure the browser is closed when the function exits
-	defer func() {
-		closeErr := browser.Close()
-		if closeErr != nil {
-			log.WithError(closeErr).Error("Error closing rod browser instance")
-			// Decide if this error should overwrite the primary return error
-			if err == nil {
-				err = fmt.Errorf("error closing browser: %w", closeErr)
-			}
-		} else {
-			log.Debug("Rod browser instance closed")
+	if meta.StatusCode >= 400 {
+		log.WithField("status", meta.StatusCode).Warn("Page returned an error status")
+		return Metadata{}, fmt.Errorf("failed to fetch %s: %w", url, &StatusError{StatusCode: meta.StatusCode})
+	}
+
+	// Extractors run once the page and host are released, since their
+	// oEmbed requests may go to the same host
+	s.extractors.apply(ctx, doc, &meta, log)
+	if s.extractArticles {
+		meta.Article = extractArticle(doc, meta.URL)
+	}
+
+	if meta.Description == "" {
+		log.Warn("Could not find description meta tag")
+	}
+	log.WithField("title", meta.Title).Info("Metadata scraping completed successfully")
+	return meta, nil
+}
+
+// withPage loads url in a pooled page and calls fn with it once loading completed.
+// The timeout covers waiting for a free page, loading and fn itself, but not
+// waiting for the per-host limits.
+func (s *RodScraper) withPage(ctx context.Context, url string, log logrus.FieldLogger, fn func(page *rod.Page) error) (err error) {
+	if !s.allowPrivate {
+		if err := checkPublicURL(ctx, url); err != nil {
+			log.WithError(err).Warn("Refusing to load page")
+			return err
 		}
-	}()
+	}
+	release, err := s.politeness.Acquire(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	pageCtx, cancel := context.WithTimeout(ctx, s.pageTimeout)
+	defer cancel()
 
-	// Option 2: Use a persistent browser instance (more complex setup/cleanup)
-	// if s.browser == nil {
-	// 	log.Error("Persistent browser instance is not initialized")
-	// 	return "", "", errors.New("scraper not initialized correctly")
-	// }
-	// browser := s.browser
-	// --- End Browser Setup ---
-
-	// --- Page Navigation and Scraping ---
-	var page *rod.Page
-	page, err = browser.Page(proto.TargetCreateTarget{URL: url}) // Use Page for simpler navigation
+	// --- Page Setup ---
+	pp, err := s.pool.acquire(pageCtx)
 	if err != nil {
-		log.WithError(err).Error("Failed to create rod page")
-		return "", "", fmt.Errorf("failed to create page: %w", err)
+		log.WithError(err).Error("Failed to get a page from the browser pool")
+		return fmt.Errorf("failed to get page: %w", err)
 	}
-	// Ensure page is closed
+	// Return the page to the pool; pages that failed are discarded
 	defer func() {
-		closeErr := page.Close()
-		if closeErr != nil {
-			log.WithError(closeErr).Error("Error closing rod page")
-			if err == nil {
-				err = fmt.Errorf("error closing page: %w", closeErr)
-			}
-		} else {
-			log.Debug("Rod page closed")
-		}
+		s.pool.release(pp, err == nil)
 	}()
+	page := pp.page.Context(pageCtx)
 
-	// Set a timeout for the entire scraping operation for this page
-	// Use a context that respects the overall request context if available
-	pageCtx, cancel := context.WithTimeout(ctx, 30*time.Second) // 30-second timeout
-	defer cancel()
-	page = page.Context(pageCtx)
+	// --- Page Navigation ---
+	err = page.Navigate(url)
+	if err != nil {
+		if errors.Is(pageCtx.Err(), context.DeadlineExceeded) {
+			log.WithError(pageCtx.Err()).Warn("Scraping timed out")
+			return fmt.Errorf("scraping timed out for %s: %w", url, pageCtx.Err())
+		}
+		log.WithError(err).Error("Failed to navigate to page")
+		return fmt.Errorf("failed to navigate to %s: %w", url, err)
+	}
 
 	// Wait for the page to load completely (adjust wait condition if needed)
 	err = page.WaitLoad()
@@ -116,59 +171,16 @@ func (s *RodScraper) ScrapeMetadata(ctx context.Context, url string) (title stri
 		// Handle context deadline exceeded specifically
 		if errors.Is(pageCtx.Err(), context.DeadlineExceeded) {
 			log.WithError(pageCtx.Err()).Warn("Scraping timed out")
-			return "", "", fmt.Errorf("scraping timed out for %s: %w", url, pageCtx.Err())
+			return fmt.Errorf("scraping timed out for %s: %w", url, pageCtx.Err())
 		}
 		log.WithError(err).Error("Failed to wait for page load")
-		return "", "", fmt.Errorf("failed waiting for page load: %w", err)
-	}
-
-	// --- Extract Title ---
-	titleElement, err := page.Element("title")
-	if err != nil {
-		// It's possible a page might not have a title, treat as warning?
-		log.WithError(err).Warn("Could not find title element")
-		title = "" // Default to empty title
-	} else {
-		title, err = titleElement.Text()
-		if err != nil {
-			log.WithError(err).Error("Failed to get text from title element")
-			// Don't return error, just use empty title maybe?
-			title = ""
-		}
-		title = strings.TrimSpace(title)
-		log.WithField("title", title).Debug("Extracted title")
+		return fmt.Errorf("failed waiting for page load: %w", err)
 	}
 
-	// --- Extract Description ---
-	// Try common meta description tags
-	descSelectors := []string{
-		`meta[name="description"]`,
-		`meta[property="og:description"]`,
-		// Add more selectors if needed
-	}
-	description = "" // Default
-	for _, selector := range descSelectors {
-		descElement, err := page.Element(selector)
-		if err == nil { // Found an element
-			descContent, err := descElement.Attribute("content")
-			if err == nil && descContent != nil {
-				description = strings.TrimSpace(*descContent)
-				if description != "" {
-					log.WithField("description", description).Debug("Extracted description")
-					break // Stop searching once a non-empty description is found
-				}
-			} else if err != nil {
-				log.WithError(err).WithField("selector", selector).Warn("Failed to get content attribute from meta tag")
-			}
-		} else if !strings.Contains(err.Error(), "element not found") { // err is guaranteed non-nil here
-			log.WithError(err).WithField("selector", selector).Warn("Error searching for meta description tag")
-		}
+	err = fn(page)
+	if err != nil && errors.Is(pageCtx.Err(), context.DeadlineExceeded) {
+		log.WithError(pageCtx.Err()).Warn("Scraping timed out")
+		return fmt.Errorf("scraping timed out for %s: %w", url, pageCtx.Err())
 	}
-	if description == "" {
-		log.Warn("Could not find description meta tag")
-	}
-
-	log.Info("Metadata scraping completed successfully")
-	// Return the extracted title and description, err should be nil here if successful
-	return title, description, nil
+	return err
 }
@@ -0,0 +1,79 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+)
+
+// Capture is the content of one page snapshot.
+type Capture struct {
+	Kind      domain.SnapshotKind
+	MediaType string
+	Data      []byte
+}
+
+// Snapshotter captures archival snapshots of pages.
+type Snapshotter interface {
+	// Capture loads url once and captures a snapshot of each requested kind.
+	Capture(ctx context.Context, url string, kinds ...domain.SnapshotKind) ([]Capture, error)
+}
+
+// Capture renders a page in the browser and captures the requested snapshots:
+// a full-page PNG screenshot, a PDF and/or a single-file MHTML archive.
+func (s *RodScraper) Capture(ctx context.Context, url string, kinds ...domain.SnapshotKind) ([]Capture, error) {
+	log := s.log.WithFields(logrus.Fields{
+		"url":   url,
+		"kinds": kinds,
+	})
+	log.Info("Attempting to capture page snapshots")
+
+	var captures []Capture
+	err := s.withPage(ctx, url, log, func(page *rod.Page) error {
+		for _, kind := range kinds {
+			c, err := capture(page, kind)
+			if err != nil {
+				log.WithError(err).WithField("kind", kind).Error("Failed to capture snapshot")
+				return fmt.Errorf("failed to capture %s of %s: %w", kind, url, err)
+			}
+			captures = append(captures, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Page snapshots captured successfully")
+	return captures, nil
+}
+
+// capture takes one snapshot of a loaded page.
+func capture(page *rod.Page, kind domain.SnapshotKind) (Capture, error) {
+	switch kind {
+	case domain.SnapshotScreenshot:
+		data, err := page.Screenshot(true, &proto.PageCaptureScreenshot{Format: proto.PageCaptureScreenshotFormatPng})
+		return Capture{Kind: kind, MediaType: "image/png", Data: data}, err
+	case domain.SnapshotPDF:
+		stream, err := page.PDF(&proto.PagePrintToPDF{PrintBackground: true})
+		if err != nil {
+			return Capture{}, err
+		}
+		defer stream.Close()
+		data, err := io.ReadAll(stream)
+		return Capture{Kind: kind, MediaType: "application/pdf", Data: data}, err
+	case domain.SnapshotMHTML:
+		res, err := proto.PageCaptureSnapshot{Format: proto.PageCaptureSnapshotFormatMhtml}.Call(page)
+		if err != nil {
+			return Capture{}, err
+		}
+		return Capture{Kind: kind, MediaType: "multipart/related", Data: []byte(res.Data)}, nil
+	}
+	return Capture{}, fmt.Errorf("unknown snapshot kind %q", kind)
+}
@@ -0,0 +1,139 @@
+// Package search turns saved links into weighted index terms and parses the
+// queries users type into /search. It only deals with text; storing and
+// looking up the inverted index is up to the repository.
+package search
+
+import (
+	"math"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+const (
+	// minTermLength drops single letters, which match almost everything.
+	minTermLength = 2
+	// maxTermLength drops long tokens such as hashes and base64 blobs.
+	maxTermLength = 40
+)
+
+// Field weights: a match in the title or tags says more about a page than
+// one somewhere in its article text.
+const (
+	WeightTitle       = 3.0
+	WeightTags        = 3.0
+	WeightSiteName    = 2.0
+	WeightURL         = 1.5
+	WeightDescription = 1.5
+	WeightArticle     = 1.0
+)
+
+// Field is a piece of text of a document that matches with the given weight.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Tokenize splits text into lowercase terms made of letters and digits.
+// Terms that are too short or too long to be useful are dropped.
+func Tokenize(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(text, isSeparator) {
+		n := len([]rune(word))
+		if n < minTermLength || n > maxTermLength {
+			continue
+		}
+		terms = append(terms, strings.ToLower(word))
+	}
+	return terms
+}
+
+// isSeparator reports whether r separates terms.
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// URLText returns the searchable words of a URL: its host name without a
+// leading "www." and its path, leaving out the scheme and query.
+func URLText(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.") + " " + u.Path
+}
+
+// Weights computes the weight of every term in a document. Repeated terms
+// count logarithmically, so long texts don't drown out short fields.
+func Weights(fields ...Field) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, f := range fields {
+		counts := make(map[string]int)
+		for _, term := range Tokenize(f.Text) {
+			counts[term]++
+		}
+		for term, n := range counts {
+			weights[term] += f.Weight * (1 + math.Log(float64(n)))
+		}
+	}
+	return weights
+}
+
+// IDF returns the inverse document frequency of a term found in docFreq of
+// docs documents: rare terms score higher than common ones.
+func IDF(docs, docFreq int) float64 {
+	if docFreq <= 0 {
+		return 0
+	}
+	return math.Log(1 + float64(docs)/float64(docFreq))
+}
+
+// Query is a parsed search query.
+type Query struct {
+	// Terms must all occur in a matching link.
+	Terms []string
+	// Tags must all be set on a matching link (tag:go or #go).
+	Tags []string
+	// Sites limit results to links on any of these hosts (site:example.com).
+	Sites []string
+}
+
+// ParseQuery parses free text with tag: and site: operators.
+func ParseQuery(s string) Query {
+	var q Query
+	for _, word := range strings.Fields(s) {
+		lower := strings.ToLower(word)
+		switch {
+		case strings.HasPrefix(lower, "tag:"):
+			if tag := strings.TrimPrefix(strings.TrimPrefix(lower, "tag:"), "#"); tag != "" {
+				q.Tags = append(q.Tags, tag)
+			}
+		case strings.HasPrefix(lower, "#") && len(lower) > 1:
+			q.Tags = append(q.Tags, lower[1:])
+		case strings.HasPrefix(lower, "site:"):
+			if site := strings.TrimPrefix(lower, "site:"); site != "" {
+				q.Sites = append(q.Sites, site)
+			}
+		default:
+			q.Terms = append(q.Terms, Tokenize(word)...)
+		}
+	}
+	return q
+}
+
+// IsEmpty reports whether the query has neither terms nor operators.
+func (q Query) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Tags) == 0 && len(q.Sites) == 0
+}
+
+// String formats the query so that ParseQuery returns it unchanged.
+func (q Query) String() string {
+	parts := append([]string{}, q.Terms...)
+	for _, tag := range q.Tags {
+		parts = append(parts, "tag:"+tag)
+	}
+	for _, site := range q.Sites {
+		parts = append(parts, "site:"+site)
+	}
+	return strings.Join(parts, " ")
+}
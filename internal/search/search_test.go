@@ -0,0 +1,43 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokenize tests splitting text and URLs into index terms.
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"go", "generics", "in", "18", "straße"}, Tokenize("Go-Generics in 1.18: a Straße!"))
+	assert.Empty(t, Tokenize("a b c"))
+	assert.Empty(t, Tokenize("0123456789abcdef0123456789abcdef0123456789"), "Hashes should not be indexed")
+	assert.Equal(t, []string{"example", "com", "posts", "go"}, Tokenize(URLText("https://www.example.com/posts/go?x=1")))
+}
+
+// TestWeights tests that field weights add up and repeated terms are damped.
+func TestWeights(t *testing.T) {
+	w := Weights(
+		Field{Text: "Go tips", Weight: WeightTitle},
+		Field{Text: "go go go go", Weight: WeightArticle},
+	)
+	require.Contains(t, w, "go")
+	assert.Equal(t, WeightTitle, w["tips"])
+	assert.Greater(t, w["go"], WeightTitle+WeightArticle)
+	assert.Less(t, w["go"], WeightTitle+4*WeightArticle)
+
+	assert.Greater(t, IDF(100, 1), IDF(100, 50), "Rare terms should count more")
+	assert.Zero(t, IDF(100, 0))
+}
+
+// TestParseQuery tests free text with tag: and site: operators.
+func TestParseQuery(t *testing.T) {
+	q := ParseQuery("Rust async  tag:Perf #web site:Example.com")
+	assert.Equal(t, []string{"rust", "async"}, q.Terms)
+	assert.Equal(t, []string{"perf", "web"}, q.Tags)
+	assert.Equal(t, []string{"example.com"}, q.Sites)
+	assert.Equal(t, "rust async tag:perf tag:web site:example.com", q.String())
+	assert.Equal(t, q, ParseQuery(q.String()))
+
+	assert.True(t, ParseQuery("  tag: a ").IsEmpty())
+}
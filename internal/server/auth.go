@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+)
+
+// ErrUnauthenticated is returned by an Authenticator when a request carries
+// no valid credentials.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// ErrForbidden is returned by an Authenticator when valid credentials may
+// not be used for a request, such as a session request without its CSRF token.
+var ErrForbidden = errors.New("forbidden")
+
+// allScopes are the scopes of credentials that are not limited, such as
+// static API keys.
+var allScopes = []domain.TokenScope{domain.ScopeRead, domain.ScopeWrite}
+
+// Principal is the user a request is made on behalf of, with what the
+// request's credentials allow.
+type Principal struct {
+	// UserID is the Telegram User ID of the user.
+	UserID int64
+	// Name is the display name of the user, if known.
+	Name string
+	// Scopes are the permissions granted by the credentials.
+	Scopes []domain.TokenScope
+	// CSRFToken is the token that changes must carry when the credentials
+	// are a browser session; it is empty otherwise.
+	CSRFToken string
+}
+
+// Can reports whether the principal was granted scope.
+func (p Principal) Can(scope domain.TokenScope) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Authenticator resolves the Telegram user a request is made on behalf of.
+type Authenticator interface {
+	// Authenticate returns the principal of the request's credentials.
+	// It returns ErrUnauthenticated if there are none or they are invalid.
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// Authenticators tries each authenticator in turn and accepts the first
+// principal found.
+type Authenticators []Authenticator
+
+// Authenticate implements Authenticator.
+func (a Authenticators) Authenticate(r *http.Request) (Principal, error) {
+	for _, auth := range a {
+		p, err := auth.Authenticate(r)
+		if !errors.Is(err, ErrUnauthenticated) {
+			return p, err
+		}
+	}
+	return Principal{}, ErrUnauthenticated
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, or "".
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// StaticKeys authenticates bearer tokens against a fixed list of API keys,
+// each belonging to one user and granting all scopes. It is meant for
+// operators running their own instance.
+type StaticKeys struct {
+	// keys maps the SHA-256 of each key to its user, so lookups do not
+	// depend on the key's bytes.
+	keys map[[sha256.Size]byte]int64
+}
+
+// ParseStaticKeys parses a comma-separated list of "{userID}:{key}" pairs.
+// An empty spec yields an authenticator that accepts nothing.
+func ParseStaticKeys(spec string) (*StaticKeys, error) {
+	s := &StaticKeys{keys: make(map[[sha256.Size]byte]int64)}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		user, key, ok := strings.Cut(pair, ":")
+		userID, err := strconv.ParseInt(user, 10, 64)
+		if !ok || err != nil || key == "" {
+			return nil, fmt.Errorf("invalid API key %q, want {userID}:{key}", user+":…")
+		}
+		s.keys[sha256.Sum256([]byte(key))] = userID
+	}
+	return s, nil
+}
+
+// Authenticate implements Authenticator.
+func (s *StaticKeys) Authenticate(r *http.Request) (Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+	sum := sha256.Sum256([]byte(token))
+	for hash, userID := range s.keys {
+		if subtle.ConstantTimeCompare(hash[:], sum[:]) == 1 {
+			return Principal{UserID: userID, Scopes: allScopes}, nil
+		}
+	}
+	return Principal{}, ErrUnauthenticated
+}
+
+// TokenAuth authenticates bearer tokens against the personal API tokens
+// users mint with the bot's /token command.
+type TokenAuth struct {
+	store storage.TokenStore
+}
+
+// NewTokenAuth creates an authenticator for the tokens in store.
+func NewTokenAuth(store storage.TokenStore) *TokenAuth {
+	return &TokenAuth{store: store}
+}
+
+// Authenticate implements Authenticator.
+func (a *TokenAuth) Authenticate(r *http.Request) (Principal, error) {
+	secret := bearerToken(r)
+	if secret == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+	token, err := a.store.AuthenticateToken(r.Context(), secret)
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return Principal{}, ErrUnauthenticated
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{UserID: token.UserID, Scopes: token.Scopes}, nil
+}
+
+// principalKey is the context key of the authenticated principal.
+type principalKey struct{}
+
+// withPrincipal returns a copy of ctx carrying the authenticated principal.
+func withPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// principalFrom returns the authenticated principal of a request.
+func principalFrom(r *http.Request) Principal {
+	p, _ := r.Context().Value(principalKey{}).(Principal)
+	return p
+}
+
+// userFrom returns the authenticated user ID of a request.
+// Only handlers behind requireUser may call it.
+func userFrom(r *http.Request) int64 {
+	return principalFrom(r).UserID
+}
+
+// requireUser rejects requests without valid credentials and passes the
+// authenticated principal on to next.
+func (h *Handler) requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := h.auth.Authenticate(r)
+		if errors.Is(err, ErrUnauthenticated) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="jetengine"`)
+			h.writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		if errors.Is(err, ErrForbidden) {
+			h.writeError(w, http.StatusForbidden, "invalid CSRF token or origin")
+			return
+		}
+		if err != nil {
+			h.log.WithError(err).Error("Failed to authenticate request")
+			h.writeError(w, http.StatusInternalServerError, "authentication failed")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
+	})
+}
+
+// requireScope rejects requests whose credentials lack scope.
+// Only handlers behind requireUser may use it.
+func (h *Handler) requireScope(scope domain.TokenScope, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !principalFrom(r).Can(scope) {
+			h.writeError(w, http.StatusForbidden, fmt.Sprintf("token lacks the %s scope", scope))
+			return
+		}
+		next(w, r)
+	})
+}
@@ -1 +1,448 @@
 package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/scraper"
+	"jetengine/internal/search"
+	"jetengine/internal/storage"
+)
+
+// maxBodyBytes limits the size of request bodies.
+const maxBodyBytes = 1 << 20
+
+// previewTimeout bounds how long a metadata preview may take.
+const previewTimeout = 45 * time.Second
+
+// Enqueuer schedules background scrape jobs, as queue.Queue does.
+type Enqueuer interface {
+	Enqueue(ctx context.Context, job *domain.ScrapeJob) error
+}
+
+// Handler holds the dependencies of the API endpoints.
+type Handler struct {
+	repo     storage.Repository
+	scraper  scraper.Scraper
+	jobs     Enqueuer
+	auth     Authenticator
+	sessions *Sessions
+	log      logrus.FieldLogger
+}
+
+// NewHandler creates the API handler. Links saved through the API are
+// scraped by the jobs enqueued on jobs, like those saved in Telegram.
+// Sessions may be nil, in which case browsers cannot log in; otherwise
+// session cookies are accepted when auth finds no credentials.
+func NewHandler(repo storage.Repository, scraper scraper.Scraper, jobs Enqueuer, auth Authenticator, sessions *Sessions, logger logrus.FieldLogger) *Handler {
+	if sessions != nil {
+		auth = Authenticators{auth, sessions}
+	}
+	return &Handler{
+		repo:     repo,
+		scraper:  scraper,
+		jobs:     jobs,
+		auth:     auth,
+		sessions: sessions,
+		log:      logger.WithField("component", "api_handler"),
+	}
+}
+
+// errorResponse is the body of every error response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// linkPageResponse is a page of links with the cursor of the next one.
+type linkPageResponse struct {
+	Links      []domain.Link `json:"links"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
+}
+
+// searchResponse is a page of search results.
+type searchResponse struct {
+	Links []domain.Link `json:"links"`
+	Total int           `json:"total"`
+}
+
+// tagResponse is a tag with the number of links carrying it.
+type tagResponse struct {
+	Tag   string `json:"tag"`
+	Links int    `json:"links"`
+}
+
+// createLinkRequest is the body of POST /api/v1/links.
+type createLinkRequest struct {
+	URL  string   `json:"url"`
+	Tags []string `json:"tags"`
+}
+
+// updateLinkRequest is the body of PATCH /api/v1/links/{id}. Fields left
+// out are not changed; tags replace the link's tags.
+type updateLinkRequest struct {
+	Read *bool     `json:"read"`
+	Tags *[]string `json:"tags"`
+}
+
+// renameTagRequest is the body of PATCH /api/v1/tags/{tag}.
+type renameTagRequest struct {
+	Name string `json:"name"`
+}
+
+// listLinks handles GET /api/v1/links. Query parameters: read (read|unread),
+// tag, domain, since and until (RFC 3339), order (newest|oldest), limit and cursor.
+func (h *Handler) listLinks(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.repo.ListLinks(r.Context(), userFrom(r), opts)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		h.writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+	if err != nil {
+		h.internalError(w, r, err, "Failed to list links")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, linkPageResponse{Links: nonNil(page.Links), NextCursor: page.NextCursor, PrevCursor: page.PrevCursor})
+}
+
+// parseListOptions reads the filters of GET /api/v1/links.
+func parseListOptions(q url.Values) (storage.ListOptions, error) {
+	opts := storage.ListOptions{
+		Tag:    q.Get("tag"),
+		Domain: q.Get("domain"),
+		Cursor: q.Get("cursor"),
+	}
+	switch q.Get("read") {
+	case "":
+	case "unread":
+		opts.Read = storage.OnlyUnread
+	case "read":
+		opts.Read = storage.OnlyRead
+	default:
+		return opts, fmt.Errorf("read must be read or unread")
+	}
+	switch q.Get("order") {
+	case "", "newest":
+	case "oldest":
+		opts.Order = storage.OldestFirst
+	default:
+		return opts, fmt.Errorf("order must be newest or oldest")
+	}
+	for name, t := range map[string]*time.Time{"since": &opts.Since, "until": &opts.Until} {
+		if v := q.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return opts, fmt.Errorf("%s must be an RFC 3339 time", name)
+			}
+			*t = parsed
+		}
+	}
+	limit, err := parseInt(q, "limit")
+	if err != nil {
+		return opts, err
+	}
+	opts.Limit = limit
+	return opts, nil
+}
+
+// createLink handles POST /api/v1/links. The link is saved right away and
+// its page scraped in the background, so the response is 202 Accepted with
+// a pending link; poll GET /api/v1/links/{id} for the metadata. If the user
+// saved the page before, the stored link gains the new tags and is scraped
+// again, and the response is 200 OK with that link.
+func (h *Handler) createLink(w http.ResponseWriter, r *http.Request) {
+	var req createLinkRequest
+	if !h.readJSON(w, r, &req) {
+		return
+	}
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		h.writeError(w, http.StatusBadRequest, "url must be an absolute http or https URL")
+		return
+	}
+
+	link := domain.Link{
+		URL:       u.String(),
+		UserID:    userFrom(r),
+		Timestamp: time.Now(),
+		Tags:      req.Tags,
+		Pending:   true,
+	}
+	existed, err := h.repo.AddLink(r.Context(), &link)
+	if err != nil {
+		h.internalError(w, r, err, "Failed to save link")
+		return
+	}
+	job := domain.ScrapeJob{UserID: link.UserID, LinkID: link.ID, URL: link.URL, Refresh: existed}
+	if err := h.jobs.Enqueue(r.Context(), &job); err != nil {
+		h.internalError(w, r, err, "Failed to enqueue scrape job")
+		return
+	}
+	w.Header().Set("Location", "/api/v1/links/"+link.ID)
+	if existed {
+		h.writeJSON(w, http.StatusOK, link)
+		return
+	}
+	h.writeJSON(w, http.StatusAccepted, link)
+}
+
+// getLink handles GET /api/v1/links/{id}.
+func (h *Handler) getLink(w http.ResponseWriter, r *http.Request) {
+	link, err := h.repo.GetLinkByID(r.Context(), userFrom(r), r.PathValue("id"))
+	if errors.Is(err, storage.ErrNotFound) {
+		h.writeError(w, http.StatusNotFound, "link not found")
+		return
+	}
+	if err != nil {
+		h.internalError(w, r, err, "Failed to get link")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, link)
+}
+
+// updateLink handles PATCH /api/v1/links/{id}, changing the read state or tags.
+func (h *Handler) updateLink(w http.ResponseWriter, r *http.Request) {
+	var req updateLinkRequest
+	if !h.readJSON(w, r, &req) {
+		return
+	}
+	ctx, userID, id := r.Context(), userFrom(r), r.PathValue("id")
+
+	// Each change is written on its own, so a scrape finishing meanwhile is kept
+	var link domain.Link
+	var err error
+	switch {
+	case req.Tags != nil:
+		link, err = h.repo.SetTags(ctx, userID, id, *req.Tags)
+	case req.Read == nil:
+		link, err = h.repo.GetLinkByID(ctx, userID, id)
+	}
+	if err == nil && req.Read != nil {
+		link, err = h.repo.SetRead(ctx, userID, id, *req.Read)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		h.writeError(w, http.StatusNotFound, "link not found")
+		return
+	}
+	if err != nil {
+		h.internalError(w, r, err, "Failed to update link")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, link)
+}
+
+// deleteLink handles DELETE /api/v1/links/{id}.
+func (h *Handler) deleteLink(w http.ResponseWriter, r *http.Request) {
+	err := h.repo.DeleteLinkByID(r.Context(), userFrom(r), r.PathValue("id"))
+	if errors.Is(err, storage.ErrNotFound) {
+		h.writeError(w, http.StatusNotFound, "link not found")
+		return
+	}
+	if err != nil {
+		h.internalError(w, r, err, "Failed to delete link")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// refreshLink handles POST /api/v1/links/{id}/refresh by scraping the page
+// again in the background.
+func (h *Handler) refreshLink(w http.ResponseWriter, r *http.Request) {
+	link, err := h.repo.GetLinkByID(r.Context(), userFrom(r), r.PathValue("id"))
+	if errors.Is(err, storage.ErrNotFound) {
+		h.writeError(w, http.StatusNotFound, "link not found")
+		return
+	}
+	if err != nil {
+		h.internalError(w, r, err, "Failed to get link")
+		return
+	}
+	job := domain.ScrapeJob{UserID: link.UserID, LinkID: link.ID, URL: link.URL, Refresh: true}
+	if err := h.jobs.Enqueue(r.Context(), &job); err != nil {
+		h.internalError(w, r, err, "Failed to enqueue scrape job")
+		return
+	}
+	h.writeJSON(w, http.StatusAccepted, link)
+}
+
+// previewLink handles GET /api/v1/preview?url=..., scraping the metadata of
+// a page without saving it.
+func (h *Handler) previewLink(w http.ResponseWriter, r *http.Request) {
+	u, err := url.Parse(r.URL.Query().Get("url"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		h.writeError(w, http.StatusBadRequest, "url must be an absolute http or https URL")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), previewTimeout)
+	defer cancel()
+	meta, err := h.scraper.ScrapeMetadata(ctx, u.String())
+	if err != nil {
+		h.log.WithError(err).WithField("url", u.String()).Warn("Failed to scrape preview")
+		h.writeError(w, http.StatusBadGateway, "could not fetch the page")
+		return
+	}
+	// The article text is only kept for saved links
+	meta.Article = nil
+	h.writeJSON(w, http.StatusOK, meta)
+}
+
+// searchLinks handles GET /api/v1/search?q=...&offset=...&limit=... The query
+// supports the same tag: and site: filters as the bot's /search.
+func (h *Handler) searchLinks(w http.ResponseWriter, r *http.Request) {
+	query := search.ParseQuery(r.URL.Query().Get("q"))
+	if query.IsEmpty() {
+		h.writeError(w, http.StatusBadRequest, "q must not be empty")
+		return
+	}
+	offset, err := parseInt(r.URL.Query(), "offset")
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parseInt(r.URL.Query(), "limit")
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.repo.SearchLinks(r.Context(), userFrom(r), query, offset, limit)
+	if err != nil {
+		h.internalError(w, r, err, "Failed to search links")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, searchResponse{Links: nonNil(result.Links), Total: result.Total})
+}
+
+// listTags handles GET /api/v1/tags.
+func (h *Handler) listTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.repo.Tags(r.Context(), userFrom(r))
+	if err != nil {
+		h.internalError(w, r, err, "Failed to list tags")
+		return
+	}
+	resp := make([]tagResponse, len(tags))
+	for i, t := range tags {
+		resp[i] = tagResponse{Tag: t.Tag, Links: t.Links}
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// renameTag handles PATCH /api/v1/tags/{tag}, renaming the tag on all links
+// and merging it into the new name if that is in use.
+func (h *Handler) renameTag(w http.ResponseWriter, r *http.Request) {
+	var req renameTagRequest
+	if !h.readJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		h.writeError(w, http.StatusBadRequest, "name must not be empty")
+		return
+	}
+
+	n, err := h.repo.RenameTag(r.Context(), userFrom(r), r.PathValue("tag"), req.Name)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.writeError(w, http.StatusNotFound, "tag not found")
+		return
+	}
+	if errors.Is(err, storage.ErrTooManyLinks) {
+		h.writeError(w, http.StatusUnprocessableEntity, "tag is on too many links to rename at once")
+		return
+	}
+	if err != nil {
+		h.internalError(w, r, err, "Failed to rename tag")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]int{"links": n})
+}
+
+// healthResponse is the body of GET /healthz.
+type healthResponse struct {
+	Status string `json:"status"`
+	// ScrapeCache counts scrape cache hits and misses since startup, if the cache is enabled.
+	ScrapeCache *scraper.CacheStats `json:"scrape_cache,omitempty"`
+}
+
+// health handles GET /healthz for load balancers and container probes.
+func (h *Handler) health(w http.ResponseWriter, r *http.Request) {
+	resp := healthResponse{Status: "ok"}
+	if cache, ok := h.scraper.(*scraper.CachingScraper); ok {
+		stats := cache.Stats()
+		resp.ScrapeCache = &stats
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// readJSON decodes a JSON request body into v. On failure it writes a
+// 400 response and returns false.
+func (h *Handler) readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as a JSON response with the given status.
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.log.WithError(err).Warn("Failed to write response")
+	}
+}
+
+// writeError writes an error response.
+func (h *Handler) writeError(w http.ResponseWriter, status int, message string) {
+	h.writeJSON(w, status, errorResponse{Error: message})
+}
+
+// internalError logs an unexpected error and writes a 500 response that
+// does not expose it.
+func (h *Handler) internalError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	h.log.WithError(err).WithFields(logrus.Fields{
+		"user_id": userFrom(r),
+		"path":    r.URL.Path,
+	}).Error(msg)
+	h.writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+// parseInt reads an optional non-negative integer query parameter.
+func parseInt(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return n, nil
+}
+
+// nonNil returns links, or an empty slice if it is nil, so responses always
+// contain a JSON array.
+func nonNil(links []domain.Link) []domain.Link {
+	if links == nil {
+		return []domain.Link{}
+	}
+	return links
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/scraper"
+	"jetengine/internal/storage"
+)
+
+// fakeScraper answers with a fixed result per URL.
+type fakeScraper struct {
+	results map[string]scraper.Metadata
+}
+
+func (f *fakeScraper) ScrapeMetadata(ctx context.Context, url string) (scraper.Metadata, error) {
+	return f.results[url], nil
+}
+
+func (f *fakeScraper) Close() error { return nil }
+
+// fakeQueue records enqueued jobs.
+type fakeQueue struct {
+	jobs []domain.ScrapeJob
+}
+
+func (q *fakeQueue) Enqueue(ctx context.Context, job *domain.ScrapeJob) error {
+	q.jobs = append(q.jobs, *job)
+	return nil
+}
+
+// testAPI is a handler backed by a temporary repository, with the API key
+// "secret" for user 1 and "other" for user 2. Personal tokens are accepted too.
+type testAPI struct {
+	repo    *storage.BadgerRepository
+	queue   *fakeQueue
+	handler http.Handler
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	repo, err := storage.NewBadgerRepository(t.TempDir(), logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+
+	keys, err := ParseStaticKeys("1:secret, 2:other")
+	require.NoError(t, err)
+	q := &fakeQueue{}
+	s := &fakeScraper{results: map[string]scraper.Metadata{
+		"https://example.com/page": {Title: "Example page"},
+	}}
+	auth := Authenticators{NewTokenAuth(repo), keys}
+	return &testAPI{repo: repo, queue: q, handler: NewHandler(repo, s, q, auth, nil, logger).Routes()}
+}
+
+// do sends a request with the given API key and decodes the JSON response into out.
+func (a *testAPI) do(t *testing.T, method, path, key, body string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	a.handler.ServeHTTP(rec, req)
+	if out != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out), rec.Body.String())
+	}
+	return rec.Code
+}
+
+// TestAPI_Health tests that health checks report the scrape cache counters.
+func TestAPI_Health(t *testing.T) {
+	api := newTestAPI(t)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cache := scraper.NewCachingScraper(&fakeScraper{}, api.repo, time.Hour, logger)
+	handler := NewHandler(api.repo, cache, api.queue, &StaticKeys{}, nil, logger).Routes()
+
+	for range 2 {
+		_, err := cache.ScrapeMetadata(context.Background(), "https://example.com/page")
+		require.NoError(t, err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var health healthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &health))
+	assert.Equal(t, "ok", health.Status)
+	require.NotNil(t, health.ScrapeCache)
+	assert.Equal(t, scraper.CacheStats{Hits: 1, Misses: 1}, *health.ScrapeCache)
+}
+
+// TestAPI_Links tests the link endpoints end to end.
+func TestAPI_Links(t *testing.T) {
+	api := newTestAPI(t)
+
+	assert.Equal(t, http.StatusUnauthorized, api.do(t, "GET", "/api/v1/links", "", "", nil))
+	assert.Equal(t, http.StatusUnauthorized, api.do(t, "GET", "/api/v1/links", "wrong", "", nil))
+	assert.Equal(t, http.StatusOK, api.do(t, "GET", "/healthz", "", "", nil), "Health checks need no credentials")
+
+	// --- Create ---
+	assert.Equal(t, http.StatusBadRequest, api.do(t, "POST", "/api/v1/links", "secret", `{"url": "ftp://example.com"}`, nil))
+	var link domain.Link
+	code := api.do(t, "POST", "/api/v1/links", "secret", `{"url": "https://example.com/page", "tags": ["Go"]}`, &link)
+	require.Equal(t, http.StatusAccepted, code)
+	assert.NotEmpty(t, link.ID)
+	assert.True(t, link.Pending)
+	assert.Equal(t, []string{"go"}, link.Tags)
+	require.Len(t, api.queue.jobs, 1, "New links should be scraped in the background")
+	assert.Equal(t, link.ID, api.queue.jobs[0].LinkID)
+
+	// --- Get, update, list ---
+	assert.Equal(t, http.StatusNotFound, api.do(t, "GET", "/api/v1/links/"+link.ID, "other", "", nil), "Links of other users should be invisible")
+	code = api.do(t, "PATCH", "/api/v1/links/"+link.ID, "secret", `{"read": true, "tags": ["reading", "Later"]}`, &link)
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, link.Read)
+	assert.ElementsMatch(t, []string{"reading", "later"}, link.Tags)
+
+	var page linkPageResponse
+	require.Equal(t, http.StatusOK, api.do(t, "GET", "/api/v1/links?read=read&tag=later", "secret", "", &page))
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, link.ID, page.Links[0].ID)
+	require.Equal(t, http.StatusOK, api.do(t, "GET", "/api/v1/links?read=unread", "secret", "", &page))
+	assert.Empty(t, page.Links)
+	assert.Equal(t, http.StatusBadRequest, api.do(t, "GET", "/api/v1/links?since=yesterday", "secret", "", nil))
+
+	// --- Tags ---
+	var tags []tagResponse
+	require.Equal(t, http.StatusOK, api.do(t, "GET", "/api/v1/tags", "secret", "", &tags))
+	assert.Len(t, tags, 2)
+	var renamed map[string]int
+	require.Equal(t, http.StatusOK, api.do(t, "PATCH", "/api/v1/tags/later", "secret", `{"name": "someday"}`, &renamed))
+	assert.Equal(t, 1, renamed["links"])
+	assert.Equal(t, http.StatusNotFound, api.do(t, "PATCH", "/api/v1/tags/missing", "secret", `{"name": "x"}`, nil))
+
+	// --- Saving the page again keeps the link ---
+	var again domain.Link
+	code = api.do(t, "POST", "/api/v1/links", "secret", `{"url": "https://example.com/page?utm_source=x", "tags": ["new"]}`, &again)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, link.ID, again.ID)
+	assert.True(t, again.Read)
+	assert.ElementsMatch(t, []string{"reading", "someday", "new"}, again.Tags)
+	require.Len(t, api.queue.jobs, 2)
+	assert.True(t, api.queue.jobs[1].Refresh, "Saved pages should only be scraped again")
+
+	// --- Refresh and delete ---
+	assert.Equal(t, http.StatusAccepted, api.do(t, "POST", "/api/v1/links/"+link.ID+"/refresh", "secret", "", nil))
+	require.Len(t, api.queue.jobs, 3)
+	assert.True(t, api.queue.jobs[2].Refresh)
+	assert.Equal(t, http.StatusNoContent, api.do(t, "DELETE", "/api/v1/links/"+link.ID, "secret", "", nil))
+	assert.Equal(t, http.StatusNotFound, api.do(t, "DELETE", "/api/v1/links/"+link.ID, "secret", "", nil))
+}
+
+// TestAPI_SearchAndPreview tests search and metadata previews.
+func TestAPI_SearchAndPreview(t *testing.T) {
+	api := newTestAPI(t)
+	ctx := context.Background()
+	link := domain.Link{URL: "https://go.dev/blog/pgo", Title: "Profile-guided optimization", UserID: 1, Tags: []string{"go"}}
+	require.NoError(t, api.repo.SaveLink(ctx, &link))
+
+	var result searchResponse
+	require.Equal(t, http.StatusOK, api.do(t, "GET", "/api/v1/search?q=optimization+tag:go", "secret", "", &result))
+	require.Equal(t, 1, result.Total)
+	assert.Equal(t, link.ID, result.Links[0].ID)
+	require.Equal(t, http.StatusOK, api.do(t, "GET", "/api/v1/search?q=optimization", "other", "", &result))
+	assert.Zero(t, result.Total)
+	assert.Equal(t, http.StatusBadRequest, api.do(t, "GET", "/api/v1/search?q=", "secret", "", nil))
+
+	var meta scraper.Metadata
+	require.Equal(t, http.StatusOK, api.do(t, "GET", "/api/v1/preview?url=https://example.com/page", "secret", "", &meta))
+	assert.Equal(t, "Example page", meta.Title)
+}
+
+// TestAPI_Tokens tests authentication with personal API tokens and their scopes.
+func TestAPI_Tokens(t *testing.T) {
+	api := newTestAPI(t)
+	ctx := context.Background()
+	reader, err := api.repo.CreateToken(ctx, &domain.APIToken{UserID: 1, Name: "reader", Scopes: []domain.TokenScope{domain.ScopeRead}})
+	require.NoError(t, err)
+	writer, err := api.repo.CreateToken(ctx, &domain.APIToken{UserID: 1, Name: "writer", Scopes: []domain.TokenScope{domain.ScopeRead, domain.ScopeWrite}})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusForbidden, api.do(t, "POST", "/api/v1/links", reader, `{"url": "https://example.com/page"}`, nil), "Read tokens should not save links")
+	var link domain.Link
+	require.Equal(t, http.StatusAccepted, api.do(t, "POST", "/api/v1/links", writer, `{"url": "https://example.com/page"}`, &link))
+	assert.Equal(t, int64(1), link.UserID, "Links should belong to the owner of the token")
+	assert.Equal(t, http.StatusOK, api.do(t, "GET", "/api/v1/links/"+link.ID, reader, "", nil))
+	assert.Equal(t, http.StatusOK, api.do(t, "GET", "/api/v1/links/"+link.ID, "secret", "", nil), "Static keys should keep working")
+
+	tokens, err := api.repo.ListTokens(ctx, 1)
+	require.NoError(t, err)
+	for _, token := range tokens {
+		require.NoError(t, api.repo.RevokeToken(ctx, 1, token.ID))
+	}
+	assert.Equal(t, http.StatusUnauthorized, api.do(t, "GET", "/api/v1/links/"+link.ID, reader, "", nil), "Revoked tokens should be rejected")
+}
+
+// TestParseStaticKeys tests parsing of the API_KEYS setting.
+func TestParseStaticKeys(t *testing.T) {
+	keys, err := ParseStaticKeys("")
+	require.NoError(t, err)
+	_, err = keys.Authenticate(httptest.NewRequest("GET", "/", nil))
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	for _, spec := range []string{"secret", "abc:secret", "1:"} {
+		_, err := ParseStaticKeys(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+// TestServer_Shutdown tests that the server stops when its context is cancelled.
+func TestServer_Shutdown(t *testing.T) {
+	api := newTestAPI(t)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	s := New(Options{ShutdownTimeout: time.Second}, NewHandler(api.repo, &fakeScraper{}, api.queue, &StaticKeys{}, nil, logger), logger)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.serve(ctx, ln) }()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+}
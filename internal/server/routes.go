@@ -1 +1,72 @@
 package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+)
+
+// Routes returns the HTTP handler serving all API endpoints.
+// Everything under /api/v1/ requires authentication; reading needs the read
+// scope and everything else the write scope. When sessions are enabled,
+// browsers log in under /auth/ and the web UI is served at /.
+func (h *Handler) Routes() http.Handler {
+	api := http.NewServeMux()
+	api.Handle("GET /api/v1/links", h.requireScope(domain.ScopeRead, h.listLinks))
+	api.Handle("POST /api/v1/links", h.requireScope(domain.ScopeWrite, h.createLink))
+	api.Handle("GET /api/v1/links/{id}", h.requireScope(domain.ScopeRead, h.getLink))
+	api.Handle("PATCH /api/v1/links/{id}", h.requireScope(domain.ScopeWrite, h.updateLink))
+	api.Handle("DELETE /api/v1/links/{id}", h.requireScope(domain.ScopeWrite, h.deleteLink))
+	api.Handle("POST /api/v1/links/{id}/refresh", h.requireScope(domain.ScopeWrite, h.refreshLink))
+	api.Handle("GET /api/v1/preview", h.requireScope(domain.ScopeRead, h.previewLink))
+	api.Handle("GET /api/v1/search", h.requireScope(domain.ScopeRead, h.searchLinks))
+	api.Handle("GET /api/v1/tags", h.requireScope(domain.ScopeRead, h.listTags))
+	api.Handle("PATCH /api/v1/tags/{tag}", h.requireScope(domain.ScopeWrite, h.renameTag))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", h.health)
+	mux.Handle("/api/v1/", h.requireUser(api))
+	if h.sessions != nil {
+		mux.HandleFunc("GET /auth/telegram", h.loginWidgetRedirect)
+		mux.HandleFunc("POST /auth/telegram", h.loginWidget)
+		mux.HandleFunc("POST /auth/webapp", h.loginWebApp)
+		mux.HandleFunc("GET /auth/session", h.currentSession)
+		mux.HandleFunc("POST /auth/logout", h.logout)
+
+		mux.HandleFunc("GET /{$}", h.linksPage)
+		mux.HandleFunc("GET /login", h.loginPage)
+		mux.HandleFunc("POST /links/bulk", h.bulkLinks)
+		mux.Handle("GET /static/", staticFiles())
+	}
+	return h.logRequests(mux)
+}
+
+// statusRecorder remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status before passing it on.
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests logs every request with its status and duration.
+func (h *Handler) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		h.log.WithFields(logrus.Fields{
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"status":   rec.status,
+			"duration": time.Since(start),
+		}).Info("Handled request")
+	})
+}
@@ -0,0 +1,88 @@
+// Package server provides the HTTP REST API of JetEngine. It exposes the
+// links, tags and search of the authenticated user, backed by the same
+// repository, scraper and job queue as the Telegram bot.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Options configures the HTTP server.
+type Options struct {
+	// Addr is the TCP address to listen on, such as ":8080".
+	Addr string
+	// ShutdownTimeout is how long in-flight requests may take to finish on shutdown.
+	ShutdownTimeout time.Duration
+}
+
+// Server serves the REST API until its context is cancelled.
+type Server struct {
+	http            *http.Server
+	shutdownTimeout time.Duration
+	log             logrus.FieldLogger
+}
+
+// New creates a server for the routes of h.
+func New(opts Options, h *Handler, logger logrus.FieldLogger) *Server {
+	return &Server{
+		http: &http.Server{
+			Addr:              opts.Addr,
+			Handler:           h.Routes(),
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+		},
+		shutdownTimeout: opts.ShutdownTimeout,
+		log:             logger.WithField("component", "http_server"),
+	}
+}
+
+// Run listens for requests until ctx is cancelled, then stops accepting new
+// connections and waits up to the shutdown timeout for running requests.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.http.Addr, err)
+	}
+	return s.serve(ctx, ln)
+}
+
+// serve runs the server on an existing listener.
+func (s *Server) serve(ctx context.Context, ln net.Listener) error {
+	s.http.BaseContext = func(net.Listener) context.Context {
+		// Requests keep running during shutdown; Shutdown waits for them
+		return context.WithoutCancel(ctx)
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.http.Serve(ln)
+	}()
+	s.log.WithField("addr", ln.Addr().String()).Info("HTTP server listening")
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("http server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	s.log.Info("Shutting down HTTP server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	if err := s.http.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down http server: %w", err)
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("http server failed: %w", err)
+	}
+	s.log.Info("HTTP server stopped")
+	return nil
+}
@@ -0,0 +1,302 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/storage"
+	"jetengine/internal/telegramauth"
+)
+
+// sessionCookie is the name of the cookie holding the session ID.
+const sessionCookie = "jetengine_session"
+
+// Requests of a session that change data carry its CSRF token in a header
+// when sent by scripts, or in a field when sent by HTML forms.
+const (
+	csrfHeader = "X-CSRF-Token"
+	csrfField  = "csrf_token"
+)
+
+// SessionOptions configures browser sessions.
+type SessionOptions struct {
+	// TTL is how long a login lasts.
+	TTL time.Duration
+	// Secure restricts the session cookie to HTTPS.
+	Secure bool
+	// BotUsername enables the Telegram Login Widget on the login page.
+	BotUsername string
+}
+
+// Sessions logs browsers in with data signed by Telegram and authenticates
+// their requests by session cookie.
+type Sessions struct {
+	store    storage.SessionStore
+	verifier *telegramauth.Verifier
+	opts     SessionOptions
+	log      logrus.FieldLogger
+}
+
+// NewSessions creates browser sessions stored in store, accepting logins
+// verified by verifier.
+func NewSessions(store storage.SessionStore, verifier *telegramauth.Verifier, opts SessionOptions, logger logrus.FieldLogger) *Sessions {
+	return &Sessions{
+		store:    store,
+		verifier: verifier,
+		opts:     opts,
+		log:      logger.WithField("component", "sessions"),
+	}
+}
+
+// Authenticate implements Authenticator. Requests that change data must
+// carry the CSRF token of the session and, if they have an Origin, come
+// from this site; otherwise ErrForbidden is returned.
+func (s *Sessions) Authenticate(r *http.Request) (Principal, error) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return Principal{}, ErrUnauthenticated
+	}
+	session, err := s.store.GetSession(r.Context(), cookie.Value)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return Principal{}, ErrUnauthenticated
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	if !safeMethod(r.Method) && (crossOrigin(r) || !validCSRF(r, session.CSRFToken)) {
+		return Principal{}, ErrForbidden
+	}
+	return Principal{UserID: session.UserID, Name: session.Name, Scopes: allScopes, CSRFToken: session.CSRFToken}, nil
+}
+
+// safeMethod reports whether requests with method only read data.
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// crossOrigin reports whether a browser sent the request from another site.
+// Requests without an Origin header, such as those of older browsers, pass.
+func crossOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != r.Host
+}
+
+// validCSRF reports whether a request carries the CSRF token want.
+func validCSRF(r *http.Request, want string) bool {
+	got := r.Header.Get(csrfHeader)
+	if got == "" {
+		got = r.PostFormValue(csrfField)
+	}
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// login starts a session for a verified Telegram user and sets its cookie.
+func (s *Sessions) login(w http.ResponseWriter, r *http.Request, user telegramauth.User) (domain.Session, error) {
+	session := domain.Session{
+		UserID:    user.ID,
+		Name:      user.Name(),
+		ExpiresAt: time.Now().Add(s.opts.TTL),
+	}
+	id, err := s.store.CreateSession(r.Context(), &session)
+	if err != nil {
+		return session, err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		Secure:   s.opts.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	s.log.WithField("user_id", user.ID).Info("User logged in")
+	return session, nil
+}
+
+// logout ends the session of a request, if any, and clears its cookie.
+func (s *Sessions) logout(w http.ResponseWriter, r *http.Request) error {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if err := s.store.DeleteSession(r.Context(), cookie.Value); err != nil {
+			return err
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   s.opts.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// sessionResponse describes the session of the logged in browser.
+type sessionResponse struct {
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	CSRFToken string    `json:"csrf_token"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// webAppLoginRequest is the body of a Mini App login.
+type webAppLoginRequest struct {
+	InitData string `json:"init_data"`
+}
+
+// loginWidgetRedirect handles the redirect of the Telegram Login Widget,
+// which passes the signed user as query parameters, and sends the browser
+// to the start page.
+func (h *Handler) loginWidgetRedirect(w http.ResponseWriter, r *http.Request) {
+	user, err := h.sessions.verifier.VerifyLogin(r.URL.Query())
+	if _, ok := h.startSession(w, r, user, err); ok {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+// loginWidget handles the user object the Telegram Login Widget passes to
+// its JavaScript callback, posted as JSON.
+func (h *Handler) loginWidget(w http.ResponseWriter, r *http.Request) {
+	if crossOrigin(r) {
+		h.writeError(w, http.StatusForbidden, "cross-origin login")
+		return
+	}
+	var raw map[string]json.RawMessage
+	if !h.readJSON(w, r, &raw) {
+		return
+	}
+	fields, err := loginFields(raw)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	user, err := h.sessions.verifier.VerifyLogin(fields)
+	if session, ok := h.startSession(w, r, user, err); ok {
+		h.writeJSON(w, http.StatusOK, newSessionResponse(session))
+	}
+}
+
+// loginFields converts the user object of the Login Widget, whose values
+// are strings and numbers, to the fields Telegram signed.
+func loginFields(raw map[string]json.RawMessage) (url.Values, error) {
+	fields := url.Values{}
+	for key, value := range raw {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			fields.Set(key, s)
+			continue
+		}
+		var n json.Number
+		if err := json.Unmarshal(value, &n); err != nil {
+			return nil, errors.New("field " + key + " must be a string or number")
+		}
+		fields.Set(key, n.String())
+	}
+	return fields, nil
+}
+
+// loginWebApp handles the initData of a Telegram Mini App, posted as JSON.
+func (h *Handler) loginWebApp(w http.ResponseWriter, r *http.Request) {
+	if crossOrigin(r) {
+		h.writeError(w, http.StatusForbidden, "cross-origin login")
+		return
+	}
+	var req webAppLoginRequest
+	if !h.readJSON(w, r, &req) {
+		return
+	}
+	user, err := h.sessions.verifier.VerifyInitData(req.InitData)
+	if session, ok := h.startSession(w, r, user, err); ok {
+		h.writeJSON(w, http.StatusOK, newSessionResponse(session))
+	}
+}
+
+// startSession logs in the user returned by a Telegram verification along
+// with its error. It writes an error response and returns false if the
+// verification failed or the session could not be stored.
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, user telegramauth.User, err error) (domain.Session, bool) {
+	switch {
+	case errors.Is(err, telegramauth.ErrMalformed):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return domain.Session{}, false
+	case err != nil:
+		h.log.WithError(err).Warn("Rejected Telegram login")
+		h.writeError(w, http.StatusUnauthorized, err.Error())
+		return domain.Session{}, false
+	}
+	session, err := h.sessions.login(w, r, user)
+	if err != nil {
+		h.internalError(w, r, err, "could not log in")
+		return domain.Session{}, false
+	}
+	return session, true
+}
+
+// newSessionResponse describes session to its browser.
+func newSessionResponse(session domain.Session) sessionResponse {
+	return sessionResponse{
+		UserID:    session.UserID,
+		Name:      session.Name,
+		CSRFToken: session.CSRFToken,
+		ExpiresAt: session.ExpiresAt,
+	}
+}
+
+// currentSession describes the session of the request, so that scripts can
+// learn the user and CSRF token after a page load.
+func (h *Handler) currentSession(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		h.writeError(w, http.StatusUnauthorized, "not logged in")
+		return
+	}
+	session, err := h.sessions.store.GetSession(r.Context(), cookie.Value)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		h.writeError(w, http.StatusUnauthorized, "not logged in")
+		return
+	}
+	if err != nil {
+		h.internalError(w, r, err, "could not load the session")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, newSessionResponse(session))
+}
+
+// logout ends the session of the request. Like other changes, it needs the
+// session's CSRF token. HTML forms are sent on to the login page.
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	_, err := h.sessions.Authenticate(r)
+	if errors.Is(err, ErrForbidden) {
+		h.writeError(w, http.StatusForbidden, "invalid CSRF token")
+		return
+	}
+	if err != nil && !errors.Is(err, ErrUnauthenticated) {
+		h.internalError(w, r, err, "could not log out")
+		return
+	}
+	if err := h.sessions.logout(w, r); err != nil {
+		h.internalError(w, r, err, "could not log out")
+		return
+	}
+	if r.PostFormValue(csrfField) != "" {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"jetengine/internal/telegramauth"
+)
+
+const testBotToken = "123456:TEST-token"
+
+// signInitData returns Mini App initData for user 1 signed like Telegram does.
+func signInitData(botToken string) string {
+	fields := url.Values{
+		"user":      {`{"id":1,"first_name":"Ada"}`},
+		"auth_date": {strconv.FormatInt(time.Now().Unix(), 10)},
+	}
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte("auth_date=" + fields.Get("auth_date") + "\nuser=" + fields.Get("user")))
+	fields.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return fields.Encode()
+}
+
+// TestAPI_Sessions tests logging in from a Mini App and using the session cookie.
+func TestAPI_Sessions(t *testing.T) {
+	api := newTestAPI(t)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	sessions := NewSessions(api.repo, telegramauth.NewVerifier(testBotToken, time.Hour), SessionOptions{TTL: time.Hour, Secure: true}, logger)
+	handler := NewHandler(api.repo, &fakeScraper{}, api.queue, &StaticKeys{}, sessions, logger).Routes()
+
+	send := func(req *http.Request, cookie *http.Cookie) *httptest.ResponseRecorder {
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	login := func(botToken string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(webAppLoginRequest{InitData: signInitData(botToken)})
+		return send(httptest.NewRequest("POST", "/auth/webapp", strings.NewReader(string(body))), nil)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login("654321:other-bot").Code, "Data signed for another bot should be rejected")
+	rec := login(testBotToken)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var session sessionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &session))
+	assert.Equal(t, int64(1), session.UserID)
+	assert.Equal(t, "Ada", session.Name)
+	require.NotEmpty(t, session.CSRFToken)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+
+	// --- Reading needs only the cookie ---
+	assert.Equal(t, http.StatusOK, send(httptest.NewRequest("GET", "/api/v1/links", nil), cookie).Code)
+	assert.Equal(t, http.StatusOK, send(httptest.NewRequest("GET", "/auth/session", nil), cookie).Code)
+
+	// --- Changes need the CSRF token from the same origin ---
+	create := func(csrf, origin string) int {
+		req := httptest.NewRequest("POST", "/api/v1/links", strings.NewReader(`{"url": "https://example.com/page"}`))
+		if csrf != "" {
+			req.Header.Set(csrfHeader, csrf)
+		}
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		return send(req, cookie).Code
+	}
+	assert.Equal(t, http.StatusForbidden, create("", ""))
+	assert.Equal(t, http.StatusForbidden, create("wrong", ""))
+	assert.Equal(t, http.StatusForbidden, create(session.CSRFToken, "https://evil.example"))
+	assert.Equal(t, http.StatusAccepted, create(session.CSRFToken, "http://example.com"))
+
+	// --- Logout ends the session ---
+	req := httptest.NewRequest("POST", "/auth/logout", nil)
+	req.Header.Set(csrfHeader, session.CSRFToken)
+	assert.Equal(t, http.StatusNoContent, send(req, cookie).Code)
+	assert.Equal(t, http.StatusUnauthorized, send(httptest.NewRequest("GET", "/api/v1/links", nil), cookie).Code)
+}
+
+// TestLoginFields tests converting the Login Widget's user object to signed fields.
+func TestLoginFields(t *testing.T) {
+	var raw map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(`{"id": 42, "first_name": "Ada", "auth_date": 1700000000}`), &raw))
+	fields, err := loginFields(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "42", fields.Get("id"))
+	assert.Equal(t, "1700000000", fields.Get("auth_date"))
+
+	require.NoError(t, json.Unmarshal([]byte(`{"id": {"nested": true}}`), &raw))
+	_, err = loginFields(raw)
+	assert.Error(t, err)
+}
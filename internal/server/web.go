@@ -0,0 +1,373 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/search"
+	"jetengine/internal/storage"
+)
+
+// webFS holds the templates and static files of the web UI, so the binary
+// serves it without any network access.
+//
+//go:embed web
+var webFS embed.FS
+
+// pageTemplates are the HTML pages of the web UI, named after their files.
+var pageTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"host":  linkHost,
+	"title": linkTitle,
+	"date":  func(t time.Time) string { return t.Format("Jan 2, 2006") },
+}).ParseFS(webFS, "web/templates/*.html"))
+
+// webPageSize is the number of links per page of the web UI.
+const webPageSize = 30
+
+// maxBulkLinks limits how many links one bulk action may change.
+const maxBulkLinks = 200
+
+// contentSecurityPolicy keeps pages to their own scripts and styles. Only
+// the Telegram Login Widget is loaded from elsewhere; preview images may
+// come from anywhere.
+const contentSecurityPolicy = "default-src 'self'; img-src 'self' https: http: data:; " +
+	"script-src 'self' https://telegram.org; frame-src https://oauth.telegram.org; " +
+	"form-action 'self'; base-uri 'none'"
+
+// staticFiles serves the stylesheet and script of the web UI.
+func staticFiles() http.Handler {
+	static, err := fs.Sub(webFS, "web/static")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/static/", http.FileServerFS(static))
+}
+
+// pageBase holds what every page shows.
+type pageBase struct {
+	Title     string
+	User      string
+	CSRFToken string
+}
+
+// loginPageData is shown to browsers without a session.
+type loginPageData struct {
+	pageBase
+	BotUsername string
+}
+
+// linksPageData is a page of links with the filters that selected it.
+type linksPageData struct {
+	pageBase
+	View    string
+	Query   string
+	Tag     string
+	Domain  string
+	Read    string
+	Links   []domain.Link
+	Tags    []storage.TagCount
+	Summary string
+	NextURL string
+	// Return is where bulk actions send the browser back to.
+	Return string
+}
+
+// params returns the query parameters of the page's view and filters.
+func (p linksPageData) params() url.Values {
+	q := url.Values{}
+	for key, value := range map[string]string{"q": p.Query, "tag": p.Tag, "domain": p.Domain, "read": p.Read} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	if p.View != "list" {
+		q.Set("view", p.View)
+	}
+	return q
+}
+
+// With returns the URL of the first page with one view or filter parameter
+// changed; an empty value removes the filter.
+func (p linksPageData) With(key, value string) string {
+	q := p.params()
+	q.Set(key, value)
+	return pageURL(q)
+}
+
+// Filtered reports whether any filter or search is applied.
+func (p linksPageData) Filtered() bool {
+	return p.Query != "" || p.Tag != "" || p.Domain != "" || p.Read != ""
+}
+
+// pageURL returns the links page URL with the non-empty parameters of q.
+func pageURL(q url.Values) string {
+	for key, values := range q {
+		if len(values) == 0 || values[0] == "" {
+			q.Del(key)
+		}
+	}
+	if len(q) == 0 {
+		return "/"
+	}
+	return "/?" + q.Encode()
+}
+
+// linkHost returns the host of a link without "www.", for display and filtering.
+func linkHost(link domain.Link) string {
+	raw := link.CanonicalURL
+	if raw == "" {
+		raw = link.URL
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// linkTitle returns the title of a link, or its URL if it has none yet.
+func linkTitle(link domain.Link) string {
+	if link.Title != "" {
+		return link.Title
+	}
+	return link.URL
+}
+
+// pageUser returns the principal of a browser session. Browsers without a
+// session are sent to the login page and false is returned.
+func (h *Handler) pageUser(w http.ResponseWriter, r *http.Request) (Principal, bool) {
+	p, err := h.sessions.Authenticate(r)
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return p, false
+	case errors.Is(err, ErrForbidden):
+		http.Error(w, "Invalid form token, please reload the page and try again.", http.StatusForbidden)
+		return p, false
+	case err != nil:
+		h.pageError(w, r, err, "Failed to authenticate request")
+		return p, false
+	}
+	return p, true
+}
+
+// renderPage writes the page template name filled with data.
+func (h *Handler) renderPage(w http.ResponseWriter, r *http.Request, name string, data any) {
+	var buf bytes.Buffer
+	if err := pageTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+		h.pageError(w, r, err, "Failed to render page")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// pageError logs an unexpected error of a page and shows a plain message.
+func (h *Handler) pageError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	h.log.WithError(err).WithFields(logrus.Fields{
+		"user_id": userFrom(r),
+		"path":    r.URL.Path,
+	}).Error(msg)
+	http.Error(w, "Something went wrong, please try again later.", http.StatusInternalServerError)
+}
+
+// loginPage handles GET /login.
+func (h *Handler) loginPage(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.sessions.Authenticate(r); err == nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	h.renderPage(w, r, "login.html", loginPageData{
+		pageBase:    pageBase{Title: "Log in"},
+		BotUsername: h.sessions.opts.BotUsername,
+	})
+}
+
+// linksPage handles GET /, listing the user's links as a list or grid.
+// Query parameters: view (list|grid), q, tag, domain, read (read|unread)
+// and cursor or, while searching, offset.
+func (h *Handler) linksPage(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.pageUser(w, r)
+	if !ok {
+		return
+	}
+	ctx, q := r.Context(), r.URL.Query()
+	page := linksPageData{
+		pageBase: pageBase{Title: "Links", User: p.Name, CSRFToken: p.CSRFToken},
+		View:     "list",
+		Query:    strings.TrimSpace(q.Get("q")),
+		Tag:      q.Get("tag"),
+		Domain:   q.Get("domain"),
+		Return:   r.URL.RequestURI(),
+	}
+	if q.Get("view") == "grid" {
+		page.View = "grid"
+	}
+	switch q.Get("read") {
+	case "read", "unread":
+		page.Read = q.Get("read")
+	}
+
+	var err error
+	if query := search.ParseQuery(page.Query); !query.IsEmpty() {
+		err = h.searchPage(ctx, p.UserID, &page, query, q.Get("offset"))
+	} else {
+		err = h.browsePage(ctx, p.UserID, &page, q.Get("cursor"))
+	}
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		http.Redirect(w, r, pageURL(page.params()), http.StatusSeeOther)
+		return
+	}
+	if err == nil {
+		page.Tags, err = h.repo.Tags(ctx, p.UserID)
+	}
+	if err != nil {
+		h.pageError(w, r, err, "Failed to list links")
+		return
+	}
+	h.renderPage(w, r, "links.html", page)
+}
+
+// browsePage fills page with the links after cursor that match its filters.
+func (h *Handler) browsePage(ctx context.Context, userID int64, page *linksPageData, cursor string) error {
+	opts := storage.ListOptions{Tag: page.Tag, Domain: page.Domain, Limit: webPageSize, Cursor: cursor}
+	switch page.Read {
+	case "read":
+		opts.Read = storage.OnlyRead
+	case "unread":
+		opts.Read = storage.OnlyUnread
+	}
+	result, err := h.repo.ListLinks(ctx, userID, opts)
+	if err != nil {
+		return err
+	}
+	page.Links = result.Links
+	page.Summary = formatCount(len(result.Links), "link", "links")
+	if result.NextCursor != "" {
+		q := page.params()
+		q.Set("cursor", result.NextCursor)
+		page.NextURL = pageURL(q)
+		page.Summary += " on this page"
+	}
+	return nil
+}
+
+// searchPage fills page with the search results at offset. The tag and
+// domain filters narrow the search; the read filter does not apply.
+func (h *Handler) searchPage(ctx context.Context, userID int64, page *linksPageData, query search.Query, offsetParam string) error {
+	if page.Tag != "" {
+		query.Tags = append(query.Tags, page.Tag)
+	}
+	if page.Domain != "" {
+		query.Sites = append(query.Sites, strings.ToLower(page.Domain))
+	}
+	offset, err := strconv.Atoi(offsetParam)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	result, err := h.repo.SearchLinks(ctx, userID, query, offset, webPageSize)
+	if err != nil {
+		return err
+	}
+	page.Links = result.Links
+	page.Summary = formatCount(result.Total, "result", "results")
+	if next := offset + len(result.Links); next < result.Total {
+		q := page.params()
+		q.Set("offset", strconv.Itoa(next))
+		page.NextURL = pageURL(q)
+	}
+	return nil
+}
+
+// formatCount returns "1 link", "2 links" and so on.
+func formatCount(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// bulkLinks handles POST /links/bulk, the form that applies an action to the
+// selected links: read, unread, tag, untag or delete. It sends the browser
+// back to the page it came from.
+func (h *Handler) bulkLinks(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	p, ok := h.pageUser(w, r)
+	if !ok {
+		return
+	}
+	action, ids := r.PostFormValue("action"), r.PostForm["id"]
+	tags := splitTags(r.PostFormValue("tags"))
+	switch {
+	case action != "read" && action != "unread" && action != "tag" && action != "untag" && action != "delete":
+		http.Error(w, "Unknown action.", http.StatusBadRequest)
+		return
+	case len(ids) > maxBulkLinks:
+		http.Error(w, fmt.Sprintf("Select at most %d links at once.", maxBulkLinks), http.StatusBadRequest)
+		return
+	}
+
+	if (action != "tag" && action != "untag") || len(tags) > 0 {
+		for _, id := range ids {
+			err := h.applyBulkAction(r.Context(), p.UserID, id, action, tags)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				h.pageError(w, r, err, "Failed to apply bulk action")
+				return
+			}
+		}
+		h.log.WithFields(logrus.Fields{
+			"user_id": p.UserID,
+			"action":  action,
+			"links":   len(ids),
+		}).Info("Applied bulk action")
+	}
+	http.Redirect(w, r, localPath(r.PostFormValue("return")), http.StatusSeeOther)
+}
+
+// applyBulkAction applies a bulk action to one link.
+func (h *Handler) applyBulkAction(ctx context.Context, userID int64, id, action string, tags []string) error {
+	switch action {
+	case "delete":
+		return h.repo.DeleteLinkByID(ctx, userID, id)
+	case "tag":
+		_, err := h.repo.TagLink(ctx, userID, id, tags, nil)
+		return err
+	case "untag":
+		_, err := h.repo.TagLink(ctx, userID, id, nil, tags)
+		return err
+	}
+	_, err := h.repo.SetRead(ctx, userID, id, action == "read")
+	return err
+}
+
+// splitTags splits tags separated by commas or spaces.
+func splitTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// localPath returns path if it stays on this site, or "/" otherwise, so
+// forms cannot redirect elsewhere.
+func localPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,70 @@
+// Conveniences for the JetEngine web UI. Every page works without scripts;
+// this adds Mini App login, selecting all links and delete confirmation.
+(function () {
+  "use strict";
+
+  // A Telegram Mini App passes its signed initData in the URL fragment.
+  function loginWithWebApp() {
+    const login = document.querySelector("[data-login]");
+    const initData = new URLSearchParams(window.location.hash.slice(1)).get("tgWebAppData");
+    if (!login || !initData) {
+      return;
+    }
+    fetch("/auth/webapp", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      credentials: "same-origin",
+      body: JSON.stringify({ init_data: initData }),
+    }).then(function (resp) {
+      if (!resp.ok) {
+        throw new Error("login failed with status " + resp.status);
+      }
+      window.location.replace("/");
+    }).catch(function () {
+      login.querySelector("[data-login-error]").hidden = false;
+    });
+  }
+
+  function bulkSelection() {
+    const form = document.getElementById("bulk");
+    if (!form) {
+      return;
+    }
+    const all = form.querySelector("[data-select-all]");
+    const count = form.querySelector("[data-selected-count]");
+    const summary = count.textContent;
+    const boxes = Array.from(form.querySelectorAll('input[name="id"]'));
+    const buttons = Array.from(form.querySelectorAll('button[name="action"]'));
+
+    function update() {
+      const selected = boxes.filter(function (box) { return box.checked; }).length;
+      count.textContent = selected ? selected + " selected" : summary;
+      all.checked = selected > 0 && selected === boxes.length;
+      all.indeterminate = selected > 0 && selected < boxes.length;
+      buttons.forEach(function (button) { button.disabled = selected === 0; });
+    }
+
+    all.addEventListener("change", function () {
+      boxes.forEach(function (box) { box.checked = all.checked; });
+      update();
+    });
+    boxes.forEach(function (box) { box.addEventListener("change", update); });
+    form.addEventListener("submit", function (event) {
+      const message = event.submitter && event.submitter.dataset.confirm;
+      if (message && !window.confirm(message)) {
+        event.preventDefault();
+      }
+    });
+    // Enter in the tags field adds the tags rather than pressing the first button
+    form.querySelector('input[name="tags"]').addEventListener("keydown", function (event) {
+      if (event.key === "Enter") {
+        event.preventDefault();
+        form.querySelector('button[value="tag"]').click();
+      }
+    });
+    update();
+  }
+
+  loginWithWebApp();
+  bulkSelection();
+})();
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/telegramauth"
+)
+
+// webClient browses the web UI with the session of user 1.
+type webClient struct {
+	handler http.Handler
+	cookie  *http.Cookie
+	csrf    string
+}
+
+func newWebClient(t *testing.T, api *testAPI) *webClient {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	sessions := NewSessions(api.repo, telegramauth.NewVerifier(testBotToken, time.Hour), SessionOptions{TTL: time.Hour, BotUsername: "jet_bot"}, logger)
+
+	session := domain.Session{UserID: 1, Name: "Ada", ExpiresAt: time.Now().Add(time.Hour)}
+	id, err := api.repo.CreateSession(context.Background(), &session)
+	require.NoError(t, err)
+	return &webClient{
+		handler: NewHandler(api.repo, &fakeScraper{}, api.queue, &StaticKeys{}, sessions, logger).Routes(),
+		cookie:  &http.Cookie{Name: sessionCookie, Value: id},
+		csrf:    session.CSRFToken,
+	}
+}
+
+func (c *webClient) get(path string, loggedIn bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if loggedIn {
+		req.AddCookie(c.cookie)
+	}
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func (c *webClient) bulk(form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/links/bulk", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(c.cookie)
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestWeb_Pages tests browsing, filtering and searching links in the web UI.
+func TestWeb_Pages(t *testing.T) {
+	api := newTestAPI(t)
+	web := newWebClient(t, api)
+	ctx := context.Background()
+	for _, link := range []domain.Link{
+		{URL: "https://go.dev/blog/pgo", Title: "Profile-guided optimization", Tags: []string{"go"}, PreviewImageURL: "https://go.dev/pgo.png"},
+		{URL: "https://www.example.com/cats", Title: "Cat pictures", Read: true},
+	} {
+		link.UserID = 1
+		require.NoError(t, api.repo.SaveLink(ctx, &link))
+	}
+
+	rec := web.get("/", false)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/login", rec.Header().Get("Location"))
+	rec = web.get("/login", false)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `data-telegram-login="jet_bot"`)
+
+	rec = web.get("/", true)
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "Profile-guided optimization")
+	assert.Contains(t, body, "Cat pictures")
+	assert.Contains(t, body, `src="https://go.dev/pgo.png"`)
+	assert.Contains(t, body, web.csrf, "Forms should carry the CSRF token")
+	assert.NotContains(t, body, "https://cdn", "Pages should not load assets from a CDN")
+	assert.NotEmpty(t, rec.Header().Get("Content-Security-Policy"))
+
+	filters := map[string]string{
+		"/?tag=go":                   "Profile-guided optimization",
+		"/?read=read":                "Cat pictures",
+		"/?domain=example.com":       "Cat pictures",
+		"/?q=optimization&view=grid": "Profile-guided optimization",
+	}
+	for path, want := range filters {
+		rec = web.get(path, true)
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Contains(t, rec.Body.String(), want, path)
+		other := "Cat pictures"
+		if want == other {
+			other = "Profile-guided optimization"
+		}
+		assert.NotContains(t, rec.Body.String(), other, path)
+	}
+	assert.Contains(t, web.get("/?view=grid", true).Body.String(), `class="links grid"`)
+
+	rec = web.get("/static/app.css", false)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/css")
+}
+
+// TestWeb_BulkActions tests changing several links at once from the web UI.
+func TestWeb_BulkActions(t *testing.T) {
+	api := newTestAPI(t)
+	web := newWebClient(t, api)
+	ctx := context.Background()
+	var ids []string
+	for _, u := range []string{"https://example.com/a", "https://example.com/b"} {
+		link := domain.Link{URL: u, UserID: 1}
+		require.NoError(t, api.repo.SaveLink(ctx, &link))
+		ids = append(ids, link.ID)
+	}
+
+	form := url.Values{"action": {"read"}, "id": ids, "return": {"/?read=unread"}}
+	assert.Equal(t, http.StatusForbidden, web.bulk(form).Code, "Bulk actions need the CSRF token")
+
+	form.Set("csrf_token", web.csrf)
+	rec := web.bulk(form)
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/?read=unread", rec.Header().Get("Location"))
+	form.Set("action", "tag")
+	form.Set("tags", "Reading, later")
+	form.Set("return", "//evil.example")
+	rec = web.bulk(form)
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/", rec.Header().Get("Location"), "Forms should only redirect within the site")
+	for _, id := range ids {
+		link, err := api.repo.GetLinkByID(ctx, 1, id)
+		require.NoError(t, err)
+		assert.True(t, link.Read)
+		assert.ElementsMatch(t, []string{"reading", "later"}, link.Tags)
+	}
+
+	form.Set("action", "delete")
+	form["id"] = ids[:1]
+	require.Equal(t, http.StatusSeeOther, web.bulk(form).Code)
+	_, err := api.repo.GetLinkByID(ctx, 1, ids[0])
+	assert.Error(t, err)
+	_, err = api.repo.GetLinkByID(ctx, 1, ids[1])
+	assert.NoError(t, err)
+
+	form.Set("action", "launch")
+	assert.Equal(t, http.StatusBadRequest, web.bulk(form).Code)
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+)
+
+// SaveArticle stores the offline copy of a link's article under its own key
+// and records its word count on the link, in one transaction.
+// It returns ErrNotFound if the link does not exist.
+func (r *BadgerRepository) SaveArticle(ctx context.Context, article *domain.Article) error {
+	log := r.log.WithFields(logrus.Fields{
+		"user_id": article.UserID,
+		"link_id": article.LinkID,
+	})
+
+	if article.ExtractedAt.IsZero() {
+		article.ExtractedAt = time.Now()
+	}
+
+	err := r.db.Update(func(txn *badger.Txn) error {
+		key, err := lookupLinkKey(txn, article.UserID, article.LinkID)
+		if err != nil {
+			return err
+		}
+		link, err := getLink(txn, key)
+		if err != nil {
+			return err
+		}
+
+		articleBytes, err := json.Marshal(article)
+		if err != nil {
+			return fmt.Errorf("failed to marshal article: %w", err)
+		}
+		if err := txn.Set(generateArticleKey(article.UserID, article.LinkID), articleBytes); err != nil {
+			return err
+		}
+
+		link.ArticleWords = article.WordCount
+		return putLink(txn, &link)
+	})
+
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.WithError(err).Error("Failed to save article to BadgerDB")
+		}
+		return fmt.Errorf("failed to save article for link %s: %w", article.LinkID, err)
+	}
+
+	log.WithField("word_count", article.WordCount).Info("Article saved successfully")
+	return nil
+}
+
+// GetArticle retrieves the offline copy of a link's article.
+// It returns ErrNotFound if no article was saved for the link.
+func (r *BadgerRepository) GetArticle(ctx context.Context, userID int64, linkID string) (domain.Article, error) {
+	var article domain.Article
+	err := r.db.View(func(txn *badger.Txn) error {
+		var err error
+		article, err = getArticle(txn, userID, linkID)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"user_id": userID,
+				"link_id": linkID,
+			}).Error("Failed to get article from BadgerDB")
+		}
+		return domain.Article{}, fmt.Errorf("failed to get article for link %s: %w", linkID, err)
+	}
+	return article, nil
+}
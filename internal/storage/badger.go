@@ -1,9 +1,14 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"sort"
 	"time"
 
@@ -11,6 +16,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	// Adjust the import path based on your go.mod file
+	"jetengine/internal/canonical"
 	"jetengine/internal/domain"
 )
 
@@ -39,6 +45,13 @@ func NewBadgerRepository(dbPath string, logger logrus.FieldLogger) (*BadgerRepos
 		log: logger.WithField("component", "repository"), // Add component field to repo logs
 	}
 
+	// Upgrade data written by older versions before serving requests
+	if err := repo.migrate(context.Background()); err != nil {
+		logger.WithError(err).Error("Failed to migrate BadgerDB")
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to migrate badger db at %s: %w", dbPath, err)
+	}
+
 	// Optional: Start garbage collection routine
 	// Consider making GC interval configurable
 	// go repo.runGC(ctx) // Need to manage context/cancellation for this goroutine
@@ -59,6 +72,7 @@ func (r *BadgerRepository) Close() error {
 }
 
 // generateLinkKey creates a unique key for storing a link.
+// linkURL is expected to be canonical, see canonicalURL.
 // Format: user:{userID}:link:{linkURL}
 func generateLinkKey(userID int64, linkURL string) []byte {
 	return []byte(fmt.Sprintf("user:%d:link:%s", userID, linkURL))
@@ -70,44 +84,437 @@ func generateUserPrefix(userID int64) []byte {
 	return []byte(fmt.Sprintf("user:%d:link:", userID))
 }
 
+// generateLinkIDKey creates the secondary index key mapping a link ID to its primary key.
+// Format: user:{userID}:id:{linkID}
+func generateLinkIDKey(userID int64, linkID string) []byte {
+	return []byte(fmt.Sprintf("user:%d:id:%s", userID, linkID))
+}
+
+// generateTimeIndexKey creates the secondary index key that orders a user's
+// links by the time they were saved, pointing to the primary key. The
+// timestamp is zero-padded Unix nanoseconds, so keys sort chronologically.
+// Format: user:{userID}:ts:{timestamp}:{linkID}
+func generateTimeIndexKey(link domain.Link) []byte {
+	return []byte(fmt.Sprintf("%s%020d:%s", generateTimeIndexPrefix(link.UserID), max(link.Timestamp.UnixNano(), 0), link.ID))
+}
+
+// generateTimeIndexPrefix creates the key prefix of a user's time index.
+// Format: user:{userID}:ts:
+func generateTimeIndexPrefix(userID int64) []byte {
+	return []byte(fmt.Sprintf("user:%d:ts:", userID))
+}
+
+// generateArticleKey creates the key of the offline article copy of a link.
+// Articles are kept apart from the link itself so listing links stays cheap.
+// Format: user:{userID}:article:{linkID}
+func generateArticleKey(userID int64, linkID string) []byte {
+	return []byte(fmt.Sprintf("user:%d:article:%s", userID, linkID))
+}
+
+// newLinkID derives a short, stable ID from a link URL.
+// The same URL always gets the same ID, so re-saving a link keeps its handle.
+func newLinkID(linkURL string) string {
+	sum := sha256.Sum256([]byte(linkURL))
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
 // SaveLink stores or updates a link in BadgerDB.
-func (r *BadgerRepository) SaveLink(ctx context.Context, link domain.Link) error {
+// Links are keyed by their canonical URL, so different spellings of the same
+// page overwrite each other. It assigns link.CanonicalURL, and link.ID and
+// link.Timestamp if they are not set yet.
+func (r *BadgerRepository) SaveLink(ctx context.Context, link *domain.Link) error {
 	log := r.log.WithFields(logrus.Fields{
 		"user_id": link.UserID,
 		"url":     link.URL,
 	})
 	log.Info("Attempting to save link")
 
-	// Ensure timestamp is set
+	// Ensure timestamp and canonical URL are set, and tags are in index form
 	if link.Timestamp.IsZero() {
 		link.Timestamp = time.Now()
 	}
+	link.CanonicalURL = canonicalURL(*link)
+	link.Tags = mergeTags(nil, link.Tags)
+
+	// Perform the save operation within a transaction
+	err := r.db.Update(func(txn *badger.Txn) error {
+		return putLink(txn, link)
+	})
+
+	if err != nil {
+		log.WithError(err).Error("Failed to save link to BadgerDB")
+		return fmt.Errorf("failed to save link: %w", err)
+	}
+
+	log.WithField("link_id", link.ID).Info("Link saved successfully")
+	return nil
+}
+
+// AddLink saves a link the user just sent. If the user saved the same page
+// before, the stored link is kept with all its state, link.Tags are merged
+// into its tags, and link is replaced by the stored link; the result is true
+// in that case.
+func (r *BadgerRepository) AddLink(ctx context.Context, link *domain.Link) (bool, error) {
+	log := r.log.WithFields(logrus.Fields{
+		"user_id": link.UserID,
+		"url":     link.URL,
+	})
+
+	if link.Timestamp.IsZero() {
+		link.Timestamp = time.Now()
+	}
+	link.CanonicalURL = canonicalURL(*link)
+	link.Tags = mergeTags(nil, link.Tags)
+
+	var existed bool
+	err := r.db.Update(func(txn *badger.Txn) error {
+		existing, err := getLink(txn, generateLinkKey(link.UserID, link.CanonicalURL))
+		if errors.Is(err, ErrNotFound) {
+			// A link first saved under this URL may have moved to the key of
+			// the page it redirects to
+			existing, err = movedLink(txn, link.UserID, link.CanonicalURL)
+		}
+		if errors.Is(err, ErrNotFound) {
+			return putLink(txn, link)
+		}
+		if err != nil {
+			return err
+		}
+		existed = true
+		tags := mergeTags(slices.Clone(existing.Tags), link.Tags)
+		// Links saved before chats were recorded learn theirs on the next save
+		learnChat := existing.ChatID == 0 && link.ChatID != 0
+		if len(tags) != len(existing.Tags) || learnChat {
+			existing.Tags = tags
+			if learnChat {
+				existing.ChatID = link.ChatID
+			}
+			if err := putLink(txn, &existing); err != nil {
+				return err
+			}
+		}
+		*link = existing
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to add link to BadgerDB")
+		return false, fmt.Errorf("failed to add link: %w", err)
+	}
+
+	log.WithFields(logrus.Fields{
+		"link_id": link.ID,
+		"existed": existed,
+	}).Info("Link added successfully")
+	return existed, nil
+}
+
+// UpdateLink stores changes to an existing link, identified by link.ID.
+// If its canonical URL changed, the link moves to the new key; when another
+// link of the user is stored there already, the two are merged like
+// duplicates found by migrations, the stored link keeps its ID and gains the
+// article and collections of the moved one, and link is replaced by the result.
+// It returns ErrNotFound if the link does not exist.
+func (r *BadgerRepository) UpdateLink(ctx context.Context, link *domain.Link) error {
+	log := r.log.WithFields(logrus.Fields{
+		"user_id": link.UserID,
+		"link_id": link.ID,
+	})
+
+	link.CanonicalURL = canonicalURL(*link)
+	err := r.db.Update(func(txn *badger.Txn) error {
+		oldKey, err := lookupLinkKey(txn, link.UserID, link.ID)
+		if err != nil {
+			return err
+		}
+		newKey := generateLinkKey(link.UserID, link.CanonicalURL)
+		if bytes.Equal(oldKey, newKey) {
+			return putLink(txn, link)
+		}
+
+		existing, err := getLink(txn, newKey)
+		switch {
+		case err == nil:
+			// The page was saved before under its final URL; keep that handle
+			old, err := getLink(txn, oldKey)
+			if err != nil {
+				return err
+			}
+			if err := unindexLink(txn, old); err != nil {
+				return err
+			}
+			if err := txn.Delete(oldKey); err != nil {
+				return err
+			}
+			words, err := moveArticle(txn, link.UserID, old.ID, existing.ID)
+			if err != nil {
+				return err
+			}
+			if err := recollectLink(txn, link.UserID, old.ID, existing.ID); err != nil {
+				return err
+			}
+			*link = mergeLinks(existing, *link)
+			link.ArticleWords = words
+		case errors.Is(err, ErrNotFound):
+			// The article is keyed by ID and moves along; putLink indexes the new key
+			old, err := getLink(txn, oldKey)
+			if err != nil {
+				return err
+			}
+			if err := unindexLink(txn, old); err != nil {
+				return err
+			}
+			if err := txn.Delete(oldKey); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+		return putLink(txn, link)
+	})
+
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.WithError(err).Error("Failed to update link in BadgerDB")
+		}
+		return fmt.Errorf("failed to update link %s: %w", link.ID, err)
+	}
+
+	log.WithField("canonical_url", link.CanonicalURL).Info("Link updated successfully")
+	return nil
+}
+
+// SetRead marks a link as read or unread and returns the updated link.
+// It returns ErrNotFound if the link does not exist.
+func (r *BadgerRepository) SetRead(ctx context.Context, userID int64, linkID string, read bool) (domain.Link, error) {
+	link, err := r.modifyLink(userID, linkID, func(link *domain.Link) { link.Read = read })
+	if err != nil {
+		return domain.Link{}, fmt.Errorf("failed to set read state of link %s: %w", linkID, err)
+	}
+	return link, nil
+}
+
+// SetPending marks whether the metadata of a link is being fetched and
+// returns the updated link. It returns ErrNotFound if the link does not exist.
+func (r *BadgerRepository) SetPending(ctx context.Context, userID int64, linkID string, pending bool) (domain.Link, error) {
+	link, err := r.modifyLink(userID, linkID, func(link *domain.Link) { link.Pending = pending })
+	if err != nil {
+		return domain.Link{}, fmt.Errorf("failed to set pending state of link %s: %w", linkID, err)
+	}
+	return link, nil
+}
+
+// modifyLink applies change to the stored link and writes it back in one
+// transaction, so fields written meanwhile by others, such as a scrape job,
+// are not overwritten with a stale copy. It returns the updated link.
+func (r *BadgerRepository) modifyLink(userID int64, linkID string, change func(link *domain.Link)) (domain.Link, error) {
+	var link domain.Link
+	err := r.db.Update(func(txn *badger.Txn) error {
+		key, err := lookupLinkKey(txn, userID, linkID)
+		if err != nil {
+			return err
+		}
+		link, err = getLink(txn, key)
+		if err != nil {
+			return err
+		}
+		change(&link)
+		return putLink(txn, &link)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"user_id": userID,
+				"link_id": linkID,
+			}).Error("Failed to modify link in BadgerDB")
+		}
+		return domain.Link{}, err
+	}
+	return link, nil
+}
+
+// movedLink returns the link that was first saved under canonical and has
+// since moved to the key of its resolved URL. Moved links keep the ID derived
+// from their first URL. It returns ErrNotFound if there is no such link.
+func movedLink(txn *badger.Txn, userID int64, canonical string) (domain.Link, error) {
+	key, err := lookupLinkKey(txn, userID, newLinkID(canonical))
+	if err != nil {
+		return domain.Link{}, err
+	}
+	link, err := getLink(txn, key)
+	if err != nil {
+		return domain.Link{}, err
+	}
+	if canonicalURL(domain.Link{URL: link.URL}) != canonical {
+		return domain.Link{}, ErrNotFound
+	}
+	return link, nil
+}
+
+// freeLinkID derives an ID for the link stored under key from its canonical
+// URL. If that ID belongs to another link already, for example one that moved
+// after a redirect, the URL is salted with a counter until the ID is unused.
+func freeLinkID(txn *badger.Txn, userID int64, canonical string, key []byte) (string, error) {
+	for n := 0; ; n++ {
+		id := newLinkID(canonical)
+		if n > 0 {
+			id = newLinkID(fmt.Sprintf("%s#%d", canonical, n))
+		}
+		owner, err := lookupLinkKey(txn, userID, id)
+		if errors.Is(err, ErrNotFound) || (err == nil && bytes.Equal(owner, key)) {
+			return id, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// putLink writes a link and its index entries under link.CanonicalURL.
+// A missing ID is taken from the link already stored under the same key, so
+// re-saving a page keeps its handle, or derived from the canonical URL.
+func putLink(txn *badger.Txn, link *domain.Link) error {
+	key := generateLinkKey(link.UserID, link.CanonicalURL)
+
+	existing, err := getLink(txn, key)
+	found := err == nil
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if link.ID == "" {
+		if found && existing.ID != "" {
+			link.ID = existing.ID
+		} else if link.ID, err = freeLinkID(txn, link.UserID, link.CanonicalURL, key); err != nil {
+			return err
+		}
+	}
+	idKey := generateLinkIDKey(link.UserID, link.ID)
+
+	// Refuse to point an existing ID at a different link
+	item, err := txn.Get(idKey)
+	if err == nil {
+		existing, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(existing, key) {
+			return fmt.Errorf("%w: %s", ErrIDConflict, link.ID)
+		}
+	} else if !errors.Is(err, badger.ErrKeyNotFound) {
+		return err
+	}
 
 	// Serialize the link struct to JSON bytes
 	linkBytes, err := json.Marshal(link)
 	if err != nil {
-		log.WithError(err).Error("Failed to marshal link to JSON")
 		return fmt.Errorf("failed to marshal link: %w", err)
 	}
 
-	// Generate the unique key for this link
-	key := generateLinkKey(link.UserID, link.URL)
+	// Set the key-value pair. This will overwrite if the key already exists.
+	// Consider adding TTL (Time To Live) if needed: e := badger.NewEntry(key, linkBytes).WithTTL(time.Hour)
+	e := badger.NewEntry(key, linkBytes)
+	if err := txn.SetEntry(e); err != nil {
+		return err
+	}
+	// The timestamp, tags and check time may have changed, so replace their index entries
+	if found && existing.ID != "" {
+		if err := txn.Delete(generateTimeIndexKey(existing)); err != nil {
+			return err
+		}
+		for _, tagKey := range tagKeys(existing) {
+			if err := txn.Delete(tagKey); err != nil {
+				return err
+			}
+		}
+		if err := txn.Delete(generateCheckKey(existing)); err != nil {
+			return err
+		}
+	}
+	if err := txn.Set(generateTimeIndexKey(*link), key); err != nil {
+		return err
+	}
+	if !link.Pending {
+		if err := txn.Set(generateCheckKey(*link), key); err != nil {
+			return err
+		}
+	}
+	for _, tagKey := range tagKeys(*link) {
+		if err := txn.Set(tagKey, key); err != nil {
+			return err
+		}
+	}
+	if err := indexLink(txn, *link); err != nil {
+		return err
+	}
+	return txn.Set(idKey, key)
+}
 
-	// Perform the save operation within a transaction
-	err = r.db.Update(func(txn *badger.Txn) error {
-		// Set the key-value pair. This will overwrite if the key already exists.
-		// Consider adding TTL (Time To Live) if needed: e := badger.NewEntry(key, linkBytes).WithTTL(time.Hour)
-		e := badger.NewEntry(key, linkBytes)
-		return txn.SetEntry(e)
+// canonicalURL returns the canonical form of the link's resolved URL (or URL),
+// falling back to the raw URL if it cannot be parsed.
+func canonicalURL(link domain.Link) string {
+	source := link.ResolvedURL
+	if source == "" {
+		source = link.URL
+	}
+	c, err := canonical.URL(source)
+	if err != nil {
+		return source
+	}
+	return c
+}
+
+// GetLinkByID retrieves a single link of a user by its short ID.
+// It returns ErrNotFound if the user has no link with that ID.
+func (r *BadgerRepository) GetLinkByID(ctx context.Context, userID int64, linkID string) (domain.Link, error) {
+	var link domain.Link
+	err := r.db.View(func(txn *badger.Txn) error {
+		key, err := lookupLinkKey(txn, userID, linkID)
+		if err != nil {
+			return err
+		}
+		link, err = getLink(txn, key)
+		return err
 	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"user_id": userID,
+				"link_id": linkID,
+			}).Error("Failed to get link by ID from BadgerDB")
+		}
+		return domain.Link{}, fmt.Errorf("failed to get link %s for user %d: %w", linkID, userID, err)
+	}
+	return link, nil
+}
 
+// lookupLinkKey resolves a link ID to the primary key of the link.
+func lookupLinkKey(txn *badger.Txn, userID int64, linkID string) ([]byte, error) {
+	item, err := txn.Get(generateLinkIDKey(userID, linkID))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
-		log.WithError(err).Error("Failed to save link to BadgerDB")
-		return fmt.Errorf("failed to save link: %w", err)
+		return nil, err
 	}
+	return item.ValueCopy(nil)
+}
 
-	log.Info("Link saved successfully")
-	return nil
+// getLink reads and decodes the link stored under a primary key.
+func getLink(txn *badger.Txn, key []byte) (domain.Link, error) {
+	var link domain.Link
+	item, err := txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return link, ErrNotFound
+	}
+	if err != nil {
+		return link, err
+	}
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &link)
+	})
+	if err != nil {
+		return link, fmt.Errorf("failed to unmarshal link data for key %s: %w", string(key), err)
+	}
+	return link, nil
 }
 
 // GetLinksByUser retrieves all links for a specific user.
@@ -171,19 +578,17 @@ func (r *BadgerRepository) DeleteLink(ctx context.Context, userID int64, linkURL
 	})
 	log.Info("Attempting to delete link")
 
-	key := generateLinkKey(userID, linkURL)
+	key := generateLinkKey(userID, canonicalURL(domain.Link{URL: linkURL}))
 
 	// Perform the delete operation within a transaction
 	err := r.db.Update(func(txn *badger.Txn) error {
-		// Check if the item exists before deleting (optional, Delete is idempotent)
-		// _, err := txn.Get(key)
-		// if err == badger.ErrKeyNotFound {
-		//  log.Warn("Attempted to delete non-existent link")
-		//  return nil // Or return a specific "not found" error if needed
-		// } else if err != nil {
-		//  return err // Propagate other errors
-		// }
-		return txn.Delete(key)
+		// Deleting a non-existent link is not an error
+		err := deleteLink(txn, key)
+		if errors.Is(err, ErrNotFound) {
+			log.Debug("Attempted to delete non-existent link")
+			return nil
+		}
+		return err
 	})
 
 	if err != nil {
@@ -195,6 +600,102 @@ func (r *BadgerRepository) DeleteLink(ctx context.Context, userID int64, linkURL
 	return nil
 }
 
+// DeleteLinkByID removes a link of a user by its short ID.
+// It returns ErrNotFound if the user has no link with that ID.
+func (r *BadgerRepository) DeleteLinkByID(ctx context.Context, userID int64, linkID string) error {
+	log := r.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"link_id": linkID,
+	})
+	log.Info("Attempting to delete link by ID")
+
+	err := r.db.Update(func(txn *badger.Txn) error {
+		key, err := lookupLinkKey(txn, userID, linkID)
+		if err != nil {
+			return err
+		}
+		return deleteLink(txn, key)
+	})
+
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.WithError(err).Error("Failed to delete link from BadgerDB")
+		}
+		return fmt.Errorf("failed to delete link %s for user %d: %w", linkID, userID, err)
+	}
+
+	log.Info("Link deleted successfully")
+	return nil
+}
+
+// deleteLink removes the link stored under a primary key together with its
+// index entries, article and collection memberships.
+func deleteLink(txn *badger.Txn, key []byte) error {
+	link, err := getLink(txn, key)
+	if err != nil {
+		return err
+	}
+	if link.ID != "" {
+		if err := unindexLink(txn, link); err != nil {
+			return err
+		}
+		if err := txn.Delete(generateArticleKey(link.UserID, link.ID)); err != nil {
+			return err
+		}
+		if err := uncollectLink(txn, link.UserID, link.ID); err != nil {
+			return err
+		}
+	}
+	return txn.Delete(key)
+}
+
+// moveArticle moves the article of a link that merged into another link of
+// the same user to the surviving link. The moved article is the newer copy
+// and replaces the survivor's. It returns the word count of the article the
+// survivor ends up with, or zero if it has none.
+func moveArticle(txn *badger.Txn, userID int64, fromID, toID string) (int, error) {
+	article, err := getArticle(txn, userID, fromID)
+	if errors.Is(err, ErrNotFound) {
+		article, err = getArticle(txn, userID, toID)
+		if errors.Is(err, ErrNotFound) {
+			return 0, nil
+		}
+		return article.WordCount, err
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	article.LinkID = toID
+	articleBytes, err := json.Marshal(article)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal article: %w", err)
+	}
+	if err := txn.Set(generateArticleKey(userID, toID), articleBytes); err != nil {
+		return 0, err
+	}
+	return article.WordCount, txn.Delete(generateArticleKey(userID, fromID))
+}
+
+// unindexLink removes the ID, time, check, tag and search index entries of a stored link.
+func unindexLink(txn *badger.Txn, link domain.Link) error {
+	if err := txn.Delete(generateLinkIDKey(link.UserID, link.ID)); err != nil {
+		return err
+	}
+	if err := txn.Delete(generateTimeIndexKey(link)); err != nil {
+		return err
+	}
+	if err := txn.Delete(generateCheckKey(link)); err != nil {
+		return err
+	}
+	for _, tagKey := range tagKeys(link) {
+		if err := txn.Delete(tagKey); err != nil {
+			return err
+		}
+	}
+	return unindexSearch(txn, link.UserID, link.ID)
+}
+
 // --- BadgerDB Internal Logger ---
 
 // badgerLogger adapts logrus.FieldLogger to Badger's logger interface.
@@ -2,16 +2,22 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/dgraph-io/badger/v4"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	// Adjust the import path based on your go.mod file
 	"jetengine/internal/domain"
+	"jetengine/internal/search"
 )
 
 // setupTestDB creates a temporary BadgerDB instance for testing.
@@ -77,11 +83,11 @@ func TestBadgerRepository_SaveAndGetLinks(t *testing.T) {
 	}
 
 	// --- Test SaveLink ---
-	err := repo.SaveLink(ctx, link1)
+	err := repo.SaveLink(ctx, &link1)
 	require.NoError(t, err, "Failed to save link1")
-	err = repo.SaveLink(ctx, link2)
+	err = repo.SaveLink(ctx, &link2)
 	require.NoError(t, err, "Failed to save link2")
-	err = repo.SaveLink(ctx, link3)
+	err = repo.SaveLink(ctx, &link3)
 	require.NoError(t, err, "Failed to save link3")
 
 	// --- Test GetLinksByUser for userID1 ---
@@ -115,7 +121,7 @@ func TestBadgerRepository_SaveAndGetLinks(t *testing.T) {
 		UserID:      userID1,
 		Timestamp:   time.Now().Add(time.Minute), // Make it newest
 	}
-	err = repo.SaveLink(ctx, updatedLink1)
+	err = repo.SaveLink(ctx, &updatedLink1)
 	require.NoError(t, err, "Failed to update link1")
 
 	linksUser1AfterUpdate, err := repo.GetLinksByUser(ctx, userID1)
@@ -142,9 +148,9 @@ func TestBadgerRepository_DeleteLink(t *testing.T) {
 	linkToKeep := domain.Link{URL: linkURLToKeep, Title: "Keep Me", UserID: userID}
 
 	// Save both links
-	err := repo.SaveLink(ctx, linkToDelete)
+	err := repo.SaveLink(ctx, &linkToDelete)
 	require.NoError(t, err)
-	err = repo.SaveLink(ctx, linkToKeep)
+	err = repo.SaveLink(ctx, &linkToKeep)
 	require.NoError(t, err)
 
 	// Verify both exist initially
@@ -181,5 +187,1053 @@ func TestBadgerRepository_DeleteLink(t *testing.T) {
 	require.Len(t, linksAfterDeleteAgain, 1, "Link count should still be 1 after deleting again")
 }
 
+// TestBadgerRepository_LinkIDs tests ID assignment, lookup and deletion by ID.
+func TestBadgerRepository_LinkIDs(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID := int64(321)
+
+	link := domain.Link{URL: "https://example.com/" + strings.Repeat("very-long-path/", 50), Title: "Long", UserID: userID}
+	err := repo.SaveLink(ctx, &link)
+	require.NoError(t, err)
+	require.NotEmpty(t, link.ID, "SaveLink should assign an ID")
+	assert.LessOrEqual(t, len(link.ID), 16, "IDs should be short")
+	assert.False(t, link.Timestamp.IsZero(), "SaveLink should assign a timestamp")
+
+	// --- Re-saving the same URL keeps the ID ---
+	again := domain.Link{URL: link.URL, Title: "Long again", UserID: userID}
+	err = repo.SaveLink(ctx, &again)
+	require.NoError(t, err)
+	assert.Equal(t, link.ID, again.ID)
+
+	// --- Test GetLinkByID ---
+	got, err := repo.GetLinkByID(ctx, userID, link.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Long again", got.Title)
+
+	_, err = repo.GetLinkByID(ctx, int64(999), link.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "IDs are scoped to their user")
+
+	// --- Test DeleteLinkByID ---
+	err = repo.DeleteLinkByID(ctx, userID, link.ID)
+	require.NoError(t, err)
+	_, err = repo.GetLinkByID(ctx, userID, link.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	err = repo.DeleteLinkByID(ctx, userID, link.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "Deleting an unknown ID should report ErrNotFound")
+
+	links, err := repo.GetLinksByUser(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
+
+// TestBadgerRepository_CanonicalURLs tests that URL variants of the same page are stored once.
+func TestBadgerRepository_CanonicalURLs(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID := int64(555)
+
+	first := domain.Link{URL: "https://example.com/a", Title: "First", UserID: userID}
+	require.NoError(t, repo.SaveLink(ctx, &first))
+	second := domain.Link{URL: "https://Example.com/a?utm_source=x#frag", Title: "Second", UserID: userID}
+	require.NoError(t, repo.SaveLink(ctx, &second))
+
+	assert.Equal(t, "https://example.com/a", second.CanonicalURL)
+	assert.Equal(t, first.ID, second.ID, "Variants of the same URL should share an ID")
+
+	links, err := repo.GetLinksByUser(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "https://Example.com/a?utm_source=x#frag", links[0].URL, "The original URL should be kept")
+
+	// --- A recorded redirect target is used for de-duplication ---
+	redirected := domain.Link{URL: "https://short.example/xyz", ResolvedURL: "https://example.com/a", UserID: userID}
+	require.NoError(t, repo.SaveLink(ctx, &redirected))
+	links, err = repo.GetLinksByUser(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+
+	// --- Deleting by any variant removes the link ---
+	require.NoError(t, repo.DeleteLink(ctx, userID, "https://EXAMPLE.com:443/a?fbclid=1"))
+	links, err = repo.GetLinksByUser(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
+
+// TestBadgerRepository_AddLink tests that saving a URL again keeps the stored link.
+func TestBadgerRepository_AddLink(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID := int64(556)
+
+	first := domain.Link{URL: "https://example.com/a", UserID: userID, Tags: []string{"go"}, Pending: true}
+	existed, err := repo.AddLink(ctx, &first)
+	require.NoError(t, err)
+	assert.False(t, existed)
+
+	first.Title = "Article"
+	first.Pending = false
+	first.Read = true
+	require.NoError(t, repo.UpdateLink(ctx, &first))
+
+	again := domain.Link{URL: "https://example.com/a?utm_source=x", UserID: userID, Tags: []string{"Reading"}, Pending: true}
+	existed, err = repo.AddLink(ctx, &again)
+	require.NoError(t, err)
+	assert.True(t, existed)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Equal(t, "Article", again.Title, "The stored link should be returned")
+
+	got, err := repo.GetLinkByID(ctx, userID, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "reading"}, got.Tags)
+	assert.True(t, got.Read)
+	assert.False(t, got.Pending)
+	assert.Equal(t, "https://example.com/a", got.URL)
+	assert.True(t, got.Timestamp.Equal(first.Timestamp), "The link should keep its place in the list")
+	assert.Zero(t, got.ChatID)
+
+	// The chat of the first save that recorded one is kept
+	for _, chatID := range []int64{-100123, 556} {
+		resaved := domain.Link{URL: "https://example.com/a", UserID: userID, ChatID: chatID}
+		_, err = repo.AddLink(ctx, &resaved)
+		require.NoError(t, err)
+		assert.Equal(t, int64(-100123), resaved.ChatID)
+	}
+
+	// A short link that moved to the page it redirects to is found again by its first URL
+	short := domain.Link{URL: "https://t.co/abc", UserID: userID, Pending: true}
+	_, err = repo.AddLink(ctx, &short)
+	require.NoError(t, err)
+	short.ResolvedURL = "https://example.com/story"
+	short.Pending = false
+	require.NoError(t, repo.UpdateLink(ctx, &short))
+	resent := domain.Link{URL: "https://t.co/abc", UserID: userID, Tags: []string{"news"}, Pending: true}
+	existed, err = repo.AddLink(ctx, &resent)
+	require.NoError(t, err)
+	assert.True(t, existed)
+	assert.Equal(t, short.ID, resent.ID)
+	assert.Equal(t, "https://example.com/story", resent.CanonicalURL)
+	assert.Equal(t, []string{"news"}, resent.Tags)
+
+	// An ID taken by an unrelated link is not reused
+	taken := domain.Link{ID: newLinkID("https://example.com/b"), URL: "https://example.com/c", UserID: userID}
+	require.NoError(t, repo.SaveLink(ctx, &taken))
+	other := domain.Link{URL: "https://example.com/b", UserID: userID}
+	existed, err = repo.AddLink(ctx, &other)
+	require.NoError(t, err)
+	assert.False(t, existed)
+	assert.NotEqual(t, taken.ID, other.ID)
+}
+
+// TestBadgerRepository_Migrations tests that legacy links get IDs and canonical keys on open.
+func TestBadgerRepository_Migrations(t *testing.T) {
+	dir := t.TempDir()
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// Write legacy entries directly, bypassing SaveLink
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	require.NoError(t, err)
+	legacy := []domain.Link{
+		{URL: "https://example.com/legacy", Title: "Legacy", UserID: 42, Timestamp: time.Now().Add(-time.Hour), Tags: []string{"old"}},
+		{URL: "https://EXAMPLE.com/legacy#top", Title: "Legacy dup", UserID: 42, Timestamp: time.Now(), Tags: []string{"new"}, Read: true},
+		{URL: "https://example.org/other?utm_medium=mail", Title: "Other", UserID: 42, Timestamp: time.Now()},
+	}
+	err = db.Update(func(txn *badger.Txn) error {
+		for _, link := range legacy {
+			value, err := json.Marshal(link)
+			if err != nil {
+				return err
+			}
+			if err := txn.Set(generateLinkKey(link.UserID, link.URL), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	repo, err := NewBadgerRepository(dir, logger)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	links, err := repo.GetLinksByUser(context.Background(), 42)
+	require.NoError(t, err)
+	require.Len(t, links, 2, "Duplicates should be merged")
+
+	byCanonical := make(map[string]domain.Link)
+	for _, link := range links {
+		require.NotEmpty(t, link.ID)
+		got, err := repo.GetLinkByID(context.Background(), 42, link.ID)
+		require.NoError(t, err)
+		assert.Equal(t, link.URL, got.URL)
+		byCanonical[link.CanonicalURL] = link
+	}
+
+	merged := byCanonical["https://example.com/legacy"]
+	assert.Equal(t, "Legacy dup", merged.Title, "The newer duplicate should win")
+	assert.True(t, merged.Read)
+	assert.ElementsMatch(t, []string{"old", "new"}, merged.Tags)
+	assert.Contains(t, byCanonical, "https://example.org/other")
+
+	page, err := repo.ListLinks(context.Background(), 42, ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 2, "Migrated links should be in the time index")
+	assert.Equal(t, "https://example.org/other?utm_medium=mail", page.Links[0].URL)
+
+	found, err := repo.SearchLinks(context.Background(), 42, search.ParseQuery("legacy dup"), 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, found.Total, "Migrated links should be searchable")
+
+	due, err := repo.LinksToCheck(context.Background(), time.Now().Add(time.Minute), 0)
+	require.NoError(t, err)
+	assert.Len(t, due, 2, "Migrated links should be in the check index")
+}
+
 // Add more tests as needed, e.g., for error conditions like marshalling failures
 // or concurrent access if that becomes relevant.
+
+// TestBadgerRepository_Articles tests storing offline article copies next to links.
+func TestBadgerRepository_Articles(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID := int64(654)
+
+	link := domain.Link{URL: "https://example.com/post", Title: "Post", UserID: userID}
+	require.NoError(t, repo.SaveLink(ctx, &link))
+
+	// --- Articles need an existing link ---
+	err := repo.SaveArticle(ctx, &domain.Article{UserID: userID, LinkID: "missing", Text: "x", WordCount: 1})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = repo.GetArticle(ctx, userID, link.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// --- Save and get ---
+	article := domain.Article{UserID: userID, LinkID: link.ID, HTML: "<p>Hello world</p>", Text: "Hello world", WordCount: 2}
+	require.NoError(t, repo.SaveArticle(ctx, &article))
+	assert.False(t, article.ExtractedAt.IsZero(), "SaveArticle should assign an extraction time")
+
+	got, err := repo.GetArticle(ctx, userID, link.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world", got.Text)
+	assert.Equal(t, "<p>Hello world</p>", got.HTML)
+
+	// The word count is recorded on the link, but the content is not
+	links, err := repo.GetLinksByUser(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, 2, links[0].ArticleWords)
+
+	_, err = repo.GetArticle(ctx, int64(999), link.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "Articles are scoped to their user")
+
+	// --- Deleting the link deletes the article ---
+	require.NoError(t, repo.DeleteLinkByID(ctx, userID, link.ID))
+	_, err = repo.GetArticle(ctx, userID, link.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// TestBadgerRepository_AddSnapshot tests recording snapshots on links.
+func TestBadgerRepository_AddSnapshot(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID := int64(987)
+
+	link := domain.Link{URL: "https://example.com/snap", UserID: userID}
+	require.NoError(t, repo.SaveLink(ctx, &link))
+
+	_, err := repo.AddSnapshot(ctx, userID, "missing", domain.Snapshot{Kind: domain.SnapshotPDF})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = repo.AddSnapshot(ctx, userID, link.ID, domain.Snapshot{Kind: domain.SnapshotPDF, Digest: "old"})
+	require.NoError(t, err)
+	_, err = repo.AddSnapshot(ctx, userID, link.ID, domain.Snapshot{Kind: domain.SnapshotScreenshot, Digest: "png"})
+	require.NoError(t, err)
+	updated, err := repo.AddSnapshot(ctx, userID, link.ID, domain.Snapshot{Kind: domain.SnapshotPDF, Digest: "new"})
+	require.NoError(t, err)
+	assert.Len(t, updated.Snapshots, 2, "A new snapshot should replace the old one of the same kind")
+
+	got, err := repo.GetLinkByID(ctx, userID, link.ID)
+	require.NoError(t, err)
+	pdf, ok := got.Snapshot(domain.SnapshotPDF)
+	require.True(t, ok)
+	assert.Equal(t, "new", pdf.Digest)
+	png, ok := got.Snapshot(domain.SnapshotScreenshot)
+	require.True(t, ok)
+	assert.Equal(t, "png", png.Digest)
+	_, ok = got.Snapshot(domain.SnapshotMHTML)
+	assert.False(t, ok)
+
+	digests, err := repo.SnapshotDigests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"new": true, "png": true}, digests, "Replaced snapshots should no longer be referenced")
+	require.NoError(t, repo.DeleteLinkByID(ctx, userID, link.ID))
+	digests, err = repo.SnapshotDigests(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, digests)
+}
+
+// TestBadgerRepository_UpdateLink tests updating a link that moves to a new canonical URL.
+func TestBadgerRepository_UpdateLink(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID := int64(654)
+
+	// --- A placeholder follows a redirect to a new URL ---
+	link := domain.Link{URL: "https://short.example/abc", UserID: userID, Pending: true}
+	require.NoError(t, repo.SaveLink(ctx, &link))
+	id := link.ID
+
+	link.ResolvedURL = "https://example.com/article"
+	link.Title = "Article"
+	link.Pending = false
+	require.NoError(t, repo.UpdateLink(ctx, &link))
+	assert.Equal(t, id, link.ID, "A moved link should keep its ID")
+
+	links, err := repo.GetLinksByUser(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, links, 1, "The old key should be removed")
+	assert.Equal(t, "Article", links[0].Title)
+	got, err := repo.GetLinkByID(ctx, userID, id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/article", got.CanonicalURL)
+
+	// --- The new URL was saved before, so the placeholder merges into it ---
+	got.Tags = []string{"go"}
+	got.Read = true
+	require.NoError(t, repo.UpdateLink(ctx, &got))
+
+	other := domain.Link{URL: "https://short.example/xyz", UserID: userID, Tags: []string{"later"}, Pending: true}
+	require.NoError(t, repo.SaveLink(ctx, &other))
+	otherID := other.ID
+	require.NoError(t, repo.SaveArticle(ctx, &domain.Article{LinkID: otherID, UserID: userID, Text: "Merged article body", WordCount: 3}))
+	reading := domain.Collection{OwnerID: userID, Name: "Reading"}
+	require.NoError(t, repo.CreateCollection(ctx, &reading))
+	_, err = repo.AddToCollection(ctx, userID, reading.ID, otherID)
+	require.NoError(t, err)
+
+	other, err = repo.GetLinkByID(ctx, userID, otherID)
+	require.NoError(t, err)
+	other.ResolvedURL = "https://example.com/article"
+	other.Title = "Article, again"
+	require.NoError(t, repo.UpdateLink(ctx, &other))
+	assert.Equal(t, id, other.ID, "The existing link should keep its ID")
+
+	links, err = repo.GetLinksByUser(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "Article, again", links[0].Title)
+	assert.ElementsMatch(t, []string{"go", "later"}, links[0].Tags, "Tags of both links should be kept")
+	assert.True(t, links[0].Read, "The link should stay read")
+	assert.Equal(t, 3, links[0].ArticleWords)
+	_, err = repo.GetLinkByID(ctx, userID, otherID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	article, err := repo.GetArticle(ctx, userID, id)
+	require.NoError(t, err, "The article should move to the surviving link")
+	assert.Equal(t, id, article.LinkID)
+	_, err = repo.GetArticle(ctx, userID, otherID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	page, err := repo.ListCollectionLinks(ctx, userID, reading.ID, ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1, "Collections should hold the surviving link")
+	assert.Equal(t, id, page.Links[0].ID)
+	reading, err = repo.GetCollection(ctx, userID, reading.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reading.LinkCount)
+
+	result, err := repo.SearchLinks(ctx, userID, search.ParseQuery("merged"), 0, 10)
+	require.NoError(t, err)
+	require.Len(t, result.Links, 1, "The moved article should be searchable")
+	assert.Equal(t, id, result.Links[0].ID)
+
+	missing := domain.Link{ID: "missing", URL: "https://example.com/missing", UserID: userID}
+	assert.ErrorIs(t, repo.UpdateLink(ctx, &missing), ErrNotFound)
+}
+
+// TestBadgerRepository_SetFields tests that changing the read state, pending
+// state or tags keeps fields written since the link was read.
+func TestBadgerRepository_SetFields(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID := int64(655)
+
+	link := domain.Link{URL: "https://example.com/a", UserID: userID, Tags: []string{"old"}, Pending: true}
+	_, err := repo.AddLink(ctx, &link)
+	require.NoError(t, err)
+	stale, err := repo.GetLinkByID(ctx, userID, link.ID)
+	require.NoError(t, err)
+
+	// A scrape finishes after the stale copy was read
+	link.Title = "Scraped"
+	link.Pending = false
+	require.NoError(t, repo.UpdateLink(ctx, &link))
+
+	got, err := repo.SetRead(ctx, userID, stale.ID, !stale.Read)
+	require.NoError(t, err)
+	assert.True(t, got.Read)
+	assert.Equal(t, "Scraped", got.Title)
+	assert.False(t, got.Pending)
+
+	got, err = repo.SetTags(ctx, userID, stale.ID, []string{"#New", "new", "go"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new", "go"}, got.Tags)
+	assert.True(t, got.Read)
+	page, err := repo.ListLinks(ctx, userID, ListOptions{Tag: "old"})
+	require.NoError(t, err)
+	assert.Empty(t, page.Links, "Replaced tags should leave the tag index")
+
+	got, err = repo.SetPending(ctx, userID, stale.ID, true)
+	require.NoError(t, err)
+	assert.True(t, got.Pending)
+	assert.Equal(t, "Scraped", got.Title)
+
+	_, err = repo.SetRead(ctx, userID, "missing", true)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// TestBadgerRepository_Jobs tests claiming, rescheduling and burying queued jobs.
+func TestBadgerRepository_Jobs(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	later := domain.ScrapeJob{URL: "https://example.com/later", RunAt: now.Add(time.Hour)}
+	require.NoError(t, repo.EnqueueJob(ctx, &later))
+	first := domain.ScrapeJob{URL: "https://example.com/first"}
+	require.NoError(t, repo.EnqueueJob(ctx, &first))
+	assert.NotEmpty(t, first.ID)
+	assert.Equal(t, domain.JobPending, first.Status)
+
+	// --- Only due jobs are claimed, earliest first ---
+	job, err := repo.ClaimJob(ctx, now.Add(time.Second), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, job.ID)
+	assert.Equal(t, domain.JobRunning, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	_, err = repo.ClaimJob(ctx, now.Add(time.Second), time.Minute)
+	assert.ErrorIs(t, err, ErrNoJob, "A running job should not be claimed again during its lease")
+
+	// --- An abandoned job is claimed again once its lease expired ---
+	job, err = repo.ClaimJob(ctx, now.Add(2*time.Minute), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, job.ID)
+	assert.Equal(t, 2, job.Attempts)
+
+	// --- Dead jobs are kept but never claimed ---
+	job.Status = domain.JobDead
+	job.LastError = "boom"
+	require.NoError(t, repo.UpdateJob(ctx, &job))
+	dead, err := repo.DeadJobs(ctx)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "boom", dead[0].LastError)
+
+	job, err = repo.ClaimJob(ctx, now.Add(2*time.Hour), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, later.ID, job.ID)
+
+	require.NoError(t, repo.DeleteJob(ctx, later.ID))
+	require.NoError(t, repo.DeleteJob(ctx, later.ID), "Deleting a missing job should not fail")
+	_, err = repo.ClaimJob(ctx, now.Add(3*time.Hour), time.Minute)
+	assert.ErrorIs(t, err, ErrNoJob)
+}
+
+// TestBadgerRepository_Cache tests storing and expiring shared cache entries.
+func TestBadgerRepository_Cache(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	_, err := repo.GetCache(ctx, "meta:https://example.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, repo.SetCache(ctx, "meta:https://example.com", []byte("cached"), time.Hour))
+	value, err := repo.GetCache(ctx, "meta:https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "cached", string(value))
+
+	// Badger expires entries with a resolution of one second
+	require.NoError(t, repo.SetCache(ctx, "meta:https://example.org", []byte("short"), time.Second))
+	time.Sleep(1100 * time.Millisecond)
+	_, err = repo.GetCache(ctx, "meta:https://example.org")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// TestBadgerRepository_LinksToCheck tests selecting the least recently checked links of all users.
+func TestBadgerRepository_LinksToCheck(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	links := []domain.Link{
+		{URL: "https://example.com/checked", UserID: 1, Timestamp: now.Add(-10 * time.Hour), LastChecked: now.Add(-2 * time.Hour)},
+		{URL: "https://example.com/never", UserID: 2, Timestamp: now.Add(-5 * time.Hour)},
+		{URL: "https://example.com/fresh", UserID: 1, Timestamp: now.Add(-10 * time.Hour), LastChecked: now},
+		{URL: "https://example.com/pending", UserID: 2, Timestamp: now.Add(-10 * time.Hour), Pending: true},
+	}
+	for i := range links {
+		require.NoError(t, repo.SaveLink(ctx, &links[i]))
+	}
+
+	due, err := repo.LinksToCheck(ctx, now.Add(-time.Hour), 0)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, "https://example.com/never", due[0].URL, "Links never checked count from when they were saved")
+	assert.Equal(t, "https://example.com/checked", due[1].URL)
+
+	due, err = repo.LinksToCheck(ctx, now.Add(-time.Hour), 1)
+	require.NoError(t, err)
+	assert.Len(t, due, 1)
+
+	// --- The index follows checks and deletes ---
+	checked := due[0]
+	checked.LastChecked = now
+	require.NoError(t, repo.UpdateLink(ctx, &checked))
+	require.NoError(t, repo.DeleteLinkByID(ctx, 1, links[0].ID))
+	due, err = repo.LinksToCheck(ctx, now.Add(-time.Hour), 0)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+// TestBadgerRepository_ListLinks tests filtering, ordering and paging links.
+func TestBadgerRepository_ListLinks(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	links := []domain.Link{
+		{URL: "https://example.com/1", UserID: 1, Timestamp: base, Tags: []string{"go"}},
+		{URL: "https://blog.example.com/2", UserID: 1, Timestamp: base.Add(time.Hour), Read: true},
+		{URL: "https://www.example.org/3", UserID: 1, Timestamp: base.Add(2 * time.Hour), Tags: []string{"Go"}},
+		{URL: "https://notexample.com/4", UserID: 1, Timestamp: base.Add(3 * time.Hour)},
+		{URL: "https://example.com/5", UserID: 1, Timestamp: base.Add(4 * time.Hour), Read: true, Tags: []string{"go"}},
+		{URL: "https://example.com/other-user", UserID: 2, Timestamp: base.Add(5 * time.Hour)},
+	}
+	for i := range links {
+		require.NoError(t, repo.SaveLink(ctx, &links[i]))
+	}
+
+	urls := func(page LinkPage) []string {
+		var out []string
+		for _, link := range page.Links {
+			out = append(out, link.URL)
+		}
+		return out
+	}
+	list := func(opts ListOptions) LinkPage {
+		t.Helper()
+		page, err := repo.ListLinks(ctx, 1, opts)
+		require.NoError(t, err)
+		return page
+	}
+
+	// --- Paging newest first visits every link once ---
+	page := list(ListOptions{Limit: 2})
+	assert.Equal(t, []string{"https://example.com/5", "https://notexample.com/4"}, urls(page))
+	require.NotEmpty(t, page.NextCursor)
+	page = list(ListOptions{Limit: 2, Cursor: page.NextCursor})
+	assert.Equal(t, []string{"https://www.example.org/3", "https://blog.example.com/2"}, urls(page))
+	page = list(ListOptions{Limit: 2, Cursor: page.NextCursor})
+	assert.Equal(t, []string{"https://example.com/1"}, urls(page))
+	assert.Empty(t, page.NextCursor, "The last page should have no cursor")
+
+	// --- Paging back visits the same pages ---
+	assert.Empty(t, list(ListOptions{Limit: 2}).PrevCursor, "The first page should have no previous cursor")
+	require.NotEmpty(t, page.PrevCursor)
+	page = list(ListOptions{Limit: 2, Cursor: page.PrevCursor})
+	assert.Equal(t, []string{"https://www.example.org/3", "https://blog.example.com/2"}, urls(page))
+	assert.NotEmpty(t, page.NextCursor)
+	page = list(ListOptions{Limit: 2, Cursor: page.PrevCursor})
+	assert.Equal(t, []string{"https://example.com/5", "https://notexample.com/4"}, urls(page))
+	assert.Empty(t, page.PrevCursor)
+
+	// --- An exactly full last page has no cursor either ---
+	page = list(ListOptions{Limit: 5})
+	assert.Len(t, page.Links, 5)
+	assert.Empty(t, page.NextCursor)
+
+	// --- Oldest first with a date range ---
+	page = list(ListOptions{Order: OldestFirst, Since: base.Add(time.Hour), Until: base.Add(4 * time.Hour)})
+	assert.Equal(t, []string{"https://blog.example.com/2", "https://www.example.org/3", "https://notexample.com/4"}, urls(page))
+	page = list(ListOptions{Since: base.Add(time.Hour), Until: base.Add(4 * time.Hour), Limit: 1})
+	assert.Equal(t, []string{"https://notexample.com/4"}, urls(page))
+	page = list(ListOptions{Since: base.Add(time.Hour), Until: base.Add(4 * time.Hour), Limit: 1, Cursor: page.NextCursor})
+	assert.Equal(t, []string{"https://www.example.org/3"}, urls(page))
+
+	// --- Filters ---
+	assert.Equal(t, []string{"https://notexample.com/4", "https://www.example.org/3", "https://example.com/1"},
+		urls(list(ListOptions{Read: OnlyUnread})))
+	assert.Equal(t, []string{"https://example.com/5", "https://blog.example.com/2"}, urls(list(ListOptions{Read: OnlyRead})))
+	assert.Equal(t, []string{"https://example.com/5", "https://www.example.org/3", "https://example.com/1"},
+		urls(list(ListOptions{Tag: "go"})), "Tags should match regardless of case")
+	assert.Equal(t, []string{"https://example.com/5", "https://blog.example.com/2", "https://example.com/1"},
+		urls(list(ListOptions{Domain: "www.Example.com"})), "Subdomains should match, other hosts should not")
+	assert.Equal(t, []string{"https://example.com/1"}, urls(list(ListOptions{Tag: "go", Read: OnlyUnread, Domain: "example.com"})))
+
+	// --- Index entries follow updates and deletes ---
+	moved := links[0]
+	moved.Timestamp = base.Add(10 * time.Hour)
+	moved.URL = "https://example.com/1?moved"
+	require.NoError(t, repo.UpdateLink(ctx, &moved))
+	require.NoError(t, repo.DeleteLinkByID(ctx, 1, links[3].ID))
+	page = list(ListOptions{})
+	assert.Equal(t, []string{"https://example.com/1?moved", "https://example.com/5", "https://www.example.org/3", "https://blog.example.com/2"}, urls(page))
+
+	_, err := repo.ListLinks(ctx, 1, ListOptions{Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+// TestBadgerRepository_SearchLinks tests ranking, operators and index updates.
+func TestBadgerRepository_SearchLinks(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+
+	links := []domain.Link{
+		{URL: "https://go.dev/blog/generics", Title: "Go generics tutorial", UserID: 1, Timestamp: base, Tags: []string{"go"}},
+		{URL: "https://example.com/cooking", Title: "Pasta recipes", Description: "Cooking with generics of pasta", UserID: 1, Timestamp: base.Add(time.Minute)},
+		{URL: "https://blog.example.com/rust", Title: "Rust for Go developers", UserID: 1, Timestamp: base.Add(2 * time.Minute), Tags: []string{"rust"}},
+		{URL: "https://go.dev/other-user", Title: "Go generics", UserID: 2, Timestamp: base},
+	}
+	for i := range links {
+		require.NoError(t, repo.SaveLink(ctx, &links[i]))
+	}
+
+	urls := func(q string) []string {
+		t.Helper()
+		res, err := repo.SearchLinks(ctx, 1, search.ParseQuery(q), 0, 10)
+		require.NoError(t, err)
+		var out []string
+		for _, link := range res.Links {
+			out = append(out, link.URL)
+		}
+		return out
+	}
+
+	// A title match outranks a description match
+	assert.Equal(t, []string{"https://go.dev/blog/generics", "https://example.com/cooking"}, urls("generics"))
+	assert.Equal(t, []string{"https://go.dev/blog/generics"}, urls("GO generics"), "All terms should match")
+	assert.Empty(t, urls("generics python"))
+	assert.Empty(t, urls("go tag:go tag:rust"), "All tags should match")
+	assert.Equal(t, []string{"https://go.dev/blog/generics"}, urls("go tag:go"))
+	assert.Equal(t, []string{"https://blog.example.com/rust", "https://example.com/cooking"}, urls("site:example.com"))
+	assert.Equal(t, []string{"https://blog.example.com/rust"}, urls("developers site:example.com"))
+
+	// Paging keeps the total
+	res, err := repo.SearchLinks(ctx, 1, search.ParseQuery("generics"), 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Total)
+	require.Len(t, res.Links, 1)
+	assert.Equal(t, "https://example.com/cooking", res.Links[0].URL)
+
+	// Articles are searchable once saved
+	assert.Empty(t, urls("borrow checker"))
+	require.NoError(t, repo.SaveArticle(ctx, &domain.Article{LinkID: links[2].ID, UserID: 1, Text: "The borrow checker explained."}))
+	assert.Equal(t, []string{"https://blog.example.com/rust"}, urls("borrow checker"))
+
+	// Updates and deletes keep the index in sync
+	updated := links[1]
+	updated.Description = "Cooking at home"
+	require.NoError(t, repo.UpdateLink(ctx, &updated))
+	assert.Equal(t, []string{"https://go.dev/blog/generics"}, urls("generics"))
+	require.NoError(t, repo.DeleteLinkByID(ctx, 1, links[2].ID))
+	assert.Empty(t, urls("borrow"))
+	assert.Empty(t, urls("rust"))
+}
+
+// TestBadgerRepository_Tags tests the tag index, tagging links and renaming tags.
+func TestBadgerRepository_Tags(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	links := []domain.Link{
+		{URL: "https://example.com/1", UserID: 1, Tags: []string{"go", "perf"}},
+		{URL: "https://example.com/2", UserID: 1, Tags: []string{"golang"}},
+		{URL: "https://example.com/3", UserID: 1, Tags: []string{"go", "golang"}},
+		{URL: "https://example.com/4", UserID: 2, Tags: []string{"go"}},
+	}
+	for i := range links {
+		require.NoError(t, repo.SaveLink(ctx, &links[i]))
+	}
+
+	tags, err := repo.Tags(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{"go", 2}, {"golang", 2}, {"perf", 1}}, tags)
+
+	// --- Tagging a single link ---
+	link, err := repo.TagLink(ctx, 1, links[0].ID, []string{"#Reading", "go"}, []string{"PERF"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "reading"}, link.Tags)
+	_, err = repo.TagLink(ctx, 1, "missing", []string{"x"}, nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// --- Renaming into an existing tag merges them ---
+	n, err := repo.RenameTag(ctx, 1, "golang", "go")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	tags, err = repo.Tags(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{"go", 3}, {"reading", 1}}, tags)
+
+	got, err := repo.GetLinkByID(ctx, 1, links[2].ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go"}, got.Tags, "Merged tags should not be duplicated")
+
+	_, err = repo.RenameTag(ctx, 1, "golang", "go")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// --- Other users and deleted links are not counted ---
+	require.NoError(t, repo.DeleteLinkByID(ctx, 1, links[1].ID))
+	tags, err = repo.Tags(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{"go", 2}, {"reading", 1}}, tags)
+	tags, err = repo.Tags(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{"go", 1}}, tags)
+
+	// --- Tags containing ':' stay apart from their prefixes ---
+	nested := domain.Link{URL: "https://example.com/nested", UserID: 3, Tags: []string{"a:b", "a", "100%"}}
+	require.NoError(t, repo.SaveLink(ctx, &nested))
+	other := domain.Link{URL: "https://example.com/nested-only", UserID: 3, Tags: []string{"a:b"}}
+	require.NoError(t, repo.SaveLink(ctx, &other))
+	tags, err = repo.Tags(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{"a:b", 2}, {"100%", 1}, {"a", 1}}, tags)
+
+	n, err = repo.RenameTag(ctx, 3, "a", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "Renaming a tag should not touch tags it prefixes")
+	got, err = repo.GetLinkByID(ctx, 3, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a:b"}, got.Tags)
+
+	page, err := repo.ListLinks(ctx, 3, ListOptions{Tag: "A:B", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, other.ID, page.Links[0].ID, "Tagged links should be listed newest first")
+	page, err = repo.ListLinks(ctx, 3, ListOptions{Tag: "a:b", Limit: 1, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, nested.ID, page.Links[0].ID)
+
+	result, err := repo.SearchLinks(ctx, 3, search.Query{Tags: []string{"a:b", "c"}}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, result.Links, 1)
+	assert.Equal(t, nested.ID, result.Links[0].ID)
+}
+
+// TestBadgerRepository_RenameTagManyLinks tests renaming a tag carried by
+// more links than a full rewrite of each would fit into one transaction.
+func TestBadgerRepository_RenameTagManyLinks(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+
+	const count = 8000
+	for i := 0; i < count; i++ {
+		link := domain.Link{
+			URL:         fmt.Sprintf("https://example.com/articles/%d", i),
+			Title:       fmt.Sprintf("Article number %d about distributed systems", i),
+			Description: "A long description of consensus, replication and partitioning",
+			UserID:      1,
+			Timestamp:   base.Add(time.Duration(i) * time.Millisecond),
+			Tags:        []string{"old", "keep"},
+		}
+		if i%10 == 0 {
+			link.Tags = append(link.Tags, "new")
+		}
+		require.NoError(t, repo.SaveLink(ctx, &link))
+	}
+
+	n, err := repo.RenameTag(ctx, 1, "old", "new")
+	require.NoError(t, err)
+	assert.Equal(t, count, n)
+
+	tags, err := repo.Tags(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{"keep", count}, {"new", count}}, tags)
+
+	page, err := repo.ListLinks(ctx, 1, ListOptions{Tag: "new", Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 5)
+	assert.Equal(t, "https://example.com/articles/7999", page.Links[0].URL, "Renamed tags should keep the saved order")
+	assert.Equal(t, []string{"keep", "new"}, page.Links[0].Tags)
+	page, err = repo.ListLinks(ctx, 1, ListOptions{Tag: "old", Limit: 5})
+	require.NoError(t, err)
+	assert.Empty(t, page.Links)
+
+	result, err := repo.SearchLinks(ctx, 1, search.ParseQuery("new"), 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, count, result.Total)
+	result, err = repo.SearchLinks(ctx, 1, search.ParseQuery("old"), 0, 1)
+	require.NoError(t, err)
+	assert.Zero(t, result.Total)
+}
+
+// TestBadgerRepository_Collections tests collection CRUD, membership and access.
+func TestBadgerRepository_Collections(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+	links := []domain.Link{
+		{URL: "https://example.com/1", UserID: 1, Timestamp: base},
+		{URL: "https://example.com/2", UserID: 1, Timestamp: base.Add(time.Minute)},
+		{URL: "https://example.com/3", UserID: 1, Timestamp: base.Add(2 * time.Minute)},
+		{URL: "https://example.com/other", UserID: 2, Timestamp: base},
+	}
+	for i := range links {
+		require.NoError(t, repo.SaveLink(ctx, &links[i]))
+	}
+
+	research := domain.Collection{OwnerID: 1, Name: " Q3 research "}
+	require.NoError(t, repo.CreateCollection(ctx, &research))
+	require.NotEmpty(t, research.ID)
+	assert.Equal(t, "Q3 research", research.Name)
+	err := repo.CreateCollection(ctx, &domain.Collection{OwnerID: 1, Name: "q3 RESEARCH"})
+	assert.ErrorIs(t, err, ErrCollectionExists)
+	onboarding := domain.Collection{OwnerID: 1, Name: "onboarding"}
+	require.NoError(t, repo.CreateCollection(ctx, &onboarding))
+
+	// --- Membership ---
+	for _, link := range links[:3] {
+		_, err := repo.AddToCollection(ctx, 1, research.ID, link.ID)
+		require.NoError(t, err)
+	}
+	c, err := repo.AddToCollection(ctx, 1, research.ID, links[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, c.LinkCount, "Adding a link twice should not count it twice")
+	_, err = repo.AddToCollection(ctx, 1, onboarding.ID, links[0].ID)
+	require.NoError(t, err)
+	_, err = repo.AddToCollection(ctx, 1, research.ID, links[3].ID)
+	assert.ErrorIs(t, err, ErrNotFound, "Links of other users cannot be added")
+
+	page, err := repo.ListCollectionLinks(ctx, 1, research.ID, ListOptions{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 2)
+	assert.Equal(t, links[2].URL, page.Links[0].URL, "The most recently added link should come first")
+	page, err = repo.ListCollectionLinks(ctx, 1, research.ID, ListOptions{Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, links[0].URL, page.Links[0].URL)
+
+	c, err = repo.RemoveFromCollection(ctx, 1, research.ID, links[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.LinkCount)
+
+	// --- Deleting a link removes it from its collections ---
+	require.NoError(t, repo.DeleteLinkByID(ctx, 1, links[0].ID))
+	c, err = repo.GetCollection(ctx, 1, onboarding.ID)
+	require.NoError(t, err)
+	assert.Zero(t, c.LinkCount)
+	page, err = repo.ListCollectionLinks(ctx, 1, research.ID, ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, links[2].URL, page.Links[0].URL)
+
+	// --- Lookup, rename, access and delete ---
+	found, err := repo.FindCollection(ctx, 1, "q3 research")
+	require.NoError(t, err)
+	assert.Equal(t, research.ID, found.ID)
+	assert.ErrorIs(t, repo.RenameCollection(ctx, 1, research.ID, "Onboarding"), ErrCollectionExists)
+	require.NoError(t, repo.RenameCollection(ctx, 1, research.ID, "Research"))
+
+	_, err = repo.GetCollection(ctx, 2, research.ID)
+	assert.ErrorIs(t, err, ErrCollectionNotFound, "Other users should not see the collection")
+	_, err = repo.ListCollectionLinks(ctx, 2, research.ID, ListOptions{})
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+
+	require.NoError(t, repo.DeleteCollection(ctx, 1, research.ID))
+	collections, err := repo.ListCollections(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, collections, 1)
+	assert.Equal(t, "onboarding", collections[0].Name)
+	_, err = repo.GetLinkByID(ctx, 1, links[2].ID)
+	assert.NoError(t, err, "Deleting a collection should keep its links")
+}
+
+// TestBadgerRepository_SharedCollections tests invites, roles and permission checks.
+func TestBadgerRepository_SharedCollections(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	const owner, editor, viewer, stranger = 1, 2, 3, 4
+	links := map[int64]*domain.Link{}
+	for _, uid := range []int64{owner, editor, viewer} {
+		link := &domain.Link{URL: "https://example.com/" + strconv.FormatInt(uid, 10), UserID: uid}
+		require.NoError(t, repo.SaveLink(ctx, link))
+		links[uid] = link
+	}
+
+	team := domain.Collection{OwnerID: owner, Name: "Team"}
+	require.NoError(t, repo.CreateCollection(ctx, &team))
+	assert.Equal(t, domain.RoleOwner, team.Role)
+
+	// --- Invites ---
+	_, err := repo.CreateCollectionInvite(ctx, owner, team.ID, domain.RoleOwner, time.Hour)
+	assert.Error(t, err, "Invites cannot grant ownership")
+	editInvite, err := repo.CreateCollectionInvite(ctx, owner, team.ID, domain.RoleEditor, time.Hour)
+	require.NoError(t, err)
+	viewInvite, err := repo.CreateCollectionInvite(ctx, owner, team.ID, domain.RoleViewer, time.Hour)
+	require.NoError(t, err)
+
+	c, err := repo.AcceptCollectionInvite(ctx, editor, "Ed", editInvite.Token)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleEditor, c.Role)
+	_, err = repo.AcceptCollectionInvite(ctx, stranger, "Eve", editInvite.Token)
+	assert.ErrorIs(t, err, ErrInviteNotFound, "Invites should work only once")
+	_, err = repo.AcceptCollectionInvite(ctx, viewer, "Vi", viewInvite.Token)
+	require.NoError(t, err)
+	_, err = repo.CreateCollectionInvite(ctx, editor, team.ID, domain.RoleViewer, time.Hour)
+	assert.ErrorIs(t, err, ErrPermissionDenied, "Only the owner may invite")
+
+	members, err := repo.ListCollectionMembers(ctx, viewer, team.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 3)
+	assert.Equal(t, int64(owner), members[0].UserID)
+	assert.Equal(t, "Ed", members[1].Name)
+
+	// --- Permission checks ---
+	for _, uid := range []int64{owner, editor} {
+		_, err := repo.AddToCollection(ctx, uid, team.ID, links[uid].ID)
+		require.NoError(t, err)
+	}
+	_, err = repo.AddToCollection(ctx, viewer, team.ID, links[viewer].ID)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = repo.GetCollection(ctx, stranger, team.ID)
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+	assert.ErrorIs(t, repo.RenameCollection(ctx, editor, team.ID, "Mine"), ErrPermissionDenied)
+	assert.ErrorIs(t, repo.DeleteCollection(ctx, editor, team.ID), ErrPermissionDenied)
+
+	page, err := repo.ListCollectionLinks(ctx, viewer, team.ID, ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, page.Links, 2, "Members should see the links of all members")
+
+	c, err = repo.RemoveFromCollection(ctx, owner, team.ID, links[editor].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.LinkCount, "Editors may remove links added by others")
+
+	// --- Roles and leaving ---
+	assert.ErrorIs(t, repo.SetCollectionMemberRole(ctx, editor, team.ID, viewer, domain.RoleEditor), ErrPermissionDenied)
+	require.NoError(t, repo.SetCollectionMemberRole(ctx, owner, team.ID, viewer, domain.RoleEditor))
+	c, err = repo.AddToCollection(ctx, viewer, team.ID, links[viewer].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.LinkCount)
+
+	assert.ErrorIs(t, repo.RemoveCollectionMember(ctx, editor, team.ID, viewer), ErrPermissionDenied)
+	assert.ErrorIs(t, repo.RemoveCollectionMember(ctx, owner, team.ID, owner), ErrPermissionDenied, "The owner cannot leave")
+	require.NoError(t, repo.RemoveCollectionMember(ctx, viewer, team.ID, viewer))
+	_, err = repo.GetCollection(ctx, viewer, team.ID)
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+	c, err = repo.GetCollection(ctx, owner, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.LinkCount, "Links of members who leave should be removed")
+
+	require.NoError(t, repo.DeleteCollection(ctx, owner, team.ID))
+	collections, err := repo.ListCollections(ctx, editor)
+	require.NoError(t, err)
+	assert.Empty(t, collections, "Deleting a collection should remove it for all members")
+}
+
+// TestBadgerRepository_Tokens tests minting, authenticating and revoking API tokens.
+func TestBadgerRepository_Tokens(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	laptop := domain.APIToken{UserID: 1, Name: "laptop", Scopes: []domain.TokenScope{domain.ScopeRead}}
+	secret, err := repo.CreateToken(ctx, &laptop)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, tokenPrefix))
+	assert.NotEmpty(t, laptop.ID)
+
+	// --- Authenticate ---
+	token, err := repo.AuthenticateToken(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, laptop.ID, token.ID)
+	assert.Equal(t, int64(1), token.UserID)
+	assert.True(t, token.HasScope(domain.ScopeRead))
+	assert.False(t, token.HasScope(domain.ScopeWrite))
+	_, err = repo.AuthenticateToken(ctx, secret+"x")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	tokens, err := repo.ListTokens(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.False(t, tokens[0].LastUsedAt.IsZero(), "Use of the token should be recorded")
+
+	// --- Expiry ---
+	expired := domain.APIToken{UserID: 1, Name: "old", ExpiresAt: time.Now().Add(-time.Second)}
+	expiredSecret, err := repo.CreateToken(ctx, &expired)
+	require.NoError(t, err)
+	_, err = repo.AuthenticateToken(ctx, expiredSecret)
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+	tokens, err = repo.ListTokens(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 1, "Expired tokens should not be listed")
+
+	// --- Revoke ---
+	assert.ErrorIs(t, repo.RevokeToken(ctx, 2, laptop.ID), ErrTokenNotFound, "Users should not revoke tokens of others")
+	require.NoError(t, repo.RevokeToken(ctx, 1, laptop.ID))
+	_, err = repo.AuthenticateToken(ctx, secret)
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+	tokens, err = repo.ListTokens(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
+
+// TestBadgerRepository_Sessions tests storing and ending browser sessions.
+func TestBadgerRepository_Sessions(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	session := domain.Session{UserID: 1, Name: "Ada", ExpiresAt: time.Now().Add(time.Hour)}
+	id, err := repo.CreateSession(ctx, &session)
+	require.NoError(t, err)
+	assert.NotEmpty(t, session.CSRFToken)
+
+	got, err := repo.GetSession(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), got.UserID)
+	assert.Equal(t, session.CSRFToken, got.CSRFToken)
+
+	_, err = repo.CreateSession(ctx, &domain.Session{UserID: 1, ExpiresAt: time.Now()})
+	assert.Error(t, err, "Sessions that already ended should not be stored")
+
+	require.NoError(t, repo.DeleteSession(ctx, id))
+	_, err = repo.GetSession(ctx, id)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.NoError(t, repo.DeleteSession(ctx, id), "Ending a missing session should not fail")
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// generateCacheKey creates the key of a cached value.
+// Cached values are shared by all users. Format: cache:{key}
+func generateCacheKey(key string) []byte {
+	return []byte("cache:" + key)
+}
+
+// GetCache returns a cached value.
+// It returns ErrNotFound if the value is missing or expired.
+func (r *BadgerRepository) GetCache(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(generateCacheKey(key))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			r.log.WithError(err).WithField("key", key).Error("Failed to read cache entry from BadgerDB")
+		}
+		return nil, fmt.Errorf("failed to get cache entry %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// SetCache stores a value that Badger expires after ttl.
+func (r *BadgerRepository) SetCache(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := r.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry(generateCacheKey(key), value).WithTTL(ttl))
+	})
+
+	if err != nil {
+		r.log.WithError(err).WithField("key", key).Error("Failed to write cache entry to BadgerDB")
+		return fmt.Errorf("failed to set cache entry %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"jetengine/internal/domain"
+)
+
+// generateCheckKey creates the index key of a link by the time it was last
+// checked, pointing to the link's primary key. The index spans all users, so
+// the refresh scheduler finds the links due for a check without a full scan.
+// Pending links are not indexed.
+// Format: check:{lastChecked}:{userID}:{linkID}
+func generateCheckKey(link domain.Link) []byte {
+	return []byte(fmt.Sprintf("%s%020d:%d:%s", generateCheckPrefix(), max(lastChecked(link).UnixNano(), 0), link.UserID, link.ID))
+}
+
+// generateCheckPrefix creates the key prefix of the check index.
+// Format: check:
+func generateCheckPrefix() []byte {
+	return []byte("check:")
+}
+
+// LinksToCheck returns up to limit links of all users that were last checked
+// before the given time, least recently checked first. Links that were never
+// checked count as checked when they were saved; pending links are skipped.
+func (r *BadgerRepository) LinksToCheck(ctx context.Context, before time.Time, limit int) ([]domain.Link, error) {
+	var links []domain.Link
+	err := r.db.View(func(txn *badger.Txn) error {
+		prefix := generateCheckPrefix()
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		defer it.Close()
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if limit > 0 && len(links) == limit {
+				return nil
+			}
+			item := it.Item()
+			checked, err := timeIndexTime(item.Key(), prefix)
+			if err != nil {
+				return err
+			}
+			if !checked.Before(before) {
+				return nil
+			}
+
+			key, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			link, err := getLink(txn, key)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			// Skip entries left behind by links that moved to another key
+			if link.Pending || !bytes.Equal(generateCheckKey(link), item.Key()) {
+				continue
+			}
+			links = append(links, link)
+		}
+		return nil
+	})
+	if err != nil {
+		r.log.WithError(err).Error("Failed to scan links to check")
+		return nil, fmt.Errorf("failed to list links to check: %w", err)
+	}
+	return links, nil
+}
+
+// lastChecked returns when a link was last checked, or saved if it never was.
+func lastChecked(link domain.Link) time.Time {
+	if link.LastChecked.IsZero() {
+		return link.Timestamp
+	}
+	return link.LastChecked
+}
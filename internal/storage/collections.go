@@ -0,0 +1,663 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+)
+
+// generateCollectionKey creates the key of a collection record.
+// Collections live outside the user: namespace, so they can outlive a single member.
+// Format: coll:{collectionID}
+func generateCollectionKey(collectionID string) []byte {
+	return []byte("coll:" + collectionID)
+}
+
+// generateCollectionItemPrefix creates the key prefix of a collection's links,
+// ordered by the time they were added.
+// Format: coll:{collectionID}:item:
+func generateCollectionItemPrefix(collectionID string) []byte {
+	return []byte(fmt.Sprintf("coll:%s:item:", collectionID))
+}
+
+// generateCollectionItemKey creates the key of a link in a collection.
+// Format: coll:{collectionID}:item:{addedAt}:{userID}:{linkID}
+func generateCollectionItemKey(collectionID string, addedAt time.Time, userID int64, linkID string) []byte {
+	return []byte(fmt.Sprintf("%s%020d:%d:%s", generateCollectionItemPrefix(collectionID), max(addedAt.UnixNano(), 0), userID, linkID))
+}
+
+// generateCollectionHasKey creates the key that records a link's membership
+// in a collection, pointing to its item key.
+// Format: coll:{collectionID}:has:{userID}:{linkID}
+func generateCollectionHasKey(collectionID string, userID int64, linkID string) []byte {
+	return []byte(fmt.Sprintf("coll:%s:has:%d:%s", collectionID, userID, linkID))
+}
+
+// generateUserCollectionKey creates the key giving a user access to a
+// collection. Its value is the user's role.
+// Format: user:{userID}:coll:{collectionID}
+func generateUserCollectionKey(userID int64, collectionID string) []byte {
+	return []byte(fmt.Sprintf("%s%s", generateUserCollectionsPrefix(userID), collectionID))
+}
+
+// generateUserCollectionsPrefix creates the key prefix of a user's collections.
+// Format: user:{userID}:coll:
+func generateUserCollectionsPrefix(userID int64) []byte {
+	return []byte(fmt.Sprintf("user:%d:coll:", userID))
+}
+
+// generateLinkCollectionKey creates the reverse index entry of a link in a
+// collection, so deleting the link can remove it from all its collections.
+// Format: user:{userID}:incoll:{linkID}:{collectionID}
+func generateLinkCollectionKey(userID int64, linkID, collectionID string) []byte {
+	return []byte(fmt.Sprintf("%s%s", generateLinkCollectionsPrefix(userID, linkID), collectionID))
+}
+
+// generateLinkCollectionsPrefix creates the key prefix of the collections of a link.
+// Format: user:{userID}:incoll:{linkID}:
+func generateLinkCollectionsPrefix(userID int64, linkID string) []byte {
+	return []byte(fmt.Sprintf("user:%d:incoll:%s:", userID, linkID))
+}
+
+// newCollectionID returns a random collection ID.
+func newCollectionID() (string, error) {
+	var b [9]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate collection ID: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// getCollection reads a collection record within a transaction.
+// It returns ErrCollectionNotFound if there is none.
+func getCollection(txn *badger.Txn, collectionID string) (domain.Collection, error) {
+	var c domain.Collection
+	item, err := txn.Get(generateCollectionKey(collectionID))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return c, ErrCollectionNotFound
+	}
+	if err != nil {
+		return c, err
+	}
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &c)
+	})
+	return c, err
+}
+
+// putCollection writes a collection record within a transaction.
+func putCollection(txn *badger.Txn, c domain.Collection) error {
+	// The role belongs to the reader, not to the collection
+	c.Role = ""
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal collection: %w", err)
+	}
+	return txn.Set(generateCollectionKey(c.ID), data)
+}
+
+// authorizeCollection loads a collection the user has access to, together
+// with the user's role, and checks that the role allows need. Collections the
+// user is no member of are reported as ErrCollectionNotFound, so their IDs
+// leak nothing; members whose role is too low get ErrPermissionDenied.
+func authorizeCollection(txn *badger.Txn, userID int64, collectionID string, need domain.CollectionRole) (domain.Collection, error) {
+	item, err := txn.Get(generateUserCollectionKey(userID, collectionID))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return domain.Collection{}, ErrCollectionNotFound
+	}
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	role, err := item.ValueCopy(nil)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+
+	c, err := getCollection(txn, collectionID)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	c.Role = domain.CollectionRole(role)
+	if !c.Role.Allows(need) {
+		return domain.Collection{}, ErrPermissionDenied
+	}
+	return c, nil
+}
+
+// userCollections loads all collections a user has access to.
+func userCollections(txn *badger.Txn, userID int64) ([]domain.Collection, error) {
+	prefix := generateUserCollectionsPrefix(userID)
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+	defer it.Close()
+
+	var collections []domain.Collection
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		item := it.Item()
+		c, err := getCollection(txn, string(item.Key()[len(prefix):]))
+		if errors.Is(err, ErrCollectionNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		role, err := item.ValueCopy(nil)
+		if err != nil {
+			return nil, err
+		}
+		c.Role = domain.CollectionRole(role)
+		collections = append(collections, c)
+	}
+	return collections, nil
+}
+
+// checkCollectionName validates a name and reports ErrCollectionExists if
+// another collection owned by the user already has it. Collections shared
+// with the user may have any name.
+func checkCollectionName(txn *badger.Txn, userID int64, collectionID, name string) error {
+	if name == "" {
+		return fmt.Errorf("collection name must not be empty")
+	}
+	collections, err := userCollections(txn, userID)
+	if err != nil {
+		return err
+	}
+	for _, c := range collections {
+		if c.OwnerID == userID && c.ID != collectionID && strings.EqualFold(c.Name, name) {
+			return ErrCollectionExists
+		}
+	}
+	return nil
+}
+
+// CreateCollection creates a collection owned by c.OwnerID, assigning its ID
+// and creation time. It returns ErrCollectionExists if the owner already has
+// a collection with the same name.
+func (r *BadgerRepository) CreateCollection(ctx context.Context, c *domain.Collection) error {
+	log := r.log.WithFields(logrus.Fields{
+		"user_id": c.OwnerID,
+		"name":    c.Name,
+	})
+
+	id, err := newCollectionID()
+	if err != nil {
+		return err
+	}
+	c.ID = id
+	c.Name = strings.TrimSpace(c.Name)
+	c.LinkCount = 0
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now()
+	}
+	c.Role = domain.RoleOwner
+
+	err = r.db.Update(func(txn *badger.Txn) error {
+		if err := checkCollectionName(txn, c.OwnerID, c.ID, c.Name); err != nil {
+			return err
+		}
+		if err := putCollection(txn, *c); err != nil {
+			return err
+		}
+		return putMember(txn, c.ID, domain.CollectionMember{UserID: c.OwnerID, Role: domain.RoleOwner, JoinedAt: c.CreatedAt})
+	})
+	if err != nil {
+		if !errors.Is(err, ErrCollectionExists) {
+			log.WithError(err).Error("Failed to create collection")
+		}
+		return fmt.Errorf("failed to create collection %q: %w", c.Name, err)
+	}
+	log.WithField("collection_id", c.ID).Info("Collection created")
+	return nil
+}
+
+// GetCollection retrieves a collection the user has access to.
+// It returns ErrCollectionNotFound otherwise.
+func (r *BadgerRepository) GetCollection(ctx context.Context, userID int64, collectionID string) (domain.Collection, error) {
+	var c domain.Collection
+	err := r.db.View(func(txn *badger.Txn) error {
+		var err error
+		c, err = authorizeCollection(txn, userID, collectionID, domain.RoleViewer)
+		return err
+	})
+	if err != nil {
+		return domain.Collection{}, fmt.Errorf("failed to get collection %s: %w", collectionID, err)
+	}
+	return c, nil
+}
+
+// FindCollection looks up one of the user's collections by ID or by name, ignoring case.
+// It returns ErrCollectionNotFound if none matches.
+func (r *BadgerRepository) FindCollection(ctx context.Context, userID int64, nameOrID string) (domain.Collection, error) {
+	nameOrID = strings.TrimSpace(nameOrID)
+	collections, err := r.ListCollections(ctx, userID)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	for _, c := range collections {
+		if c.ID == nameOrID {
+			return c, nil
+		}
+	}
+	// Shared collections may share a name with the user's own; those win
+	var shared []domain.Collection
+	for _, c := range collections {
+		if !strings.EqualFold(c.Name, nameOrID) {
+			continue
+		}
+		if c.OwnerID == userID {
+			return c, nil
+		}
+		shared = append(shared, c)
+	}
+	if len(shared) > 0 {
+		return shared[0], nil
+	}
+	return domain.Collection{}, fmt.Errorf("failed to find collection %q: %w", nameOrID, ErrCollectionNotFound)
+}
+
+// ListCollections returns the collections a user has access to, sorted by name.
+func (r *BadgerRepository) ListCollections(ctx context.Context, userID int64) ([]domain.Collection, error) {
+	var collections []domain.Collection
+	err := r.db.View(func(txn *badger.Txn) error {
+		var err error
+		collections, err = userCollections(txn, userID)
+		return err
+	})
+	if err != nil {
+		r.log.WithError(err).WithField("user_id", userID).Error("Failed to list collections")
+		return nil, fmt.Errorf("failed to list collections for user %d: %w", userID, err)
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		return strings.ToLower(collections[i].Name) < strings.ToLower(collections[j].Name)
+	})
+	return collections, nil
+}
+
+// RenameCollection changes the name of a collection. Only its owner may rename it.
+// It returns ErrCollectionExists if the user has another collection with that name.
+func (r *BadgerRepository) RenameCollection(ctx context.Context, userID int64, collectionID, name string) error {
+	name = strings.TrimSpace(name)
+	err := r.db.Update(func(txn *badger.Txn) error {
+		c, err := authorizeCollection(txn, userID, collectionID, domain.RoleOwner)
+		if err != nil {
+			return err
+		}
+		if err := checkCollectionName(txn, userID, collectionID, name); err != nil {
+			return err
+		}
+		c.Name = name
+		return putCollection(txn, c)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rename collection %s: %w", collectionID, err)
+	}
+	return nil
+}
+
+// DeleteCollection removes a collection, its memberships and its members.
+// The links themselves are kept. Only the owner may delete a collection.
+func (r *BadgerRepository) DeleteCollection(ctx context.Context, userID int64, collectionID string) error {
+	log := r.log.WithFields(logrus.Fields{
+		"user_id":       userID,
+		"collection_id": collectionID,
+	})
+
+	err := r.db.Update(func(txn *badger.Txn) error {
+		c, err := authorizeCollection(txn, userID, collectionID, domain.RoleOwner)
+		if err != nil {
+			return err
+		}
+
+		// Collect the keys first; deleting while iterating is not allowed
+		prefix := []byte(fmt.Sprintf("coll:%s:", c.ID))
+		hasPrefix := []byte(fmt.Sprintf("coll:%s:has:", c.ID))
+		memberPrefix := generateCollectionMembersPrefix(c.ID)
+		var keys [][]byte
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			key := it.Item().KeyCopy(nil)
+			keys = append(keys, key)
+			if rest, ok := strings.CutPrefix(string(key), string(memberPrefix)); ok {
+				memberID, err := strconv.ParseInt(rest, 10, 64)
+				if err != nil {
+					it.Close()
+					return fmt.Errorf("malformed collection key %s: %w", string(key), err)
+				}
+				keys = append(keys, generateUserCollectionKey(memberID, c.ID))
+			}
+			if rest, ok := strings.CutPrefix(string(key), string(hasPrefix)); ok {
+				owner, linkID, _ := strings.Cut(rest, ":")
+				ownerID, err := strconv.ParseInt(owner, 10, 64)
+				if err != nil {
+					it.Close()
+					return fmt.Errorf("malformed collection key %s: %w", string(key), err)
+				}
+				keys = append(keys, generateLinkCollectionKey(ownerID, linkID, c.ID))
+			}
+		}
+		it.Close()
+
+		keys = append(keys, generateCollectionKey(c.ID))
+		for _, key := range keys {
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrCollectionNotFound) {
+			log.WithError(err).Error("Failed to delete collection")
+		}
+		return fmt.Errorf("failed to delete collection %s: %w", collectionID, err)
+	}
+	log.Info("Collection deleted")
+	return nil
+}
+
+// AddToCollection adds one of the user's links to a collection and returns
+// the updated collection. Adding a link twice has no effect.
+// It returns ErrCollectionNotFound or ErrNotFound if either does not exist,
+// and ErrPermissionDenied if the user is only a viewer of the collection.
+func (r *BadgerRepository) AddToCollection(ctx context.Context, userID int64, collectionID, linkID string) (domain.Collection, error) {
+	var c domain.Collection
+	err := r.db.Update(func(txn *badger.Txn) error {
+		var err error
+		c, err = authorizeCollection(txn, userID, collectionID, domain.RoleEditor)
+		if err != nil {
+			return err
+		}
+		if _, err := lookupLinkKey(txn, userID, linkID); err != nil {
+			return err
+		}
+
+		added, err := addCollectionItem(txn, &c, userID, linkID, time.Now())
+		if err != nil || !added {
+			return err
+		}
+		return putCollection(txn, c)
+	})
+	if err != nil {
+		return domain.Collection{}, fmt.Errorf("failed to add link %s to collection %s: %w", linkID, collectionID, err)
+	}
+	return c, nil
+}
+
+// RemoveFromCollection removes a link from a collection and returns the
+// updated collection. The link may have been added by any member; the user's
+// own link wins if two members have links with the same ID. Removing a link
+// that is not in the collection has no effect.
+// It returns ErrCollectionNotFound if the collection does not exist, and
+// ErrPermissionDenied if the user is only a viewer of the collection.
+func (r *BadgerRepository) RemoveFromCollection(ctx context.Context, userID int64, collectionID, linkID string) (domain.Collection, error) {
+	var c domain.Collection
+	err := r.db.Update(func(txn *badger.Txn) error {
+		var err error
+		c, err = authorizeCollection(txn, userID, collectionID, domain.RoleEditor)
+		if err != nil {
+			return err
+		}
+		owner, err := collectionItemOwner(txn, c.ID, userID, linkID)
+		if err != nil {
+			return err
+		}
+		removed, err := removeCollectionItem(txn, &c, owner, linkID)
+		if err != nil || !removed {
+			return err
+		}
+		return putCollection(txn, c)
+	})
+	if err != nil {
+		return domain.Collection{}, fmt.Errorf("failed to remove link %s from collection %s: %w", linkID, collectionID, err)
+	}
+	return c, nil
+}
+
+// collectionItemOwner finds the member who added the link with the given ID
+// to a collection, preferring userID. It returns userID if no member did.
+func collectionItemOwner(txn *badger.Txn, collectionID string, userID int64, linkID string) (int64, error) {
+	if _, err := txn.Get(generateCollectionHasKey(collectionID, userID, linkID)); err == nil {
+		return userID, nil
+	} else if !errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, err
+	}
+
+	prefix := []byte(fmt.Sprintf("coll:%s:has:", collectionID))
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: false})
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		owner, id, _ := strings.Cut(string(it.Item().Key()[len(prefix):]), ":")
+		if id != linkID {
+			continue
+		}
+		ownerID, err := strconv.ParseInt(owner, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed collection key %s: %w", string(it.Item().Key()), err)
+		}
+		return ownerID, nil
+	}
+	return userID, nil
+}
+
+// addCollectionItem writes the membership keys of a link in a collection
+// and increments its count, unless the link is in the collection already.
+// The caller stores the collection.
+func addCollectionItem(txn *badger.Txn, c *domain.Collection, userID int64, linkID string, addedAt time.Time) (bool, error) {
+	hasKey := generateCollectionHasKey(c.ID, userID, linkID)
+	if _, err := txn.Get(hasKey); err == nil {
+		return false, nil
+	} else if !errors.Is(err, badger.ErrKeyNotFound) {
+		return false, err
+	}
+
+	itemKey := generateCollectionItemKey(c.ID, addedAt, userID, linkID)
+	if err := txn.Set(itemKey, nil); err != nil {
+		return false, err
+	}
+	if err := txn.Set(hasKey, itemKey); err != nil {
+		return false, err
+	}
+	if err := txn.Set(generateLinkCollectionKey(userID, linkID, c.ID), nil); err != nil {
+		return false, err
+	}
+	c.LinkCount++
+	return true, nil
+}
+
+// removeCollectionItem deletes the membership keys of a link in a collection
+// and decrements its count. The caller stores the collection.
+func removeCollectionItem(txn *badger.Txn, c *domain.Collection, userID int64, linkID string) (bool, error) {
+	hasKey := generateCollectionHasKey(c.ID, userID, linkID)
+	item, err := txn.Get(hasKey)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	itemKey, err := item.ValueCopy(nil)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range [][]byte{itemKey, hasKey, generateLinkCollectionKey(userID, linkID, c.ID)} {
+		if err := txn.Delete(key); err != nil {
+			return false, err
+		}
+	}
+	c.LinkCount = max(c.LinkCount-1, 0)
+	return true, nil
+}
+
+// linkCollectionIDs returns the IDs of the collections a link is in.
+func linkCollectionIDs(txn *badger.Txn, userID int64, linkID string) []string {
+	prefix := generateLinkCollectionsPrefix(userID, linkID)
+	var ids []string
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: false})
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		ids = append(ids, string(it.Item().Key()[len(prefix):]))
+	}
+	return ids
+}
+
+// recollectLink moves the collection memberships of a link that merged into
+// another link of the same user to the surviving link, keeping the time each
+// was added. Collections that have both keep the survivor's entry.
+func recollectLink(txn *badger.Txn, userID int64, fromID, toID string) error {
+	for _, id := range linkCollectionIDs(txn, userID, fromID) {
+		c, err := getCollection(txn, id)
+		if errors.Is(err, ErrCollectionNotFound) {
+			if err := txn.Delete(generateLinkCollectionKey(userID, fromID, id)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		addedAt, err := collectionItemAddedAt(txn, c.ID, userID, fromID)
+		if err != nil {
+			return err
+		}
+		if _, err := removeCollectionItem(txn, &c, userID, fromID); err != nil {
+			return err
+		}
+		if _, err := addCollectionItem(txn, &c, userID, toID, addedAt); err != nil {
+			return err
+		}
+		if err := putCollection(txn, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectionItemAddedAt returns when a link was added to a collection, read
+// from its item key. It returns the current time if the link is not in it.
+func collectionItemAddedAt(txn *badger.Txn, collectionID string, userID int64, linkID string) (time.Time, error) {
+	item, err := txn.Get(generateCollectionHasKey(collectionID, userID, linkID))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return time.Now(), nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	itemKey, err := item.ValueCopy(nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	prefix := generateCollectionItemPrefix(collectionID)
+	stamp, _, _ := strings.Cut(strings.TrimPrefix(string(itemKey), string(prefix)), ":")
+	nanos, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed collection item key %s: %w", string(itemKey), err)
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// uncollectLink removes a link that is being deleted from all its collections.
+func uncollectLink(txn *badger.Txn, userID int64, linkID string) error {
+	for _, id := range linkCollectionIDs(txn, userID, linkID) {
+		c, err := getCollection(txn, id)
+		if errors.Is(err, ErrCollectionNotFound) {
+			if err := txn.Delete(generateLinkCollectionKey(userID, linkID, id)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := removeCollectionItem(txn, &c, userID, linkID); err != nil {
+			return err
+		}
+		if err := putCollection(txn, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListCollectionLinks returns a page of the links in a collection, added by
+// any of its members, most recently added first unless opts says otherwise.
+// Filters and cursors work as for ListLinks; Since and Until refer to when
+// links were added.
+// It returns ErrCollectionNotFound if the user has no access to the collection.
+func (r *BadgerRepository) ListCollectionLinks(ctx context.Context, userID int64, collectionID string, opts ListOptions) (LinkPage, error) {
+	var page LinkPage
+	err := r.db.View(func(txn *badger.Txn) error {
+		if _, err := authorizeCollection(txn, userID, collectionID, domain.RoleViewer); err != nil {
+			return err
+		}
+		prefix := generateCollectionItemPrefix(collectionID)
+		var err error
+		seek := func(at time.Time, linkID string) ([]byte, error) {
+			return collectionCursorKey(txn, collectionID, at, linkID)
+		}
+		page, err = listIndex(ctx, txn, prefix, opts, seek, func(item *badger.Item) (domain.Link, error) {
+			// The key ends in {addedAt}:{userID}:{linkID}
+			parts := strings.SplitN(string(item.Key()[len(prefix):]), ":", 3)
+			if len(parts) != 3 {
+				return domain.Link{}, fmt.Errorf("malformed collection item key %s", string(item.Key()))
+			}
+			owner, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return domain.Link{}, fmt.Errorf("malformed collection item key %s: %w", string(item.Key()), err)
+			}
+			key, err := lookupLinkKey(txn, owner, parts[2])
+			if err != nil {
+				return domain.Link{}, err
+			}
+			return getLink(txn, key)
+		})
+		return err
+	})
+	if errors.Is(err, ErrInvalidCursor) || errors.Is(err, ErrCollectionNotFound) {
+		return LinkPage{}, err
+	}
+	if err != nil {
+		r.log.WithError(err).WithField("collection_id", collectionID).Error("Failed to list collection links")
+		return LinkPage{}, fmt.Errorf("failed to list links of collection %s: %w", collectionID, err)
+	}
+	return page, nil
+}
+
+// collectionCursorKey returns the item key a collection cursor points to.
+// Cursors leave out the member who added the link, so each member is tried.
+// If the item is gone, the key sorts just before the items added at the same
+// time, which continues the listing close to where it stopped.
+func collectionCursorKey(txn *badger.Txn, collectionID string, addedAt time.Time, linkID string) ([]byte, error) {
+	prefix := generateCollectionMembersPrefix(collectionID)
+	var members []int64
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: false})
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		userID, err := strconv.ParseInt(string(it.Item().Key()[len(prefix):]), 10, 64)
+		if err != nil {
+			it.Close()
+			return nil, fmt.Errorf("malformed collection member key %s: %w", string(it.Item().Key()), err)
+		}
+		members = append(members, userID)
+	}
+	it.Close()
+
+	for _, userID := range members {
+		key := generateCollectionItemKey(collectionID, addedAt, userID, linkID)
+		if _, err := txn.Get(key); err == nil {
+			return key, nil
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return nil, err
+		}
+	}
+	return append(timeIndexBound(generateCollectionItemPrefix(collectionID), addedAt), ':'), nil
+}
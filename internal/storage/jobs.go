@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+)
+
+// Jobs are stored under job:{jobID}. Jobs that can still run are indexed
+// under jobdue:{runAt}:{jobID}, with runAt as zero-padded Unix nanoseconds so
+// the keys sort by time; dead jobs have no index entry.
+const (
+	jobPrefix    = "job:"
+	jobDuePrefix = "jobdue:"
+)
+
+// generateJobKey creates the key of a job.
+// Format: job:{jobID}
+func generateJobKey(id string) []byte {
+	return []byte(jobPrefix + id)
+}
+
+// generateJobDueKey creates the index key ordering runnable jobs by due time.
+// Format: jobdue:{runAt}:{jobID}
+func generateJobDueKey(job domain.ScrapeJob) []byte {
+	return []byte(fmt.Sprintf("%s%020d:%s", jobDuePrefix, job.RunAt.UnixNano(), job.ID))
+}
+
+// newJobID returns a random job ID.
+func newJobID() (string, error) {
+	var b [9]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// EnqueueJob adds a pending job, assigning its ID and timestamps.
+// A zero RunAt makes the job due right away.
+func (r *BadgerRepository) EnqueueJob(ctx context.Context, job *domain.ScrapeJob) error {
+	id, err := newJobID()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	job.ID = id
+	job.Status = domain.JobPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+
+	err = r.db.Update(func(txn *badger.Txn) error {
+		return putJob(txn, job)
+	})
+	if err != nil {
+		r.log.WithError(err).WithField("url", job.URL).Error("Failed to enqueue job in BadgerDB")
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	r.log.WithFields(logrus.Fields{
+		"job_id":  job.ID,
+		"user_id": job.UserID,
+		"link_id": job.LinkID,
+	}).Debug("Job enqueued")
+	return nil
+}
+
+// ClaimJob marks the earliest job that is due at now as running until
+// now+lease, counts the attempt and returns the job.
+// It returns ErrNoJob if no job is due, or if the claim conflicted with a
+// concurrent update of the queue.
+func (r *BadgerRepository) ClaimJob(ctx context.Context, now time.Time, lease time.Duration) (domain.ScrapeJob, error) {
+	var job domain.ScrapeJob
+	err := r.db.Update(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(jobDuePrefix)})
+		prefix := []byte(jobDuePrefix)
+		it.Seek(prefix)
+		if !it.ValidForPrefix(prefix) {
+			it.Close()
+			return ErrNoJob
+		}
+		dueKey := it.Item().KeyCopy(nil)
+		it.Close()
+
+		runAtText, id, _ := strings.Cut(string(dueKey[len(jobDuePrefix):]), ":")
+		runAt, err := strconv.ParseInt(runAtText, 10, 64)
+		if err != nil {
+			return fmt.Errorf("malformed job index key %s: %w", dueKey, err)
+		}
+		if runAt > now.UnixNano() {
+			return ErrNoJob
+		}
+
+		job, err = getJob(txn, id)
+		if err != nil {
+			return err
+		}
+		if err := txn.Delete(dueKey); err != nil {
+			return err
+		}
+		job.Status = domain.JobRunning
+		job.Attempts++
+		job.RunAt = now.Add(lease)
+		job.UpdatedAt = now
+		return putJob(txn, &job)
+	})
+
+	if errors.Is(err, badger.ErrConflict) {
+		// A worker updated a job concurrently; the caller simply claims again later
+		err = ErrNoJob
+	}
+	if err != nil {
+		if !errors.Is(err, ErrNoJob) {
+			r.log.WithError(err).Error("Failed to claim job from BadgerDB")
+		}
+		return domain.ScrapeJob{}, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return job, nil
+}
+
+// UpdateJob stores a changed job and moves its index entry to the new RunAt.
+// It returns ErrNotFound if the job does not exist.
+func (r *BadgerRepository) UpdateJob(ctx context.Context, job *domain.ScrapeJob) error {
+	job.UpdatedAt = time.Now()
+	err := r.db.Update(func(txn *badger.Txn) error {
+		old, err := getJob(txn, job.ID)
+		if err != nil {
+			return err
+		}
+		if err := txn.Delete(generateJobDueKey(old)); err != nil {
+			return err
+		}
+		return putJob(txn, job)
+	})
+
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			r.log.WithError(err).WithField("job_id", job.ID).Error("Failed to update job in BadgerDB")
+		}
+		return fmt.Errorf("failed to update job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// DeleteJob removes a job and its index entry. Deleting a missing job is not an error.
+func (r *BadgerRepository) DeleteJob(ctx context.Context, id string) error {
+	err := r.db.Update(func(txn *badger.Txn) error {
+		job, err := getJob(txn, id)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := txn.Delete(generateJobDueKey(job)); err != nil {
+			return err
+		}
+		return txn.Delete(generateJobKey(id))
+	})
+
+	if err != nil {
+		r.log.WithError(err).WithField("job_id", id).Error("Failed to delete job from BadgerDB")
+		return fmt.Errorf("failed to delete job %s: %w", id, err)
+	}
+	return nil
+}
+
+// DeadJobs lists the jobs that were given up, oldest first.
+func (r *BadgerRepository) DeadJobs(ctx context.Context) ([]domain.ScrapeJob, error) {
+	var jobs []domain.ScrapeJob
+	err := r.db.View(func(txn *badger.Txn) error {
+		prefix := []byte(jobPrefix)
+		it := txn.NewIterator(badger.IteratorOptions{PrefetchValues: true, PrefetchSize: 100, Prefix: prefix})
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var job domain.ScrapeJob
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &job)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to unmarshal job data for key %s: %w", it.Item().Key(), err)
+			}
+			if job.Status == domain.JobDead {
+				jobs = append(jobs, job)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		r.log.WithError(err).Error("Failed to list dead jobs from BadgerDB")
+		return nil, fmt.Errorf("failed to list dead jobs: %w", err)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// putJob writes a job, and its due index entry unless the job is dead.
+func putJob(txn *badger.Txn, job *domain.ScrapeJob) error {
+	jobBytes, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	if err := txn.Set(generateJobKey(job.ID), jobBytes); err != nil {
+		return err
+	}
+	if job.Status == domain.JobDead {
+		return nil
+	}
+	return txn.Set(generateJobDueKey(*job), nil)
+}
+
+// getJob reads and decodes a job.
+func getJob(txn *badger.Txn, id string) (domain.ScrapeJob, error) {
+	var job domain.ScrapeJob
+	item, err := txn.Get(generateJobKey(id))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return job, ErrNotFound
+	}
+	if err != nil {
+		return job, err
+	}
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &job)
+	})
+	if err != nil {
+		return job, fmt.Errorf("failed to unmarshal job %s: %w", id, err)
+	}
+	return job, nil
+}
@@ -0,0 +1,349 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"jetengine/internal/domain"
+)
+
+// ErrInvalidCursor is returned by ListLinks when the cursor was not produced by a previous page.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	// defaultListLimit is the page size used when ListOptions.Limit is not set.
+	defaultListLimit = 20
+	// maxListLimit caps ListOptions.Limit.
+	maxListLimit = 100
+)
+
+// ReadFilter selects links by their read state.
+type ReadFilter int
+
+const (
+	// AnyReadState lists read and unread links.
+	AnyReadState ReadFilter = iota
+	// OnlyUnread lists links that were not read yet.
+	OnlyUnread
+	// OnlyRead lists links that were read.
+	OnlyRead
+)
+
+// SortOrder is the order in which ListLinks returns links.
+type SortOrder int
+
+const (
+	// NewestFirst lists the most recently saved links first.
+	NewestFirst SortOrder = iota
+	// OldestFirst lists the earliest saved links first.
+	OldestFirst
+)
+
+// ListOptions filters and pages the links returned by ListLinks.
+// The zero value lists the newest links of a user.
+type ListOptions struct {
+	// Read filters links by read state.
+	Read ReadFilter
+	// Tag, if set, only lists links with this tag.
+	Tag string
+	// Domain, if set, only lists links on this host or its subdomains.
+	// A leading "www." is ignored on both sides.
+	Domain string
+	// Since, if set, only lists links saved at or after this time.
+	Since time.Time
+	// Until, if set, only lists links saved before this time.
+	Until time.Time
+	// Order is the sort order by save time.
+	Order SortOrder
+	// Limit is the maximum number of links per page. Default 20, at most 100.
+	Limit int
+	// Cursor continues a listing after or before the page that returned it.
+	// It must be used with the same filters and order.
+	Cursor string
+}
+
+// LinkPage is one page of links returned by ListLinks.
+type LinkPage struct {
+	Links []domain.Link
+	// NextCursor fetches the next page; it is empty on the last page.
+	NextCursor string
+	// PrevCursor fetches the previous page; it is empty on the first page.
+	PrevCursor string
+}
+
+// ListLinks returns a page of a user's links matching opts. It walks the time
+// index in the requested order and stops as soon as the page is full, so the
+// cost depends on the page size and how selective the filters are rather than
+// on the total number of links.
+func (r *BadgerRepository) ListLinks(ctx context.Context, userID int64, opts ListOptions) (LinkPage, error) {
+	var page LinkPage
+	err := r.db.View(func(txn *badger.Txn) error {
+		var err error
+		// Links with a tag are listed from its part of the tag index
+		prefix := generateTimeIndexPrefix(userID)
+		seek := func(at time.Time, linkID string) ([]byte, error) {
+			return generateTimeIndexKey(domain.Link{UserID: userID, Timestamp: at, ID: linkID}), nil
+		}
+		if tag := normalizeTag(opts.Tag); tag != "" {
+			prefix = generateTagPrefix(userID, tag)
+			seek = func(at time.Time, linkID string) ([]byte, error) {
+				return generateTagKey(domain.Link{UserID: userID, Timestamp: at, ID: linkID}, tag), nil
+			}
+		}
+		page, err = listIndex(ctx, txn, prefix, opts, seek, func(item *badger.Item) (domain.Link, error) {
+			key, err := item.ValueCopy(nil)
+			if err != nil {
+				return domain.Link{}, err
+			}
+			return getLink(txn, key)
+		})
+		return err
+	})
+	if errors.Is(err, ErrInvalidCursor) {
+		return LinkPage{}, err
+	}
+	if err != nil {
+		r.log.WithError(err).WithField("user_id", userID).Error("Failed to list links")
+		return LinkPage{}, fmt.Errorf("failed to list links for user %d: %w", userID, err)
+	}
+	return page, nil
+}
+
+// listIndex returns a page of links from a time-ordered index, whose keys
+// are the prefix followed by "{timestamp}:{rest}". seek returns the key of
+// the entry a cursor points to, given its time and link ID. resolve loads
+// the link an index entry refers to and returns ErrNotFound for stale entries.
+func listIndex(ctx context.Context, txn *badger.Txn, prefix []byte, opts ListOptions,
+	seek func(at time.Time, linkID string) ([]byte, error), resolve func(*badger.Item) (domain.Link, error)) (LinkPage, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	limit = min(limit, maxListLimit)
+
+	var cursorKey []byte
+	var backward bool
+	if opts.Cursor != "" {
+		var at time.Time
+		var linkID string
+		var err error
+		at, linkID, backward, err = decodeCursor(opts.Cursor)
+		if err != nil {
+			return LinkPage{}, err
+		}
+		if cursorKey, err = seek(at, linkID); err != nil {
+			return LinkPage{}, err
+		}
+	}
+	domainFilter := normalizeDomain(opts.Domain)
+
+	// Previous pages are read from the cursor against the requested order
+	reverse := (opts.Order == NewestFirst) != backward
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, Reverse: reverse})
+	defer it.Close()
+
+	// Seek to the cursor or the bound of the time range. In reverse, Seek
+	// finds the last key at or before the target, so a key one past the
+	// prefix starts at the newest link.
+	start := cursorKey
+	switch {
+	case start != nil:
+	case reverse && !opts.Until.IsZero():
+		start = timeIndexBound(prefix, opts.Until)
+	case reverse:
+		start = append(bytes.Clone(prefix), 0xFF)
+	case !opts.Since.IsZero():
+		start = timeIndexBound(prefix, opts.Since)
+	default:
+		start = prefix
+	}
+
+	var page LinkPage
+	var saves []time.Time
+	more := false
+	for it.Seek(start); it.ValidForPrefix(prefix); it.Next() {
+		if err := ctx.Err(); err != nil {
+			return LinkPage{}, err
+		}
+		item := it.Item()
+		if cursorKey != nil && bytes.Equal(item.Key(), cursorKey) {
+			continue
+		}
+		saved, err := timeIndexTime(item.Key(), prefix)
+		if err != nil {
+			return LinkPage{}, err
+		}
+		if !opts.Since.IsZero() && saved.Before(opts.Since) {
+			if reverse {
+				break
+			}
+			continue
+		}
+		if !opts.Until.IsZero() && !saved.Before(opts.Until) {
+			if !reverse {
+				break
+			}
+			continue
+		}
+
+		link, err := resolve(item)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return LinkPage{}, err
+		}
+		if !matchesListOptions(link, opts, domainFilter) {
+			continue
+		}
+
+		if len(page.Links) == limit {
+			// One more match exists, so the page is not the last one
+			more = true
+			break
+		}
+		page.Links = append(page.Links, link)
+		saves = append(saves, saved)
+	}
+
+	if backward {
+		if !more {
+			// Nothing precedes this page, so show a full first page instead
+			opts.Cursor = ""
+			return listIndex(ctx, txn, prefix, opts, seek, resolve)
+		}
+		slices.Reverse(page.Links)
+		slices.Reverse(saves)
+		more = true
+	}
+	if n := len(page.Links); n > 0 {
+		if more {
+			page.NextCursor = encodeCursor(saves[n-1], page.Links[n-1].ID, false)
+		}
+		if opts.Cursor != "" {
+			page.PrevCursor = encodeCursor(saves[0], page.Links[0].ID, true)
+		}
+	}
+	return page, nil
+}
+
+// matchesListOptions reports whether a link passes the filters of opts.
+// domainFilter is opts.Domain normalized by normalizeDomain.
+func matchesListOptions(link domain.Link, opts ListOptions, domainFilter string) bool {
+	switch opts.Read {
+	case OnlyUnread:
+		if link.Read {
+			return false
+		}
+	case OnlyRead:
+		if !link.Read {
+			return false
+		}
+	}
+	if opts.Tag != "" && !hasTag(link, opts.Tag) {
+		return false
+	}
+	if domainFilter != "" && !onDomain(link, domainFilter) {
+		return false
+	}
+	return true
+}
+
+// onDomain reports whether a link is on a site or one of its subdomains.
+// site must be normalized by normalizeDomain.
+func onDomain(link domain.Link, site string) bool {
+	host := linkHost(link)
+	return host == site || strings.HasSuffix(host, "."+site)
+}
+
+// hasTag reports whether a link carries a tag, ignoring case.
+func hasTag(link domain.Link, tag string) bool {
+	for _, t := range link.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkHost returns the normalized host name of a link.
+func linkHost(link domain.Link) string {
+	raw := link.CanonicalURL
+	if raw == "" {
+		raw = link.URL
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return normalizeDomain(u.Hostname())
+}
+
+// normalizeDomain lowercases a host name and strips a leading "www.".
+func normalizeDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	return strings.TrimPrefix(host, "www.")
+}
+
+// timeIndexBound returns the position in a time-ordered index of the first key at t.
+func timeIndexBound(prefix []byte, t time.Time) []byte {
+	return fmt.Appendf(bytes.Clone(prefix), "%020d", max(t.UnixNano(), 0))
+}
+
+// timeIndexTime parses the time out of a time-ordered index key.
+func timeIndexTime(key, prefix []byte) (time.Time, error) {
+	ts, _, ok := strings.Cut(string(key[len(prefix):]), ":")
+	if !ok {
+		return time.Time{}, fmt.Errorf("malformed time index key %s", string(key))
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed time index key %s: %w", string(key), err)
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// Cursors start with a byte giving their direction.
+const (
+	cursorForward  = 'f'
+	cursorBackward = 'b'
+)
+
+// encodeCursor turns the time and link ID of the index entry at the edge of
+// a page into an opaque cursor. Backward cursors fetch the page before the
+// entry, others the page after it. Times are packed into 8 bytes, so with an
+// 11-character link ID the cursor takes 27 characters and fits into
+// Telegram callback data.
+func encodeCursor(at time.Time, linkID string, backward bool) string {
+	raw := []byte{cursorForward}
+	if backward {
+		raw[0] = cursorBackward
+	}
+	raw = binary.BigEndian.AppendUint64(raw, uint64(max(at.UnixNano(), 0)))
+	return base64.RawURLEncoding.EncodeToString(append(raw, linkID...))
+}
+
+// decodeCursor reverses encodeCursor and checks that the result looks valid.
+func decodeCursor(cursor string) (at time.Time, linkID string, backward bool, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(raw) <= 9 || (raw[0] != cursorForward && raw[0] != cursorBackward) {
+		return time.Time{}, "", false, ErrInvalidCursor
+	}
+	nanos := int64(binary.BigEndian.Uint64(raw[1:9]))
+	linkID = string(raw[9:])
+	if nanos < 0 || strings.ContainsRune(linkID, ':') {
+		return time.Time{}, "", false, ErrInvalidCursor
+	}
+	return time.Unix(0, nanos), linkID, raw[0] == cursorBackward, nil
+}
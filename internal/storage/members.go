@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+)
+
+// generateCollectionMemberKey creates the key of a member of a collection.
+// Format: coll:{collectionID}:member:{userID}
+func generateCollectionMemberKey(collectionID string, userID int64) []byte {
+	return []byte(fmt.Sprintf("%s%d", generateCollectionMembersPrefix(collectionID), userID))
+}
+
+// generateCollectionMembersPrefix creates the key prefix of the members of a collection.
+// Format: coll:{collectionID}:member:
+func generateCollectionMembersPrefix(collectionID string) []byte {
+	return []byte(fmt.Sprintf("coll:%s:member:", collectionID))
+}
+
+// generateInviteKey creates the key of a collection invite. Invites expire
+// through Badger's TTL.
+// Format: invite:{token}
+func generateInviteKey(token string) []byte {
+	return []byte("invite:" + token)
+}
+
+// newInviteToken returns a random invite token. It is URL-safe, so it can be
+// used as a Telegram deep link payload.
+func newInviteToken() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate invite token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// putMember writes a member of a collection and the user's access key.
+func putMember(txn *badger.Txn, collectionID string, m domain.CollectionMember) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal collection member: %w", err)
+	}
+	if err := txn.Set(generateCollectionMemberKey(collectionID, m.UserID), data); err != nil {
+		return err
+	}
+	return txn.Set(generateUserCollectionKey(m.UserID, collectionID), []byte(m.Role))
+}
+
+// getMember reads a member of a collection within a transaction.
+// It returns ErrMemberNotFound if the user is no member.
+func getMember(txn *badger.Txn, collectionID string, userID int64) (domain.CollectionMember, error) {
+	var m domain.CollectionMember
+	item, err := txn.Get(generateCollectionMemberKey(collectionID, userID))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return m, ErrMemberNotFound
+	}
+	if err != nil {
+		return m, err
+	}
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &m)
+	})
+	return m, err
+}
+
+// checkInviteRole reports an error unless role can be granted by an invite
+// or a role change. There is only one owner per collection.
+func checkInviteRole(role domain.CollectionRole) error {
+	if role != domain.RoleEditor && role != domain.RoleViewer {
+		return fmt.Errorf("invalid collection role %q", role)
+	}
+	return nil
+}
+
+// CreateCollectionInvite creates a one-time invite to join a collection with
+// the given role. Only the owner may invite; the invite expires after ttl.
+func (r *BadgerRepository) CreateCollectionInvite(ctx context.Context, userID int64, collectionID string, role domain.CollectionRole, ttl time.Duration) (domain.CollectionInvite, error) {
+	if err := checkInviteRole(role); err != nil {
+		return domain.CollectionInvite{}, err
+	}
+	token, err := newInviteToken()
+	if err != nil {
+		return domain.CollectionInvite{}, err
+	}
+	invite := domain.CollectionInvite{
+		Token:        token,
+		CollectionID: collectionID,
+		Role:         role,
+		CreatedBy:    userID,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+
+	err = r.db.Update(func(txn *badger.Txn) error {
+		if _, err := authorizeCollection(txn, userID, collectionID, domain.RoleOwner); err != nil {
+			return err
+		}
+		data, err := json.Marshal(invite)
+		if err != nil {
+			return fmt.Errorf("failed to marshal invite: %w", err)
+		}
+		return txn.SetEntry(badger.NewEntry(generateInviteKey(token), data).WithTTL(ttl))
+	})
+	if err != nil {
+		return domain.CollectionInvite{}, fmt.Errorf("failed to create invite to collection %s: %w", collectionID, err)
+	}
+	r.log.WithFields(logrus.Fields{
+		"user_id":       userID,
+		"collection_id": collectionID,
+		"role":          role,
+	}).Info("Collection invite created")
+	return invite, nil
+}
+
+// AcceptCollectionInvite redeems an invite and returns the collection it
+// grants access to, with the user's role. The invite is deleted, so it works
+// only once; members who already have at least the offered role keep it and
+// leave the invite for someone else.
+func (r *BadgerRepository) AcceptCollectionInvite(ctx context.Context, userID int64, name, token string) (domain.Collection, error) {
+	log := r.log.WithField("user_id", userID)
+
+	var c domain.Collection
+	err := r.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(generateInviteKey(token))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return ErrInviteNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var invite domain.CollectionInvite
+		err = item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &invite)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal invite: %w", err)
+		}
+		if time.Now().After(invite.ExpiresAt) {
+			return ErrInviteNotFound
+		}
+
+		c, err = getCollection(txn, invite.CollectionID)
+		if err != nil {
+			return err
+		}
+		member, err := getMember(txn, c.ID, userID)
+		if err == nil && member.Role.Allows(invite.Role) {
+			c.Role = member.Role
+			return nil
+		}
+		if err != nil && !errors.Is(err, ErrMemberNotFound) {
+			return err
+		}
+
+		if err := txn.Delete(generateInviteKey(token)); err != nil {
+			return err
+		}
+		c.Role = invite.Role
+		return putMember(txn, c.ID, domain.CollectionMember{UserID: userID, Name: name, Role: invite.Role, JoinedAt: time.Now()})
+	})
+	if err != nil {
+		if !errors.Is(err, ErrInviteNotFound) && !errors.Is(err, ErrCollectionNotFound) {
+			log.WithError(err).Error("Failed to accept collection invite")
+		}
+		return domain.Collection{}, fmt.Errorf("failed to accept invite: %w", err)
+	}
+	log.WithFields(logrus.Fields{
+		"collection_id": c.ID,
+		"role":          c.Role,
+	}).Info("Collection invite accepted")
+	return c, nil
+}
+
+// ListCollectionMembers returns the members of a collection, owner first and
+// then in the order they joined. Any member may list them.
+func (r *BadgerRepository) ListCollectionMembers(ctx context.Context, userID int64, collectionID string) ([]domain.CollectionMember, error) {
+	var members []domain.CollectionMember
+	err := r.db.View(func(txn *badger.Txn) error {
+		if _, err := authorizeCollection(txn, userID, collectionID, domain.RoleViewer); err != nil {
+			return err
+		}
+		prefix := generateCollectionMembersPrefix(collectionID)
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true})
+		defer it.Close()
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var m domain.CollectionMember
+				if err := json.Unmarshal(val, &m); err != nil {
+					return fmt.Errorf("failed to unmarshal collection member: %w", err)
+				}
+				members = append(members, m)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members of collection %s: %w", collectionID, err)
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		if (members[i].Role == domain.RoleOwner) != (members[j].Role == domain.RoleOwner) {
+			return members[i].Role == domain.RoleOwner
+		}
+		return members[i].JoinedAt.Before(members[j].JoinedAt)
+	})
+	return members, nil
+}
+
+// SetCollectionMemberRole changes the role of a member other than the owner.
+// Only the owner may change roles.
+func (r *BadgerRepository) SetCollectionMemberRole(ctx context.Context, userID int64, collectionID string, memberID int64, role domain.CollectionRole) error {
+	if err := checkInviteRole(role); err != nil {
+		return err
+	}
+	err := r.db.Update(func(txn *badger.Txn) error {
+		if _, err := authorizeCollection(txn, userID, collectionID, domain.RoleOwner); err != nil {
+			return err
+		}
+		m, err := getMember(txn, collectionID, memberID)
+		if err != nil {
+			return err
+		}
+		if m.Role == domain.RoleOwner {
+			return ErrPermissionDenied
+		}
+		m.Role = role
+		return putMember(txn, collectionID, m)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change role of user %d in collection %s: %w", memberID, collectionID, err)
+	}
+	return nil
+}
+
+// RemoveCollectionMember removes a member from a collection, together with
+// the links they added, which are no longer shared. The owner may remove
+// anyone but themselves; other members may only leave.
+func (r *BadgerRepository) RemoveCollectionMember(ctx context.Context, userID int64, collectionID string, memberID int64) error {
+	log := r.log.WithFields(logrus.Fields{
+		"user_id":       userID,
+		"collection_id": collectionID,
+		"member_id":     memberID,
+	})
+
+	need := domain.RoleOwner
+	if memberID == userID {
+		need = domain.RoleViewer
+	}
+	err := r.db.Update(func(txn *badger.Txn) error {
+		c, err := authorizeCollection(txn, userID, collectionID, need)
+		if err != nil {
+			return err
+		}
+		m, err := getMember(txn, collectionID, memberID)
+		if err != nil {
+			return err
+		}
+		if m.Role == domain.RoleOwner {
+			// The owner cannot leave; they delete the collection instead
+			return ErrPermissionDenied
+		}
+
+		prefix := []byte(fmt.Sprintf("coll:%s:has:%d:", collectionID, memberID))
+		var linkIDs []string
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			linkIDs = append(linkIDs, string(it.Item().Key()[len(prefix):]))
+		}
+		it.Close()
+		for _, linkID := range linkIDs {
+			if _, err := removeCollectionItem(txn, &c, memberID, linkID); err != nil {
+				return err
+			}
+		}
+		if err := putCollection(txn, c); err != nil {
+			return err
+		}
+
+		if err := txn.Delete(generateCollectionMemberKey(collectionID, memberID)); err != nil {
+			return err
+		}
+		return txn.Delete(generateUserCollectionKey(memberID, collectionID))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove user %d from collection %s: %w", memberID, collectionID, err)
+	}
+	log.Info("Collection member removed")
+	return nil
+}
@@ -0,0 +1,387 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"jetengine/internal/domain"
+)
+
+// schemaVersionKey stores the version of the last applied migration.
+var schemaVersionKey = []byte("meta:schema_version")
+
+// migration upgrades data written by older versions of the repository.
+type migration struct {
+	version int
+	name    string
+	run     func(ctx context.Context, r *BadgerRepository) error
+}
+
+// migrations lists all schema migrations in the order they must be applied.
+// Append new migrations with increasing versions; never reorder or remove them.
+var migrations = []migration{
+	{version: 1, name: "assign link IDs", run: migrateLinkIDs},
+	{version: 2, name: "key links by canonical URL", run: migrateCanonicalKeys},
+	{version: 3, name: "index links by save time", run: migrateTimeIndex},
+	{version: 4, name: "build search index", run: migrateSearchIndex},
+	{version: 5, name: "index links by tag", run: migrateTagIndex},
+	{version: 6, name: "record collection owners as members", run: migrateCollectionOwners},
+	{version: 7, name: "index links by last check", run: migrateCheckIndex},
+	{version: 8, name: "order tag index by save time", run: migrateTagIndexOrder},
+}
+
+// migrate applies all migrations newer than the stored schema version.
+func (r *BadgerRepository) migrate(ctx context.Context) error {
+	current, err := r.schemaVersion()
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		log := r.log.WithField("migration", m.name)
+		log.Info("Applying storage migration")
+		if err := m.run(ctx, r); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		err := r.db.Update(func(txn *badger.Txn) error {
+			return txn.Set(schemaVersionKey, []byte(strconv.Itoa(m.version)))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record schema version %d: %w", m.version, err)
+		}
+		log.Info("Storage migration applied")
+	}
+	return nil
+}
+
+// schemaVersion returns the stored schema version, or 0 for a fresh or legacy database.
+func (r *BadgerRepository) schemaVersion() (int, error) {
+	var version int
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(schemaVersionKey)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			version, err = strconv.Atoi(string(val))
+			return err
+		})
+	})
+	return version, err
+}
+
+// storedLink is a link together with the key it is stored under.
+type storedLink struct {
+	key  []byte
+	link domain.Link
+}
+
+// allLinks loads every stored link across all users.
+// It is only meant for migrations and other maintenance tasks.
+func (r *BadgerRepository) allLinks() ([]storedLink, error) {
+	var links []storedLink
+	err := r.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("user:")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			if !isLinkKey(item.Key()) {
+				continue
+			}
+			err := item.Value(func(val []byte) error {
+				var link domain.Link
+				if err := json.Unmarshal(val, &link); err != nil {
+					return fmt.Errorf("failed to unmarshal link data for key %s: %w", string(item.Key()), err)
+				}
+				links = append(links, storedLink{key: item.KeyCopy(nil), link: link})
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return links, err
+}
+
+// updateEachLink calls fn for every link in its own transaction, like
+// migrateSearchIndex, so rebuilding an index never exceeds Badger's
+// transaction size however many links are stored. fn must be idempotent:
+// a migration that failed halfway runs again from the start.
+func (r *BadgerRepository) updateEachLink(ctx context.Context, links []storedLink, fn func(txn *badger.Txn, stored storedLink) error) error {
+	for _, stored := range links {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := r.db.Update(func(txn *badger.Txn) error {
+			return fn(txn, stored)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isLinkKey reports whether key is a primary link key (user:{userID}:link:{url}).
+func isLinkKey(key []byte) bool {
+	rest, ok := strings.CutPrefix(string(key), "user:")
+	if !ok {
+		return false
+	}
+	userID, rest, ok := strings.Cut(rest, ":")
+	if !ok {
+		return false
+	}
+	if _, err := strconv.ParseInt(userID, 10, 64); err != nil {
+		return false
+	}
+	return strings.HasPrefix(rest, "link:")
+}
+
+// migrateLinkIDs assigns IDs to links saved before IDs existed and builds the ID index.
+func migrateLinkIDs(ctx context.Context, r *BadgerRepository) error {
+	links, err := r.allLinks()
+	if err != nil {
+		return err
+	}
+	for _, stored := range links {
+		if stored.link.ID != "" {
+			continue
+		}
+		link := stored.link
+		link.ID = newLinkID(link.URL)
+		err := r.db.Update(func(txn *badger.Txn) error {
+			linkBytes, err := json.Marshal(link)
+			if err != nil {
+				return err
+			}
+			if err := txn.Set(stored.key, linkBytes); err != nil {
+				return err
+			}
+			return txn.Set(generateLinkIDKey(link.UserID, link.ID), stored.key)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateCanonicalKeys moves links saved under their raw URL to the key of
+// their canonical URL. Links that turn out to be duplicates are merged: the
+// newer one wins, tags are combined and the link stays read if either was read.
+func migrateCanonicalKeys(ctx context.Context, r *BadgerRepository) error {
+	links, err := r.allLinks()
+	if err != nil {
+		return err
+	}
+	for _, stored := range links {
+		err := r.db.Update(func(txn *badger.Txn) error {
+			// Re-read the link: an earlier merge may already have rewritten it
+			link, err := getLink(txn, stored.key)
+			if errors.Is(err, ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			canon := canonicalURL(link)
+			newKey := generateLinkKey(link.UserID, canon)
+			if bytes.Equal(newKey, stored.key) && link.CanonicalURL == canon {
+				return nil
+			}
+
+			if link.ID != "" {
+				if err := txn.Delete(generateLinkIDKey(link.UserID, link.ID)); err != nil {
+					return err
+				}
+			}
+			if err := txn.Delete(stored.key); err != nil {
+				return err
+			}
+
+			link.CanonicalURL = canon
+			if !bytes.Equal(newKey, stored.key) {
+				existing, err := getLink(txn, newKey)
+				if err == nil {
+					link = mergeLinks(existing, link)
+				} else if !errors.Is(err, ErrNotFound) {
+					return err
+				}
+			}
+			return putLink(txn, &link)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateTimeIndex builds the time index of links saved before it existed.
+func migrateTimeIndex(ctx context.Context, r *BadgerRepository) error {
+	links, err := r.allLinks()
+	if err != nil {
+		return err
+	}
+	return r.updateEachLink(ctx, links, func(txn *badger.Txn, stored storedLink) error {
+		return txn.Set(generateTimeIndexKey(stored.link), stored.key)
+	})
+}
+
+// migrateSearchIndex indexes the content of links saved before search existed.
+// Each link gets its own transaction, since articles can add many postings.
+func migrateSearchIndex(ctx context.Context, r *BadgerRepository) error {
+	links, err := r.allLinks()
+	if err != nil {
+		return err
+	}
+	for _, stored := range links {
+		if stored.link.ID == "" {
+			continue
+		}
+		err := r.db.Update(func(txn *badger.Txn) error {
+			return indexLink(txn, stored.link)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateTagIndex builds the tag index of links saved before it existed.
+func migrateTagIndex(ctx context.Context, r *BadgerRepository) error {
+	links, err := r.allLinks()
+	if err != nil {
+		return err
+	}
+	return r.updateEachLink(ctx, links, func(txn *badger.Txn, stored storedLink) error {
+		for _, tagKey := range tagKeys(stored.link) {
+			if err := txn.Set(tagKey, stored.key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateTagIndexOrder replaces the tag index entries written before tags
+// were escaped and ordered by save time, keyed user:{userID}:tag:{tag}:{linkID}.
+func migrateTagIndexOrder(ctx context.Context, r *BadgerRepository) error {
+	links, err := r.allLinks()
+	if err != nil {
+		return err
+	}
+	return r.updateEachLink(ctx, links, func(txn *badger.Txn, stored storedLink) error {
+		for _, tag := range stored.link.Tags {
+			if tag = normalizeTag(tag); tag == "" {
+				continue
+			}
+			legacy := fmt.Sprintf("%s%s:%s", generateTagsPrefix(stored.link.UserID), tag, stored.link.ID)
+			if err := txn.Delete([]byte(legacy)); err != nil {
+				return err
+			}
+		}
+		for _, tagKey := range tagKeys(stored.link) {
+			if err := txn.Set(tagKey, stored.key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateCheckIndex builds the index of links by last check time for links
+// saved before it existed.
+func migrateCheckIndex(ctx context.Context, r *BadgerRepository) error {
+	links, err := r.allLinks()
+	if err != nil {
+		return err
+	}
+	return r.updateEachLink(ctx, links, func(txn *badger.Txn, stored storedLink) error {
+		if stored.link.Pending || stored.link.ID == "" {
+			return nil
+		}
+		return txn.Set(generateCheckKey(stored.link), stored.key)
+	})
+}
+
+// migrateCollectionOwners adds the owner of every collection created before
+// sharing existed to its members, with the owner role.
+func migrateCollectionOwners(ctx context.Context, r *BadgerRepository) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		var collections []domain.Collection
+		prefix := []byte("coll:")
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			// Collection records are the only keys without a second colon
+			if strings.Contains(string(it.Item().Key()[len(prefix):]), ":") {
+				continue
+			}
+			err := it.Item().Value(func(val []byte) error {
+				var c domain.Collection
+				if err := json.Unmarshal(val, &c); err != nil {
+					return fmt.Errorf("failed to unmarshal collection for key %s: %w", string(it.Item().Key()), err)
+				}
+				collections = append(collections, c)
+				return nil
+			})
+			if err != nil {
+				it.Close()
+				return err
+			}
+		}
+		it.Close()
+
+		for _, c := range collections {
+			owner := domain.CollectionMember{UserID: c.OwnerID, Role: domain.RoleOwner, JoinedAt: c.CreatedAt}
+			if err := putMember(txn, c.ID, owner); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// mergeLinks combines two stored copies of the same canonical link.
+// The result keeps the ID of existing, which is already indexed, and the
+// canonical URL of dup, which has just been computed.
+func mergeLinks(existing, dup domain.Link) domain.Link {
+	merged := existing
+	if dup.Timestamp.After(existing.Timestamp) {
+		merged = dup
+		merged.ID = existing.ID
+	}
+	merged.CanonicalURL = dup.CanonicalURL
+	merged.Read = existing.Read || dup.Read
+
+	seen := make(map[string]struct{})
+	merged.Tags = nil
+	for _, tag := range append(append([]string{}, existing.Tags...), dup.Tags...) {
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		merged.Tags = append(merged.Tags, tag)
+	}
+	return merged
+}
@@ -2,24 +2,281 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"jetengine/internal/domain"
+	"jetengine/internal/search"
+)
+
+var (
+	// ErrNotFound is returned when a requested link does not exist.
+	ErrNotFound = errors.New("link not found")
+
+	// ErrIDConflict is returned when a link ID is already taken by a different link.
+	ErrIDConflict = errors.New("link ID already in use")
+
+	// ErrTooManyLinks is returned when a change spans more links than fit
+	// into a single transaction.
+	ErrTooManyLinks = errors.New("too many links to change at once")
+
+	// ErrNoJob is returned by ClaimJob when no job is due.
+	ErrNoJob = errors.New("no job due")
+
+	// ErrCollectionNotFound is returned when a collection does not exist or
+	// the user has no access to it.
+	ErrCollectionNotFound = errors.New("collection not found")
+
+	// ErrCollectionExists is returned when a user already has a collection with the same name.
+	ErrCollectionExists = errors.New("collection already exists")
+
+	// ErrPermissionDenied is returned when a member's role in a collection
+	// does not allow the requested change.
+	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrMemberNotFound is returned when a user is not a member of a collection.
+	ErrMemberNotFound = errors.New("collection member not found")
+
+	// ErrInviteNotFound is returned when an invite does not exist, was
+	// already used or has expired.
+	ErrInviteNotFound = errors.New("invite not found")
+
+	// ErrTokenNotFound is returned when an API token does not exist, was
+	// revoked or has expired.
+	ErrTokenNotFound = errors.New("token not found")
+
+	// ErrSessionNotFound is returned when a browser session does not exist,
+	// has ended or has expired.
+	ErrSessionNotFound = errors.New("session not found")
 )
 
 // Repository defines the interface for data storage operations.
 // This allows us to swap storage implementations (e.g., BadgerDB, PostgreSQL)
 // without changing the core application logic that uses it.
 type Repository interface {
+	// TokenStore keeps the personal API tokens of users.
+	TokenStore
+	// SessionStore keeps the browser sessions of users.
+	SessionStore
+
 	// SaveLink stores a new link or updates an existing one for a specific user.
-	// The combination of UserID and link.URL should be unique.
-	SaveLink(ctx context.Context, link domain.Link) error
+	// Links are unique per UserID and canonical URL; the original URL is kept in link.URL.
+	// CanonicalURL, and a missing ID or Timestamp, are assigned and written back to link;
+	// tags are normalized (lower case, without "#", duplicates dropped).
+	SaveLink(ctx context.Context, link *domain.Link) error
+
+	// AddLink saves a link the user just sent. If the user saved the same page
+	// before, the stored link keeps its state and gains link.Tags, link is
+	// replaced by it, and true is returned.
+	AddLink(ctx context.Context, link *domain.Link) (bool, error)
+
+	// UpdateLink stores changes to an existing link, identified by link.ID.
+	// If its canonical URL changed, e.g. after following redirects, the link
+	// moves to the new URL; when the user already saved that URL, the existing
+	// link is updated instead and its ID is written back to link.
+	// It returns ErrNotFound if the link does not exist.
+	UpdateLink(ctx context.Context, link *domain.Link) error
+
+	// GetLinkByID retrieves a single link of a user by its short ID.
+	// It returns ErrNotFound if no such link exists.
+	GetLinkByID(ctx context.Context, userID int64, linkID string) (domain.Link, error)
 
 	// GetLinksByUser retrieves all links saved by a specific user, ordered perhaps by timestamp.
 	GetLinksByUser(ctx context.Context, userID int64) ([]domain.Link, error)
 
+	// ListLinks returns a page of a user's links that match opts, in the
+	// requested order. Pass the returned NextCursor in opts to get the next page.
+	// It returns ErrInvalidCursor if opts.Cursor is malformed.
+	ListLinks(ctx context.Context, userID int64, opts ListOptions) (LinkPage, error)
+
+	// SearchLinks returns a page of a user's links matching a search query,
+	// best matches first, starting at offset.
+	SearchLinks(ctx context.Context, userID int64, q search.Query, offset, limit int) (SearchResult, error)
+
+	// Tags returns all tags of a user with the number of links carrying them,
+	// most used first.
+	Tags(ctx context.Context, userID int64) ([]TagCount, error)
+
+	// TagLink adds and removes tags on a link and returns the updated link.
+	// It returns ErrNotFound if the link does not exist.
+	TagLink(ctx context.Context, userID int64, linkID string, add, remove []string) (domain.Link, error)
+
+	// SetTags replaces all tags of a link and returns the updated link.
+	// It returns ErrNotFound if the link does not exist.
+	SetTags(ctx context.Context, userID int64, linkID string, tags []string) (domain.Link, error)
+
+	// DismissTagSuggestions drops the open tag suggestions of a link and
+	// returns the updated link. It returns ErrNotFound if the link does not exist.
+	DismissTagSuggestions(ctx context.Context, userID int64, linkID string) (domain.Link, error)
+
+	// SetRead marks a link as read or unread and returns the updated link.
+	// It returns ErrNotFound if the link does not exist.
+	SetRead(ctx context.Context, userID int64, linkID string, read bool) (domain.Link, error)
+
+	// SetPending marks whether the metadata of a link is being fetched and
+	// returns the updated link. It returns ErrNotFound if the link does not exist.
+	SetPending(ctx context.Context, userID int64, linkID string, pending bool) (domain.Link, error)
+
+	// RenameTag renames a tag on all links of a user at once, merging it into
+	// the new tag if that is already in use, and returns the number of links changed.
+	// It returns ErrNotFound if no link has the old tag.
+	RenameTag(ctx context.Context, userID int64, from, to string) (int, error)
+
 	// DeleteLink removes a specific link for a given user.
+	// linkURL may be any spelling of the link that canonicalizes to the same URL.
 	DeleteLink(ctx context.Context, userID int64, linkURL string) error
 
+	// DeleteLinkByID removes a link of a user by its short ID.
+	// It returns ErrNotFound if no such link exists.
+	DeleteLinkByID(ctx context.Context, userID int64, linkID string) error
+
+	// SaveArticle stores the offline copy of a link's article, replacing any previous copy.
+	// It returns ErrNotFound if the link does not exist.
+	SaveArticle(ctx context.Context, article *domain.Article) error
+
+	// GetArticle retrieves the offline copy of a link's article.
+	// It returns ErrNotFound if no article was saved for the link.
+	GetArticle(ctx context.Context, userID int64, linkID string) (domain.Article, error)
+
+	// AddSnapshot records a snapshot on a link, replacing an earlier snapshot of
+	// the same kind, and returns the updated link.
+	// It returns ErrNotFound if the link does not exist.
+	AddSnapshot(ctx context.Context, userID int64, linkID string, snapshot domain.Snapshot) (domain.Link, error)
+
+	// SnapshotDigests returns the digests of the snapshots referenced by any
+	// link of any user.
+	SnapshotDigests(ctx context.Context) (map[string]bool, error)
+
+	// LinksToCheck returns up to limit links of all users that were last checked
+	// (or saved, if never checked) before the given time, least recently checked first.
+	// Links whose metadata is still being fetched are skipped.
+	LinksToCheck(ctx context.Context, before time.Time, limit int) ([]domain.Link, error)
+
+	// CreateCollection creates a collection owned by c.OwnerID, assigning its ID.
+	// It returns ErrCollectionExists if the owner already has one with that name.
+	CreateCollection(ctx context.Context, c *domain.Collection) error
+
+	// Collections can be shared: every call checks the role of the user in the
+	// collection. Collections the user is no member of are reported as
+	// ErrCollectionNotFound; a role that is too low gives ErrPermissionDenied.
+
+	// GetCollection retrieves a collection the user has access to, with the user's role.
+	// It returns ErrCollectionNotFound otherwise.
+	GetCollection(ctx context.Context, userID int64, collectionID string) (domain.Collection, error)
+
+	// FindCollection looks up one of the user's collections by ID or name.
+	// It returns ErrCollectionNotFound if none matches.
+	FindCollection(ctx context.Context, userID int64, nameOrID string) (domain.Collection, error)
+
+	// ListCollections returns the collections a user has access to, sorted by name.
+	ListCollections(ctx context.Context, userID int64) ([]domain.Collection, error)
+
+	// RenameCollection changes the name of a collection. It requires the owner role.
+	RenameCollection(ctx context.Context, userID int64, collectionID, name string) error
+
+	// DeleteCollection removes a collection; its links are kept. It requires the owner role.
+	DeleteCollection(ctx context.Context, userID int64, collectionID string) error
+
+	// AddToCollection adds one of the user's links to a collection and returns
+	// the updated collection. It requires the editor role.
+	AddToCollection(ctx context.Context, userID int64, collectionID, linkID string) (domain.Collection, error)
+
+	// RemoveFromCollection removes a link added by any member from a collection
+	// and returns the updated collection. It requires the editor role.
+	RemoveFromCollection(ctx context.Context, userID int64, collectionID, linkID string) (domain.Collection, error)
+
+	// ListCollectionLinks returns a page of the links in a collection, most recently added first.
+	ListCollectionLinks(ctx context.Context, userID int64, collectionID string, opts ListOptions) (LinkPage, error)
+
+	// CreateCollectionInvite creates a one-time invite to join a collection
+	// with the given role, valid for ttl. It requires the owner role.
+	CreateCollectionInvite(ctx context.Context, userID int64, collectionID string, role domain.CollectionRole, ttl time.Duration) (domain.CollectionInvite, error)
+
+	// AcceptCollectionInvite redeems an invite, making the user a member of its
+	// collection under the given display name, and returns the collection.
+	// It returns ErrInviteNotFound if the invite was used or has expired.
+	AcceptCollectionInvite(ctx context.Context, userID int64, name, token string) (domain.Collection, error)
+
+	// ListCollectionMembers returns the members of a collection, owner first.
+	ListCollectionMembers(ctx context.Context, userID int64, collectionID string) ([]domain.CollectionMember, error)
+
+	// SetCollectionMemberRole changes the role of a member. It requires the owner role.
+	// It returns ErrMemberNotFound if memberID is not a member.
+	SetCollectionMemberRole(ctx context.Context, userID int64, collectionID string, memberID int64, role domain.CollectionRole) error
+
+	// RemoveCollectionMember removes a member and the links they added from a
+	// collection. Owners may remove anyone else; other members only themselves.
+	// It returns ErrMemberNotFound if memberID is not a member.
+	RemoveCollectionMember(ctx context.Context, userID int64, collectionID string, memberID int64) error
+
 	// Close gracefully shuts down the repository connection.
 	Close() error
 }
+
+// JobQueue is a persistent queue of background scrape jobs. Due jobs are
+// claimed in order of their RunAt time.
+type JobQueue interface {
+	// EnqueueJob adds a pending job, assigning its ID and timestamps.
+	// A zero RunAt makes the job due right away.
+	EnqueueJob(ctx context.Context, job *domain.ScrapeJob) error
+
+	// ClaimJob marks the earliest job that is due at now as running until
+	// now+lease, counts the attempt and returns the job.
+	// It returns ErrNoJob if no job is due.
+	ClaimJob(ctx context.Context, now time.Time, lease time.Duration) (domain.ScrapeJob, error)
+
+	// UpdateJob stores a changed job, e.g. one rescheduled for a retry.
+	// Dead jobs are kept for inspection but never claimed again.
+	// It returns ErrNotFound if the job does not exist.
+	UpdateJob(ctx context.Context, job *domain.ScrapeJob) error
+
+	// DeleteJob removes a finished job. Deleting a missing job is not an error.
+	DeleteJob(ctx context.Context, id string) error
+
+	// DeadJobs lists the jobs that were given up, oldest first.
+	DeadJobs(ctx context.Context) ([]domain.ScrapeJob, error)
+}
+
+// TokenStore keeps the personal API tokens of users. Only hashes of the
+// token secrets are stored.
+type TokenStore interface {
+	// CreateToken stores a new token, assigning its ID and creation time, and
+	// returns its secret, which cannot be retrieved again.
+	CreateToken(ctx context.Context, token *domain.APIToken) (string, error)
+
+	// AuthenticateToken returns the token with the given secret and records its use.
+	// It returns ErrTokenNotFound if the token does not exist, was revoked or has expired.
+	AuthenticateToken(ctx context.Context, secret string) (domain.APIToken, error)
+
+	// ListTokens returns the unexpired tokens of a user, newest first.
+	ListTokens(ctx context.Context, userID int64) ([]domain.APIToken, error)
+
+	// RevokeToken deletes a token of a user.
+	// It returns ErrTokenNotFound if the user has no token with that ID.
+	RevokeToken(ctx context.Context, userID int64, tokenID string) error
+}
+
+// SessionStore keeps the browser sessions of users logged in through
+// Telegram. Only hashes of the session IDs are stored.
+type SessionStore interface {
+	// CreateSession stores a new session lasting until session.ExpiresAt,
+	// assigning its CSRF token and creation time, and returns its ID.
+	CreateSession(ctx context.Context, session *domain.Session) (string, error)
+
+	// GetSession returns the session with the given ID.
+	// It returns ErrSessionNotFound if it does not exist, has ended or has expired.
+	GetSession(ctx context.Context, id string) (domain.Session, error)
+
+	// DeleteSession ends a session. Ending a missing session is not an error.
+	DeleteSession(ctx context.Context, id string) error
+}
+
+// Cache stores short-lived values shared by all users, such as scrape results.
+type Cache interface {
+	// GetCache returns a cached value.
+	// It returns ErrNotFound if the value is missing or expired.
+	GetCache(ctx context.Context, key string) ([]byte, error)
+
+	// SetCache stores a value that expires after ttl.
+	SetCache(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
@@ -0,0 +1,337 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/search"
+)
+
+// SearchResult is one page of links matching a search query.
+type SearchResult struct {
+	Links []domain.Link
+	// Total is the number of matching links across all pages.
+	Total int
+}
+
+// generateTermKey creates the posting key of a term in a link, holding the
+// weight of the term in that link. All links containing a term share a prefix.
+// Format: user:{userID}:term:{term}:{linkID}
+func generateTermKey(userID int64, term, linkID string) []byte {
+	return []byte(fmt.Sprintf("%s%s", generateTermPrefix(userID, term), linkID))
+}
+
+// generateTermPrefix creates the key prefix of the postings of a term.
+// Format: user:{userID}:term:{term}:
+func generateTermPrefix(userID int64, term string) []byte {
+	return []byte(fmt.Sprintf("user:%d:term:%s:", userID, term))
+}
+
+// generateDocKey creates the key listing the indexed terms of a link and
+// their weights, so its postings can be updated and removed.
+// Format: user:{userID}:doc:{linkID}
+func generateDocKey(userID int64, linkID string) []byte {
+	return []byte(fmt.Sprintf("%s%s", generateDocPrefix(userID), linkID))
+}
+
+// generateDocPrefix creates the key prefix of the indexed links of a user.
+// Format: user:{userID}:doc:
+func generateDocPrefix(userID int64) []byte {
+	return []byte(fmt.Sprintf("user:%d:doc:", userID))
+}
+
+// indexLink updates the search postings of a link to its current content,
+// including the text of its article if one is stored. Postings that did not
+// change are left alone, so small edits like marking a link read stay cheap.
+func indexLink(txn *badger.Txn, link domain.Link) error {
+	fields := []search.Field{
+		{Text: link.Title, Weight: search.WeightTitle},
+		{Text: strings.Join(link.Tags, " "), Weight: search.WeightTags},
+		{Text: link.SiteName, Weight: search.WeightSiteName},
+		{Text: search.URLText(canonicalURL(link)), Weight: search.WeightURL},
+		{Text: link.Description, Weight: search.WeightDescription},
+	}
+	article, err := getArticle(txn, link.UserID, link.ID)
+	switch {
+	case err == nil:
+		fields = append(fields, search.Field{Text: article.Text, Weight: search.WeightArticle})
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+	weights := search.Weights(fields...)
+
+	old, err := getDocWeights(txn, link.UserID, link.ID)
+	if err != nil {
+		return err
+	}
+	if maps.Equal(old, weights) && old != nil {
+		return nil
+	}
+	for term := range old {
+		if _, ok := weights[term]; !ok {
+			if err := txn.Delete(generateTermKey(link.UserID, term, link.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	for term, weight := range weights {
+		if w, ok := old[term]; ok && w == weight {
+			continue
+		}
+		value := strconv.FormatFloat(weight, 'g', -1, 64)
+		if err := txn.Set(generateTermKey(link.UserID, term, link.ID), []byte(value)); err != nil {
+			return err
+		}
+	}
+
+	docBytes, err := json.Marshal(weights)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search terms: %w", err)
+	}
+	return txn.Set(generateDocKey(link.UserID, link.ID), docBytes)
+}
+
+// unindexSearch removes all search postings of a link.
+func unindexSearch(txn *badger.Txn, userID int64, linkID string) error {
+	weights, err := getDocWeights(txn, userID, linkID)
+	if err != nil {
+		return err
+	}
+	for term := range weights {
+		if err := txn.Delete(generateTermKey(userID, term, linkID)); err != nil {
+			return err
+		}
+	}
+	return txn.Delete(generateDocKey(userID, linkID))
+}
+
+// getDocWeights reads the indexed term weights of a link, or nil if it is not indexed.
+func getDocWeights(txn *badger.Txn, userID int64, linkID string) (map[string]float64, error) {
+	item, err := txn.Get(generateDocKey(userID, linkID))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var weights map[string]float64
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &weights)
+	})
+	return weights, err
+}
+
+// getArticle reads the article of a link within a transaction.
+// It returns ErrNotFound if the link has none.
+func getArticle(txn *badger.Txn, userID int64, linkID string) (domain.Article, error) {
+	var article domain.Article
+	item, err := txn.Get(generateArticleKey(userID, linkID))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return article, ErrNotFound
+	}
+	if err != nil {
+		return article, err
+	}
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &article)
+	})
+	return article, err
+}
+
+// SearchLinks returns a page of a user's links matching q. Links must contain
+// every term of the query and pass its tag: and site: filters. Results are
+// ranked by TF-IDF, with field weights favoring titles and tags; a query with
+// only filters lists the matching links newest first.
+func (r *BadgerRepository) SearchLinks(ctx context.Context, userID int64, q search.Query, offset, limit int) (SearchResult, error) {
+	log := r.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"query":   q.String(),
+	})
+
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	limit = min(limit, maxListLimit)
+	offset = max(offset, 0)
+
+	type hit struct {
+		link  domain.Link
+		score float64
+	}
+	var hits []hit
+	err := r.db.View(func(txn *badger.Txn) error {
+		// The IDs of the links with every tag of the query, nil without tag filters
+		var tagged map[string]bool
+		for _, tag := range q.Tags {
+			ids := taggedLinkIDs(txn, userID, tag)
+			if tagged != nil {
+				maps.DeleteFunc(ids, func(id string, _ bool) bool { return !tagged[id] })
+			}
+			tagged = ids
+		}
+		keep := func(link domain.Link) bool {
+			if tagged != nil && !tagged[link.ID] {
+				return false
+			}
+			if len(q.Sites) == 0 {
+				return true
+			}
+			for _, site := range q.Sites {
+				if onDomain(link, normalizeDomain(site)) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(q.Terms) == 0 {
+			// Without terms, list newest first from the time or tag index
+			prefix := generateTimeIndexPrefix(userID)
+			if len(q.Tags) > 0 {
+				prefix = generateTagPrefix(userID, normalizeTag(q.Tags[0]))
+			}
+			it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, Reverse: true})
+			defer it.Close()
+			for it.Seek(append(bytes.Clone(prefix), 0xFF)); it.ValidForPrefix(prefix); it.Next() {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				key, err := it.Item().ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				link, err := getLink(txn, key)
+				if errors.Is(err, ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				if keep(link) {
+					hits = append(hits, hit{link: link})
+				}
+			}
+			return nil
+		}
+
+		scores, err := scoreTerms(ctx, txn, userID, q.Terms)
+		if err != nil {
+			return err
+		}
+		for id, score := range scores {
+			if tagged != nil && !tagged[id] {
+				continue
+			}
+			key, err := lookupLinkKey(txn, userID, id)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			link, err := getLink(txn, key)
+			if err != nil {
+				return err
+			}
+			if keep(link) {
+				hits = append(hits, hit{link: link, score: score})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to search links")
+		return SearchResult{}, fmt.Errorf("failed to search links for user %d: %w", userID, err)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].link.Timestamp.After(hits[j].link.Timestamp)
+	})
+
+	result := SearchResult{Total: len(hits)}
+	for _, h := range hits[min(offset, len(hits)):min(offset+limit, len(hits))] {
+		result.Links = append(result.Links, h.link)
+	}
+	log.WithField("total", result.Total).Debug("Searched links")
+	return result, nil
+}
+
+// scoreTerms returns the TF-IDF score of every link of a user that contains
+// all terms, by intersecting their posting lists.
+func scoreTerms(ctx context.Context, txn *badger.Txn, userID int64, terms []string) (map[string]float64, error) {
+	docs := countKeys(txn, generateDocPrefix(userID))
+
+	var scores map[string]float64
+	for _, term := range slices.Compact(slices.Sorted(slices.Values(terms))) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		postings, err := readPostings(txn, generateTermPrefix(userID, term))
+		if err != nil {
+			return nil, err
+		}
+		idf := search.IDF(docs, len(postings))
+
+		next := make(map[string]float64)
+		for id, weight := range postings {
+			if scores == nil {
+				next[id] = weight * idf
+			} else if score, ok := scores[id]; ok {
+				next[id] = score + weight*idf
+			}
+		}
+		scores = next
+		if len(scores) == 0 {
+			break
+		}
+	}
+	return scores, nil
+}
+
+// readPostings reads the link IDs and weights stored under a term prefix.
+func readPostings(txn *badger.Txn, prefix []byte) (map[string]float64, error) {
+	postings := make(map[string]float64)
+	it := txn.NewIterator(badger.IteratorOptions{PrefetchValues: true, PrefetchSize: 100, Prefix: prefix})
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		item := it.Item()
+		err := item.Value(func(val []byte) error {
+			weight, err := strconv.ParseFloat(string(val), 64)
+			if err != nil {
+				return fmt.Errorf("malformed posting %s: %w", string(item.Key()), err)
+			}
+			postings[string(item.Key()[len(prefix):])] = weight
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return postings, nil
+}
+
+// countKeys counts the keys with a prefix without reading their values.
+func countKeys(txn *badger.Txn, prefix []byte) int {
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+	defer it.Close()
+	n := 0
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"jetengine/internal/domain"
+)
+
+// generateSessionKey creates the key of a browser session, addressed by the
+// hex SHA-256 of its ID so the cookie value itself is never stored.
+// Format: session:{hash}
+func generateSessionKey(hash string) []byte {
+	return []byte("session:" + hash)
+}
+
+// CreateSession stores a new session that lasts until session.ExpiresAt,
+// assigning its CSRF token and creation time, and returns the session ID to
+// hand to the browser.
+func (r *BadgerRepository) CreateSession(ctx context.Context, session *domain.Session) (string, error) {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return "", fmt.Errorf("session of user %d would expire immediately", session.UserID)
+	}
+	id, err := randomString(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	csrf, err := randomString(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate CSRF token: %w", err)
+	}
+	session.CSRFToken = csrf
+	session.CreatedAt = time.Now()
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal session: %w", err)
+	}
+	err = r.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry(generateSessionKey(hashToken(id)), data).WithTTL(ttl))
+	})
+	if err != nil {
+		r.log.WithError(err).WithField("user_id", session.UserID).Error("Failed to create session")
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	r.log.WithField("user_id", session.UserID).Info("Session created")
+	return id, nil
+}
+
+// GetSession returns the session with the given ID.
+// It returns ErrSessionNotFound if it does not exist, has ended or has expired.
+func (r *BadgerRepository) GetSession(ctx context.Context, id string) (domain.Session, error) {
+	var session domain.Session
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(generateSessionKey(hashToken(id)))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return ErrSessionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &session)
+		})
+	})
+	if err == nil && session.Expired(time.Now()) {
+		err = ErrSessionNotFound
+	}
+	if err != nil {
+		return domain.Session{}, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+// DeleteSession ends the session with the given ID. Ending a missing
+// session is not an error.
+func (r *BadgerRepository) DeleteSession(ctx context.Context, id string) error {
+	err := r.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(generateSessionKey(hashToken(id)))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
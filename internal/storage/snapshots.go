@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+)
+
+// AddSnapshot records a snapshot on a link, replacing an earlier snapshot of
+// the same kind, and returns the updated link.
+// It returns ErrNotFound if the link does not exist.
+func (r *BadgerRepository) AddSnapshot(ctx context.Context, userID int64, linkID string, snapshot domain.Snapshot) (domain.Link, error) {
+	log := r.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"link_id": linkID,
+		"kind":    snapshot.Kind,
+	})
+
+	var link domain.Link
+	err := r.db.Update(func(txn *badger.Txn) error {
+		key, err := lookupLinkKey(txn, userID, linkID)
+		if err != nil {
+			return err
+		}
+		link, err = getLink(txn, key)
+		if err != nil {
+			return err
+		}
+
+		snapshots := []domain.Snapshot{snapshot}
+		for _, s := range link.Snapshots {
+			if s.Kind != snapshot.Kind {
+				snapshots = append(snapshots, s)
+			}
+		}
+		link.Snapshots = snapshots
+		return putLink(txn, &link)
+	})
+
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.WithError(err).Error("Failed to add snapshot in BadgerDB")
+		}
+		return domain.Link{}, fmt.Errorf("failed to add snapshot to link %s: %w", linkID, err)
+	}
+
+	log.WithField("digest", snapshot.Digest).Info("Snapshot added successfully")
+	return link, nil
+}
+
+// SnapshotDigests returns the digests of the snapshots referenced by any link
+// of any user, so that blobs missing from it can be deleted.
+func (r *BadgerRepository) SnapshotDigests(ctx context.Context) (map[string]bool, error) {
+	digests := make(map[string]bool)
+	err := r.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("user:")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			item := it.Item()
+			if !isLinkKey(item.Key()) {
+				continue
+			}
+			err := item.Value(func(val []byte) error {
+				var link struct {
+					Snapshots []domain.Snapshot `json:"snapshots"`
+				}
+				if err := json.Unmarshal(val, &link); err != nil {
+					return fmt.Errorf("failed to unmarshal link data for key %s: %w", string(item.Key()), err)
+				}
+				for _, s := range link.Snapshots {
+					digests[s.Digest] = true
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		r.log.WithError(err).Error("Failed to collect snapshot digests")
+		return nil, fmt.Errorf("failed to collect snapshot digests: %w", err)
+	}
+	return digests, nil
+}
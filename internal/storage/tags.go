@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+)
+
+// TagCount is a tag together with the number of links carrying it.
+type TagCount struct {
+	Tag   string
+	Links int
+}
+
+// generateTagKey creates the secondary index key of a tag on a link, pointing
+// to the link's primary key. All links with a tag share a prefix and are
+// ordered by the time they were saved, like the time index.
+// Format: user:{userID}:tag:{escapedTag}:{timestamp}:{linkID}
+func generateTagKey(link domain.Link, tag string) []byte {
+	return []byte(fmt.Sprintf("%s%020d:%s", generateTagPrefix(link.UserID, tag), max(link.Timestamp.UnixNano(), 0), link.ID))
+}
+
+// generateTagPrefix creates the key prefix of the links with a tag. The tag
+// is escaped, so a tag is never the prefix of another one.
+// Format: user:{userID}:tag:{escapedTag}:
+func generateTagPrefix(userID int64, tag string) []byte {
+	return []byte(fmt.Sprintf("%s%s:", generateTagsPrefix(userID), tagKeyEscaper.Replace(tag)))
+}
+
+// generateTagsPrefix creates the key prefix of a user's whole tag index.
+// Format: user:{userID}:tag:
+func generateTagsPrefix(userID int64) []byte {
+	return []byte(fmt.Sprintf("user:%d:tag:", userID))
+}
+
+// tagKeyEscaper escapes the ':' separating key parts in tags, and '%' so
+// that escaping can be undone by tagKeyUnescaper.
+var (
+	tagKeyEscaper   = strings.NewReplacer("%", "%25", ":", "%3A")
+	tagKeyUnescaper = strings.NewReplacer("%25", "%", "%3A", ":")
+)
+
+// normalizeTag lowercases a tag and strips leading '#' characters, the form
+// in which tags are indexed and compared.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimLeft(strings.TrimSpace(tag), "#"))
+}
+
+// tagKeys returns the tag index keys of a link.
+func tagKeys(link domain.Link) [][]byte {
+	var keys [][]byte
+	for _, tag := range link.Tags {
+		if tag = normalizeTag(tag); tag != "" {
+			keys = append(keys, generateTagKey(link, tag))
+		}
+	}
+	return keys
+}
+
+// Tags returns all tags of a user with the number of links carrying them,
+// most used first. It only reads the tag index.
+func (r *BadgerRepository) Tags(ctx context.Context, userID int64) ([]TagCount, error) {
+	counts := make(map[string]int)
+	err := r.db.View(func(txn *badger.Txn) error {
+		prefix := generateTagsPrefix(userID)
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		defer it.Close()
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			// Escaped tags never contain ':'
+			rest := it.Item().Key()[len(prefix):]
+			if i := bytes.IndexByte(rest, ':'); i > 0 {
+				counts[tagKeyUnescaper.Replace(string(rest[:i]))]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		r.log.WithError(err).WithField("user_id", userID).Error("Failed to read tag index")
+		return nil, fmt.Errorf("failed to get tags for user %d: %w", userID, err)
+	}
+
+	tags := make([]TagCount, 0, len(counts))
+	for tag, n := range counts {
+		tags = append(tags, TagCount{Tag: tag, Links: n})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Links != tags[j].Links {
+			return tags[i].Links > tags[j].Links
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+// TagLink adds and removes tags on a link in one transaction and returns the
+// updated link. Tags are normalized; adding a tag the link already has is a no-op.
+// It returns ErrNotFound if the link does not exist.
+func (r *BadgerRepository) TagLink(ctx context.Context, userID int64, linkID string, add, remove []string) (domain.Link, error) {
+	link, err := r.modifyLink(userID, linkID, func(link *domain.Link) {
+		drop := make(map[string]bool)
+		for _, tag := range remove {
+			drop[normalizeTag(tag)] = true
+		}
+		link.Tags = slices.DeleteFunc(link.Tags, func(tag string) bool {
+			return drop[normalizeTag(tag)]
+		})
+		link.Tags = mergeTags(link.Tags, add)
+	})
+	if err != nil {
+		return domain.Link{}, fmt.Errorf("failed to tag link %s: %w", linkID, err)
+	}
+	r.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"link_id": linkID,
+		"tags":    link.Tags,
+	}).Info("Link tags updated")
+	return link, nil
+}
+
+// SetTags replaces all tags of a link in one transaction and returns the
+// updated link. Tags are normalized. It returns ErrNotFound if the link does not exist.
+func (r *BadgerRepository) SetTags(ctx context.Context, userID int64, linkID string, tags []string) (domain.Link, error) {
+	link, err := r.modifyLink(userID, linkID, func(link *domain.Link) {
+		link.Tags = mergeTags(nil, tags)
+	})
+	if err != nil {
+		return domain.Link{}, fmt.Errorf("failed to set tags of link %s: %w", linkID, err)
+	}
+	r.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"link_id": linkID,
+		"tags":    link.Tags,
+	}).Info("Link tags updated")
+	return link, nil
+}
+
+// DismissTagSuggestions drops the open tag suggestions of a link and returns
+// the updated link. It returns ErrNotFound if the link does not exist.
+func (r *BadgerRepository) DismissTagSuggestions(ctx context.Context, userID int64, linkID string) (domain.Link, error) {
+	link, err := r.modifyLink(userID, linkID, func(link *domain.Link) { link.SuggestedTags = nil })
+	if err != nil {
+		return domain.Link{}, fmt.Errorf("failed to dismiss tag suggestions of link %s: %w", linkID, err)
+	}
+	return link, nil
+}
+
+// RenameTag replaces a tag with another on all links of a user in a single
+// transaction and returns the number of links changed. If the new tag is
+// already in use the two are merged. It returns ErrNotFound if no link has
+// the old tag and ErrTooManyLinks if the change does not fit into one
+// transaction.
+func (r *BadgerRepository) RenameTag(ctx context.Context, userID int64, from, to string) (int, error) {
+	from, to = normalizeTag(from), normalizeTag(to)
+	log := r.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"from":    from,
+		"to":      to,
+	})
+	if from == "" || to == "" {
+		return 0, fmt.Errorf("failed to rename tag: tags must not be empty")
+	}
+
+	changed := 0
+	err := r.db.Update(func(txn *badger.Txn) error {
+		// Collect the links first: putLink rewrites the index being scanned
+		var keys [][]byte
+		prefix := generateTagPrefix(userID, from)
+		it := txn.NewIterator(badger.IteratorOptions{PrefetchValues: true, Prefix: prefix})
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			key, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				it.Close()
+				return err
+			}
+			keys = append(keys, key)
+		}
+		it.Close()
+		if len(keys) == 0 {
+			return ErrNotFound
+		}
+
+		for _, key := range keys {
+			link, err := getLink(txn, key)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := retagLink(txn, key, link, from, to); err != nil {
+				return err
+			}
+			changed++
+		}
+		return nil
+	})
+	if errors.Is(err, badger.ErrTxnTooBig) {
+		err = ErrTooManyLinks
+	}
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.WithError(err).Error("Failed to rename tag")
+		}
+		return 0, fmt.Errorf("failed to rename tag %s: %w", from, err)
+	}
+	log.WithField("link_count", changed).Info("Tag renamed")
+	return changed, nil
+}
+
+// retagLink replaces the tag from with to on a stored link. Unlike putLink it
+// only writes the link itself, its two tag index entries and the search
+// postings of the changed terms, so renames across many links stay small.
+func retagLink(txn *badger.Txn, key []byte, link domain.Link, from, to string) error {
+	if err := txn.Delete(generateTagKey(link, from)); err != nil {
+		return err
+	}
+	if !hasTag(link, to) {
+		if err := txn.Set(generateTagKey(link, to), key); err != nil {
+			return err
+		}
+	}
+	link.Tags = slices.DeleteFunc(link.Tags, func(tag string) bool {
+		return normalizeTag(tag) == from
+	})
+	link.Tags = mergeTags(link.Tags, []string{to})
+
+	linkBytes, err := json.Marshal(link)
+	if err != nil {
+		return fmt.Errorf("failed to marshal link: %w", err)
+	}
+	if err := txn.Set(key, linkBytes); err != nil {
+		return err
+	}
+	return indexLink(txn, link)
+}
+
+// mergeTags appends the normalized tags of add to tags, skipping duplicates.
+func mergeTags(tags, add []string) []string {
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		seen[normalizeTag(tag)] = true
+	}
+	for _, tag := range add {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// taggedLinkIDs returns the IDs of the links of a user carrying a tag, read
+// from the tag index.
+func taggedLinkIDs(txn *badger.Txn, userID int64, tag string) map[string]bool {
+	ids := make(map[string]bool)
+	prefix := generateTagPrefix(userID, normalizeTag(tag))
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: false})
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		key := it.Item().Key()
+		ids[string(key[bytes.LastIndexByte(key, ':')+1:])] = true
+	}
+	return ids
+}
@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/sirupsen/logrus"
+
+	"jetengine/internal/domain"
+)
+
+// tokenPrefix starts every token secret, so leaked tokens are easy to spot.
+const tokenPrefix = "jet_"
+
+// tokenTouchInterval limits how often LastUsedAt is written for a token.
+const tokenTouchInterval = time.Minute
+
+// generateTokenKey creates the key of a token record, addressed by the hex
+// SHA-256 of its secret so the secret itself is never stored.
+// Format: token:{hash}
+func generateTokenKey(hash string) []byte {
+	return []byte("token:" + hash)
+}
+
+// generateUserTokenKey creates the key listing a token of a user, holding the hash of its secret.
+// Format: user:{userID}:token:{tokenID}
+func generateUserTokenKey(userID int64, tokenID string) []byte {
+	return []byte(fmt.Sprintf("%s%s", generateUserTokensPrefix(userID), tokenID))
+}
+
+// generateUserTokensPrefix creates the key prefix of a user's tokens.
+// Format: user:{userID}:token:
+func generateUserTokensPrefix(userID int64) []byte {
+	return []byte(fmt.Sprintf("user:%d:token:", userID))
+}
+
+// hashToken returns the hex SHA-256 of a token secret. Secrets are random, so
+// a fast hash without salt is enough to make a stolen database useless.
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes encoded as unpadded base64url.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// putToken writes a token record, expiring it with the token.
+func putToken(txn *badger.Txn, hash string, token domain.APIToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	entry := badger.NewEntry(generateTokenKey(hash), data)
+	if !token.ExpiresAt.IsZero() {
+		entry = entry.WithTTL(time.Until(token.ExpiresAt))
+	}
+	return txn.SetEntry(entry)
+}
+
+// getToken reads a token record by the hash of its secret.
+// It returns ErrTokenNotFound if there is none.
+func getToken(txn *badger.Txn, hash string) (domain.APIToken, error) {
+	var token domain.APIToken
+	item, err := txn.Get(generateTokenKey(hash))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return token, ErrTokenNotFound
+	}
+	if err != nil {
+		return token, err
+	}
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &token)
+	})
+	return token, err
+}
+
+// CreateToken stores a new token for token.UserID, assigning its ID and
+// creation time, and returns the secret. The secret is not stored and cannot
+// be retrieved again.
+func (r *BadgerRepository) CreateToken(ctx context.Context, token *domain.APIToken) (string, error) {
+	log := r.log.WithFields(logrus.Fields{
+		"user_id": token.UserID,
+		"name":    token.Name,
+	})
+
+	id, err := randomString(6)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret = tokenPrefix + secret
+	token.ID = id
+	token.CreatedAt = time.Now()
+	token.LastUsedAt = time.Time{}
+
+	hash := hashToken(secret)
+	err = r.db.Update(func(txn *badger.Txn) error {
+		if err := putToken(txn, hash, *token); err != nil {
+			return err
+		}
+		entry := badger.NewEntry(generateUserTokenKey(token.UserID, token.ID), []byte(hash))
+		if !token.ExpiresAt.IsZero() {
+			entry = entry.WithTTL(time.Until(token.ExpiresAt))
+		}
+		return txn.SetEntry(entry)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to create token")
+		return "", fmt.Errorf("failed to create token: %w", err)
+	}
+	log.WithField("token_id", token.ID).Info("API token created")
+	return secret, nil
+}
+
+// AuthenticateToken returns the token with the given secret and records its use.
+// It returns ErrTokenNotFound if the token does not exist, was revoked or has expired.
+func (r *BadgerRepository) AuthenticateToken(ctx context.Context, secret string) (domain.APIToken, error) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return domain.APIToken{}, ErrTokenNotFound
+	}
+	hash := hashToken(secret)
+	now := time.Now()
+
+	var token domain.APIToken
+	err := r.db.View(func(txn *badger.Txn) error {
+		var err error
+		token, err = getToken(txn, hash)
+		return err
+	})
+	if err == nil && token.Expired(now) {
+		err = ErrTokenNotFound
+	}
+	if err != nil {
+		return domain.APIToken{}, fmt.Errorf("failed to authenticate token: %w", err)
+	}
+
+	if now.Sub(token.LastUsedAt) >= tokenTouchInterval {
+		token.LastUsedAt = now
+		err := r.db.Update(func(txn *badger.Txn) error {
+			// A token revoked meanwhile must not come back
+			if _, err := getToken(txn, hash); err != nil {
+				return err
+			}
+			return putToken(txn, hash, token)
+		})
+		if err != nil && !errors.Is(err, ErrTokenNotFound) && !errors.Is(err, badger.ErrConflict) {
+			r.log.WithError(err).WithField("token_id", token.ID).Warn("Failed to record token use")
+		}
+	}
+	return token, nil
+}
+
+// ListTokens returns the unexpired tokens of a user, newest first.
+func (r *BadgerRepository) ListTokens(ctx context.Context, userID int64) ([]domain.APIToken, error) {
+	now := time.Now()
+	var tokens []domain.APIToken
+	err := r.db.View(func(txn *badger.Txn) error {
+		prefix := generateUserTokensPrefix(userID)
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true})
+		defer it.Close()
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			hash, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			token, err := getToken(txn, string(hash))
+			if errors.Is(err, ErrTokenNotFound) {
+				// Expired tokens are dropped by Badger
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if !token.Expired(now) {
+				tokens = append(tokens, token)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		r.log.WithError(err).WithField("user_id", userID).Error("Failed to list tokens")
+		return nil, fmt.Errorf("failed to list tokens of user %d: %w", userID, err)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+// RevokeToken deletes a token of a user, which stops working immediately.
+// It returns ErrTokenNotFound if the user has no token with that ID.
+func (r *BadgerRepository) RevokeToken(ctx context.Context, userID int64, tokenID string) error {
+	err := r.db.Update(func(txn *badger.Txn) error {
+		key := generateUserTokenKey(userID, tokenID)
+		item, err := txn.Get(key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return ErrTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+		hash, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if err := txn.Delete(generateTokenKey(string(hash))); err != nil {
+			return err
+		}
+		return txn.Delete(key)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke token %s: %w", tokenID, err)
+	}
+	r.log.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"token_id": tokenID,
+	}).Info("API token revoked")
+	return nil
+}
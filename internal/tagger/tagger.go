@@ -0,0 +1,213 @@
+// Package tagger suggests tags for a saved link without calling any external
+// service. It learns from the tags a user already gave to their other links:
+// each tag is represented by the TF-IDF centroid of the links carrying it, and
+// a new link is compared against every centroid. Keywords declared by the
+// page add suggestions of their own, and count extra when the user already
+// uses them as tags.
+package tagger
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+
+	"jetengine/internal/domain"
+	"jetengine/internal/search"
+)
+
+const (
+	// minScore is the lowest score a suggestion needs to be shown.
+	minScore = 0.15
+	// keywordScore is the score of a page keyword the user never used as a tag.
+	keywordScore = 0.2
+	// knownKeywordBonus is added when a page keyword is one of the user's tags.
+	knownKeywordBonus = 1.0
+	// maxTagLength skips keywords that are sentences rather than tags.
+	maxTagLength = 30
+)
+
+// Feature weights: keywords and the site say more about the topic than the
+// description, which often is boilerplate.
+const (
+	weightSite        = 2.0
+	weightKeywords    = 1.5
+	weightTitle       = 1.0
+	weightDescription = 0.5
+)
+
+// stopWords are frequent English words that say nothing about a topic.
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "you": true, "your": true,
+	"how": true, "what": true, "why": true, "this": true, "that": true, "are": true,
+	"from": true, "into": true, "about": true, "will": true, "can": true, "not": true,
+	"all": true, "our": true, "its": true, "has": true, "have": true, "more": true,
+	"new": true, "was": true, "out": true, "use": true, "using": true, "one": true,
+}
+
+// Suggest proposes up to limit tags for link, best first, based on its
+// content and on the tagged links in history. Tags the link already has are
+// never suggested.
+func Suggest(link domain.Link, history []domain.Link, limit int) []string {
+	scores := make(map[string]float64)
+
+	// --- Learn tag profiles from the user's tagged links ---
+	var docs []map[string]float64
+	var docTags [][]string
+	for _, h := range history {
+		if h.ID == link.ID || len(h.Tags) == 0 {
+			continue
+		}
+		docs = append(docs, features(h))
+		docTags = append(docTags, normalizeTags(h.Tags))
+	}
+	df := make(map[string]int)
+	for _, doc := range docs {
+		for f := range doc {
+			df[f]++
+		}
+	}
+	idf := func(f string) float64 {
+		return math.Log(1 + float64(len(docs))/float64(1+df[f]))
+	}
+
+	centroids := make(map[string]map[string]float64)
+	for i, doc := range docs {
+		vec := weigh(doc, idf)
+		for _, tag := range docTags[i] {
+			c := centroids[tag]
+			if c == nil {
+				c = make(map[string]float64)
+				centroids[tag] = c
+			}
+			for f, w := range vec {
+				c[f] += w
+			}
+		}
+	}
+
+	target := weigh(features(link), idf)
+	for tag, c := range centroids {
+		scores[tag] = cosine(target, c)
+	}
+
+	// --- Page keywords ---
+	for _, k := range link.Keywords {
+		tag := normalizeTag(k)
+		if tag == "" || len(tag) > maxTagLength {
+			continue
+		}
+		if _, known := centroids[tag]; known {
+			scores[tag] += knownKeywordBonus
+		} else {
+			scores[tag] = max(scores[tag], keywordScore)
+		}
+	}
+
+	for _, tag := range normalizeTags(link.Tags) {
+		delete(scores, tag)
+	}
+	var tags []string
+	for tag, score := range scores {
+		if score >= minScore {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if scores[tags[i]] != scores[tags[j]] {
+			return scores[tags[i]] > scores[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags
+}
+
+// features returns the raw weighted features of a link: the words of its
+// title, description and keywords, and its site.
+func features(link domain.Link) map[string]float64 {
+	f := make(map[string]float64)
+	add := func(text string, weight float64) {
+		for _, term := range search.Tokenize(text) {
+			if len(term) > 2 && !stopWords[term] {
+				f[term] += weight
+			}
+		}
+	}
+	add(link.Title, weightTitle)
+	add(link.Description, weightDescription)
+	add(strings.Join(link.Keywords, " "), weightKeywords)
+	if site := siteOf(link); site != "" {
+		f["site:"+site] = weightSite
+	}
+	return f
+}
+
+// weigh scales raw features by their inverse document frequency and
+// normalizes the result to unit length.
+func weigh(raw map[string]float64, idf func(string) float64) map[string]float64 {
+	vec := make(map[string]float64, len(raw))
+	var norm float64
+	for f, w := range raw {
+		v := (1 + math.Log(w+1)) * idf(f)
+		vec[f] = v
+		norm += v * v
+	}
+	if norm == 0 {
+		return vec
+	}
+	norm = math.Sqrt(norm)
+	for f := range vec {
+		vec[f] /= norm
+	}
+	return vec
+}
+
+// cosine returns the cosine similarity of two sparse vectors.
+func cosine(a, b map[string]float64) float64 {
+	var dot, na, nb float64
+	for f, v := range a {
+		dot += v * b[f]
+		na += v * v
+	}
+	for _, v := range b {
+		nb += v * v
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// siteOf returns the host of a link without a leading "www.".
+func siteOf(link domain.Link) string {
+	raw := link.CanonicalURL
+	if raw == "" {
+		raw = link.URL
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// normalizeTag turns a keyword into tag form: lower case, '#' stripped and
+// inner spaces replaced by dashes.
+func normalizeTag(s string) string {
+	s = strings.ToLower(strings.TrimLeft(strings.TrimSpace(s), "#"))
+	return strings.Join(strings.Fields(s), "-")
+}
+
+// normalizeTags normalizes a list of tags, dropping empty ones.
+func normalizeTags(tags []string) []string {
+	var out []string
+	for _, tag := range tags {
+		if tag = normalizeTag(tag); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
@@ -0,0 +1,41 @@
+package tagger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"jetengine/internal/domain"
+)
+
+// TestSuggest tests suggestions learned from history and page keywords.
+func TestSuggest(t *testing.T) {
+	history := []domain.Link{
+		{ID: "1", URL: "https://go.dev/blog/pgo", Title: "Profile-guided optimization in Go", Tags: []string{"go", "perf"}},
+		{ID: "2", URL: "https://go.dev/doc/effective_go", Title: "Effective Go", Tags: []string{"go"}},
+		{ID: "3", URL: "https://www.seriouseats.com/pasta", Title: "The best fresh pasta recipe", Tags: []string{"cooking"}},
+		{ID: "4", URL: "https://example.com/untagged", Title: "Go generics"},
+	}
+
+	link := domain.Link{ID: "5", URL: "https://go.dev/blog/generics", Title: "An introduction to generics in Go"}
+	got := Suggest(link, history, 3)
+	if assert.NotEmpty(t, got) {
+		assert.Equal(t, "go", got[0])
+	}
+	assert.NotContains(t, got, "cooking")
+
+	link = domain.Link{ID: "6", URL: "https://cooking.example/risotto", Title: "Risotto recipe"}
+	assert.Equal(t, []string{"cooking"}, Suggest(link, history, 3), "A shared title word should suggest the tag")
+
+	link.Keywords = []string{"Italian Food", "Perf", "Go"}
+	link.Tags = []string{"go"}
+	got = Suggest(link, history, 3)
+	if assert.NotEmpty(t, got) {
+		assert.Equal(t, "perf", got[0], "Keywords the user already tags with should rank first")
+	}
+	assert.Contains(t, got, "italian-food", "Page keywords should be suggested as tags")
+	assert.NotContains(t, got, "go", "Tags the link already has should not be suggested")
+
+	assert.Empty(t, Suggest(domain.Link{URL: "https://example.org/"}, nil, 3))
+	assert.Len(t, Suggest(link, history, 1), 1)
+}
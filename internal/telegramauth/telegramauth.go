@@ -0,0 +1,167 @@
+// Package telegramauth verifies the user data Telegram hands to websites,
+// either through the Login Widget or as the initData of a Mini App (Web App).
+// Both are signed with an HMAC-SHA256 derived from the bot token, so only the
+// bot's own server can check them.
+package telegramauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSignature is returned when the data was not signed with the bot token.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrExpired is returned when the data was signed too long ago to be trusted.
+	ErrExpired = errors.New("authentication data expired")
+
+	// ErrMalformed is returned when required fields are missing or invalid.
+	ErrMalformed = errors.New("malformed authentication data")
+)
+
+// webAppKey is the key that derives the secret for Mini App initData from the bot token.
+const webAppKey = "WebAppData"
+
+// clockSkew is how far in the future auth_date may lie, for clocks that are slightly off.
+const clockSkew = time.Minute
+
+// User is the Telegram user vouched for by verified data.
+type User struct {
+	// ID is the Telegram User ID.
+	ID int64 `json:"id"`
+	// FirstName is the user's first name.
+	FirstName string `json:"first_name"`
+	// LastName is the user's last name, if any.
+	LastName string `json:"last_name,omitempty"`
+	// Username is the user's username without "@", if any.
+	Username string `json:"username,omitempty"`
+	// PhotoURL is the URL of the user's profile photo, if shared.
+	PhotoURL string `json:"photo_url,omitempty"`
+	// AuthDate indicates when Telegram signed the data.
+	AuthDate time.Time `json:"-"`
+}
+
+// Name returns the display name of the user.
+func (u User) Name() string {
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if name == "" && u.Username != "" {
+		name = "@" + u.Username
+	}
+	return name
+}
+
+// Verifier checks data signed for one bot.
+type Verifier struct {
+	loginSecret  []byte
+	webAppSecret []byte
+	maxAge       time.Duration
+	now          func() time.Time
+}
+
+// NewVerifier creates a verifier for the bot with the given token. Data
+// signed more than maxAge ago is rejected.
+func NewVerifier(botToken string, maxAge time.Duration) *Verifier {
+	// The Login Widget signs with SHA256(token), Mini Apps with HMAC("WebAppData", token)
+	loginSecret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, []byte(webAppKey))
+	mac.Write([]byte(botToken))
+	return &Verifier{
+		loginSecret:  loginSecret[:],
+		webAppSecret: mac.Sum(nil),
+		maxAge:       maxAge,
+		now:          time.Now,
+	}
+}
+
+// VerifyLogin checks the fields the Login Widget passes to its callback or
+// redirect URL (id, first_name, ..., auth_date and hash) and returns the user.
+func (v *Verifier) VerifyLogin(fields url.Values) (User, error) {
+	if err := v.verify(fields, v.loginSecret); err != nil {
+		return User{}, err
+	}
+	id, err := strconv.ParseInt(fields.Get("id"), 10, 64)
+	if err != nil || id == 0 {
+		return User{}, fmt.Errorf("%w: invalid id", ErrMalformed)
+	}
+	return User{
+		ID:        id,
+		FirstName: fields.Get("first_name"),
+		LastName:  fields.Get("last_name"),
+		Username:  fields.Get("username"),
+		PhotoURL:  fields.Get("photo_url"),
+		AuthDate:  authDate(fields),
+	}, nil
+}
+
+// VerifyInitData checks the initData string of a Mini App
+// (window.Telegram.WebApp.initData) and returns the user who opened it.
+func (v *Verifier) VerifyInitData(initData string) (User, error) {
+	fields, err := url.ParseQuery(initData)
+	if err != nil {
+		return User{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if err := v.verify(fields, v.webAppSecret); err != nil {
+		return User{}, err
+	}
+	var user User
+	if err := json.Unmarshal([]byte(fields.Get("user")), &user); err != nil || user.ID == 0 {
+		return User{}, fmt.Errorf("%w: invalid user", ErrMalformed)
+	}
+	user.AuthDate = authDate(fields)
+	return user, nil
+}
+
+// verify checks the hash of fields against secret and the age of auth_date.
+func (v *Verifier) verify(fields url.Values, secret []byte) error {
+	hash, err := hex.DecodeString(fields.Get("hash"))
+	if err != nil || len(hash) != sha256.Size {
+		return fmt.Errorf("%w: missing hash", ErrMalformed)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(dataCheckString(fields)))
+	if !hmac.Equal(hash, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	signed := authDate(fields)
+	if signed.IsZero() {
+		return fmt.Errorf("%w: missing auth_date", ErrMalformed)
+	}
+	now := v.now()
+	if now.Sub(signed) > v.maxAge || signed.Sub(now) > clockSkew {
+		return ErrExpired
+	}
+	return nil
+}
+
+// dataCheckString joins all fields but hash as sorted "key=value" lines, the
+// message Telegram signs.
+func dataCheckString(fields url.Values) string {
+	lines := make([]string, 0, len(fields))
+	for key := range fields {
+		if key != "hash" {
+			lines = append(lines, key+"="+fields.Get(key))
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// authDate returns the signing time of fields, or the zero time if it is missing.
+func authDate(fields url.Values) time.Time {
+	sec, err := strconv.ParseInt(fields.Get("auth_date"), 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
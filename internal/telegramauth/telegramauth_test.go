@@ -0,0 +1,86 @@
+package telegramauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBotToken = "123456:TEST-token"
+
+// sign adds the hash Telegram would compute for fields with secret.
+func sign(fields url.Values, secret []byte) url.Values {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(dataCheckString(fields)))
+	fields.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return fields
+}
+
+// newTestVerifier returns a verifier whose clock is fixed at now.
+func newTestVerifier(now time.Time) *Verifier {
+	v := NewVerifier(testBotToken, time.Hour)
+	v.now = func() time.Time { return now }
+	return v
+}
+
+// TestVerifyLogin tests checking Login Widget data.
+func TestVerifyLogin(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(now)
+	fields := func(signedAt time.Time) url.Values {
+		return sign(url.Values{
+			"id":         {"42"},
+			"first_name": {"Ada"},
+			"username":   {"ada"},
+			"auth_date":  {strconv.FormatInt(signedAt.Unix(), 10)},
+		}, v.loginSecret)
+	}
+
+	user, err := v.VerifyLogin(fields(now.Add(-time.Minute)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), user.ID)
+	assert.Equal(t, "Ada", user.Name())
+	assert.Equal(t, now.Add(-time.Minute), user.AuthDate)
+
+	tampered := fields(now)
+	tampered.Set("id", "43")
+	_, err = v.VerifyLogin(tampered)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = v.VerifyLogin(fields(now.Add(-2 * time.Hour)))
+	assert.ErrorIs(t, err, ErrExpired)
+
+	_, err = v.VerifyLogin(url.Values{"id": {"42"}})
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	// Mini App signatures must not be accepted as Login Widget ones
+	_, err = v.VerifyLogin(sign(url.Values{"id": {"42"}, "auth_date": {strconv.FormatInt(now.Unix(), 10)}}, v.webAppSecret))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+// TestVerifyInitData tests checking Mini App initData.
+func TestVerifyInitData(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(now)
+	fields := sign(url.Values{
+		"query_id":  {"AAF"},
+		"user":      {`{"id":42,"first_name":"Ada","last_name":"Lovelace"}`},
+		"auth_date": {strconv.FormatInt(now.Unix(), 10)},
+	}, v.webAppSecret)
+
+	user, err := v.VerifyInitData(fields.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), user.ID)
+	assert.Equal(t, "Ada Lovelace", user.Name())
+
+	fields.Set("query_id", "other")
+	_, err = v.VerifyInitData(fields.Encode())
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}